@@ -3,11 +3,15 @@ package client
 import (
 	"context"
 	"fmt"
+	"math"
+	"math/rand"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 	"tr369-wss-client/client/model"
 	logger "tr369-wss-client/log"
+	"tr369-wss-client/pkg/api"
 	"tr369-wss-client/utils"
 
 	"github.com/coder/websocket"
@@ -15,40 +19,53 @@ import (
 	"tr369-wss-client/config"
 )
 
-// WSClient represents the TR369 WebSocket client
+// WSClient represents the TR369 WebSocket connection to one controller
 type WSClient struct {
-	config               *config.Config
-	conn                 *websocket.Conn
-	ctx                  context.Context
-	cancel               context.CancelFunc
-	connected            bool
-	pingTicker           *time.Ticker
-	dataRepo             model.DataRepository
-	clientUseCase        model.ClientUseCase
-	messageChannel       chan []byte   // 消息发送通道
-	reconnectInterval    time.Duration // 重连间隔
-	maxReconnectAttempts int           // 最大重连次数
-	reconnectAttempts    int           // 当前重连次数
-	reconnectTicker      *time.Ticker  // 重连定时器
+	config         *config.Config
+	endpoint       model.ControllerEndpoint // 连接的 controller
+	conn           *websocket.Conn
+	ctx            context.Context
+	cancel         context.CancelFunc
+	connected      bool
+	pingTicker     *time.Ticker
+	dataRepo       model.DataRepository
+	clientUseCase  model.ClientUseCase
+	messageChannel chan []byte // 发送队列，连接断开期间的消息在重新连接后发送
+
+	connDone     chan struct{} // 当前连接关闭时关闭，通知该连接的收发 goroutine 退出
+	flushDone    chan struct{} // 发送队列刷新完成通知
+	reconnecting bool          // 连接断开后正在按退避策略重连
+	connMu       sync.Mutex
+
+	peers  map[string]bool // 通过该连接发送过 Record 的 endpoint，响应按 from_id 经同一连接返回
+	peerMu sync.RWMutex
 }
 
-// NewWSClient creates a new WebSocket client instance
+// restartFlushTimeout 模拟重启时等待发送队列清空的最长时间
+const restartFlushTimeout = 5 * time.Second
+
+// maxRetryExponent 重连等待时间不再增长的重连次数
+const maxRetryExponent = 10
+
+// NewWSClient creates a new WebSocket client instance for the given controller
 func NewWSClient(
 	cfg *config.Config,
+	endpoint model.ControllerEndpoint,
 	dataRepo model.DataRepository,
 	clientUseCase model.ClientUseCase,
-	messageChannel chan []byte,
 ) *WSClient {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &WSClient{
 		config:         cfg,
+		endpoint:       endpoint,
 		ctx:            ctx,
 		cancel:         cancel,
 		connected:      false,
 		dataRepo:       dataRepo,
 		clientUseCase:  clientUseCase,
-		messageChannel: messageChannel,
+		messageChannel: make(chan []byte, cfg.WebsocketConfig.MessageChannelSize),
+		peers:          map[string]bool{endpoint.EndpointID: true},
 	}
 }
 
@@ -66,7 +83,7 @@ func (c *WSClient) Connect() error {
 	headers := http.Header{}
 	options.HTTPHeader = headers
 
-	connectUrl := c.config.WebsocketConfig.ServerURL
+	connectUrl := c.endpoint.URL
 
 	// 检查URL是否已经包含查询参数
 	if strings.Contains(connectUrl, "?") {
@@ -75,7 +92,7 @@ func (c *WSClient) Connect() error {
 		connectUrl += "?eid=" + c.config.WebsocketConfig.EndpointId
 	}
 
-	logger.Infof("Connecting with eid in url: %s", connectUrl)
+	logger.Infof("Connecting to controller %s with eid in url: %s", c.endpoint.EndpointID, connectUrl)
 
 	// 连接服务器
 	conn, _, err := websocket.Dial(c.ctx, connectUrl, options)
@@ -83,7 +100,11 @@ func (c *WSClient) Connect() error {
 		return fmt.Errorf("failed to dial: %w", err)
 	}
 
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+
 	c.conn = conn
+	c.connDone = make(chan struct{})
 	c.connected = true
 
 	// 设置读消息的最大大小
@@ -95,81 +116,163 @@ func (c *WSClient) Connect() error {
 	return nil
 }
 
-// Disconnect closes the WebSocket connection
+// Disconnect closes the WebSocket connection and reconnects in the background
 func (c *WSClient) Disconnect() {
+	c.closeConnection("client disconnecting")
+	c.startReconnect()
+}
+
+// closeConnection 关闭当前连接，并通知该连接的收发 goroutine 退出
+func (c *WSClient) closeConnection(reason string) {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+
 	if c.pingTicker != nil {
 		c.pingTicker.Stop()
 		c.pingTicker = nil
 	}
 
+	if c.connDone != nil {
+		close(c.connDone)
+		c.connDone = nil
+	}
+
 	if c.conn != nil {
 		// 先发送关闭帧
-		err := c.conn.Close(websocket.StatusNormalClosure, "client disconnecting")
+		err := c.conn.Close(websocket.StatusNormalClosure, reason)
 		if err != nil {
 			logger.Errorf("Failed to close WebSocket connection: %v", err)
 		}
 		c.conn = nil
 	}
 
-	if c.reconnectTicker != nil {
-		c.reconnectTicker.Stop()
-		c.reconnectTicker = nil
+	c.connected = false
+}
+
+// Stop 关闭连接并停止所有 goroutine，controller 被删除或禁用时调用
+func (c *WSClient) Stop() {
+	c.cancel()
+	c.closeConnection("controller removed")
+}
+
+// IsConnected 判断连接是否已建立
+func (c *WSClient) IsConnected() bool {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	return c.connected
+}
+
+// Send 将消息放入发送队列，队列已满时返回错误，避免调用方阻塞
+func (c *WSClient) Send(payload []byte) error {
+	select {
+	case c.messageChannel <- payload:
+		return nil
+	default:
+		return fmt.Errorf("send queue of controller %s is full", c.endpoint.EndpointID)
 	}
+}
 
-	c.connected = false
+// HasPeer 判断是否通过该连接收到过 endpoint 发送的 Record
+func (c *WSClient) HasPeer(endpointId string) bool {
+	c.peerMu.RLock()
+	defer c.peerMu.RUnlock()
+	return c.peers[endpointId]
+}
 
-	// 启动重连逻辑，除非已经达到最大重连次数
-	if c.reconnectAttempts < c.maxReconnectAttempts {
-		c.StartReconnectTicker()
+// addPeer 记录通过该连接发送 Record 的 endpoint
+func (c *WSClient) addPeer(endpointId string) {
+	c.peerMu.Lock()
+	defer c.peerMu.Unlock()
+	c.peers[endpointId] = true
+}
+
+// flushSendQueue 等待发送队列中已有的消息发送完成
+// 向队列写入空 payload 作为刷新标记，发送 goroutine 处理到标记时说明之前的消息均已发送
+func (c *WSClient) flushSendQueue(timeout time.Duration) {
+	flushDone := make(chan struct{})
+	c.flushDone = flushDone
+
+	deadline := time.After(timeout)
+	select {
+	case c.messageChannel <- nil:
+	case <-deadline:
+		logger.Warnf("Timed out waiting for send queue of controller %s to flush", c.endpoint.EndpointID)
+		return
+	}
+
+	select {
+	case <-flushDone:
+	case <-deadline:
+		logger.Warnf("Timed out waiting for send queue of controller %s to flush", c.endpoint.EndpointID)
 	}
 }
 
 // StartMessageHandler starts the message handling goroutines
 func (c *WSClient) StartMessageHandler() {
+	c.connMu.Lock()
+	conn, done, pingTicker := c.conn, c.connDone, c.pingTicker
+	c.connMu.Unlock()
+
 	// 启动ping goroutine
-	go c.pingHandler()
+	go c.pingHandler(conn, done, pingTicker)
 
 	// 启动消息接收goroutine
-	go c.messageHandler()
+	go c.messageHandler(conn, done)
 
 	// 启动消息写goroutine，顺序写入，便于控制
-	go c.messageSendHandler()
+	go c.messageSendHandler(conn, done)
+
+	c.clientUseCase.OnConnected(c.endpoint.EndpointID)
 }
 
 // pingHandler handles periodic ping messages
-func (c *WSClient) pingHandler() {
+func (c *WSClient) pingHandler(conn *websocket.Conn, done <-chan struct{}, pingTicker *time.Ticker) {
+	if conn == nil || pingTicker == nil {
+		return
+	}
 	for {
 		select {
-		case <-c.pingTicker.C:
-			if c.conn == nil {
-				return
-			}
-			if err := c.conn.Ping(c.ctx); err != nil {
+		case <-pingTicker.C:
+			if err := conn.Ping(c.ctx); err != nil {
 				logger.Infof("Ping failed: %v", err)
-				c.Disconnect()
+				c.disconnectUnlessClosed(done)
 				return
 			}
+		case <-done:
+			return
 		case <-c.ctx.Done():
 			return
 		}
 	}
 }
 
+// disconnectUnlessClosed 连接异常时断开连接
+// 连接已被主动关闭（如模拟重启）时不做处理，避免误关闭新建立的连接
+func (c *WSClient) disconnectUnlessClosed(done <-chan struct{}) {
+	select {
+	case <-done:
+	default:
+		c.Disconnect()
+	}
+}
+
 // messageHandler handles incoming messages using protobuf
-func (c *WSClient) messageHandler() {
+func (c *WSClient) messageHandler(conn *websocket.Conn, done <-chan struct{}) {
+	if conn == nil {
+		return
+	}
 	for {
 		select {
 		case <-c.ctx.Done():
 			return
+		case <-done:
+			return
 		default:
-			if c.conn == nil {
-				return
-			}
 			// 读取二进制消息
-			_, data, err := c.conn.Read(c.ctx)
+			_, data, err := conn.Read(c.ctx)
 			if err != nil {
 				logger.Infof("Connection closed with status %v", err)
-				c.Disconnect()
+				c.disconnectUnlessClosed(done)
 				return
 			}
 
@@ -179,12 +282,26 @@ func (c *WSClient) messageHandler() {
 				continue
 			}
 
-			logger.Infof("Decoded Record - From: %s, To: %s", record.FromId, record.ToId)
+			logger.Infof("Decoded Record - From: %s, To: %s, Version: %s", record.FromId, record.ToId, record.Version)
+
+			// 校验 to_id 和 from_id，被拒绝的 Record 不回复，避免向未知 endpoint 发送消息
+			if !c.clientUseCase.AcceptRecord(record.ToId, record.FromId) {
+				continue
+			}
+			c.addPeer(record.FromId)
+
+			// 校验 Record 协议版本，不支持的版本直接拒绝，不再解析 payload
+			if !c.clientUseCase.IsSupportedVersion(record.Version) {
+				logger.Warnf("Unsupported USP record version %s from %s", record.Version, record.FromId)
+				c.rejectRecord(record, model.ErrCodeRecordFieldInvalid, fmt.Sprintf("unsupported USP record version: %s", record.Version))
+				continue
+			}
 
 			// 提取NoSessionContextRecord
 			noSessionContext := record.GetNoSessionContext()
 			if noSessionContext == nil {
 				logger.Infof("Record is not NoSessionContextRecord")
+				c.rejectRecord(record, model.ErrCodeMessageFailed, "only no-session-context records are supported")
 				continue
 			}
 
@@ -192,34 +309,60 @@ func (c *WSClient) messageHandler() {
 			msg, err := utils.DecodeUSPMessage(noSessionContext.GetPayload())
 			if err != nil {
 				logger.Infof("Failed to decode USP Message: %v", err)
+				c.clientUseCase.SendErrorMessage(record.FromId, "", model.ErrCodeMessageFailed,
+					fmt.Sprintf("failed to decode USP message: %v", err))
 				continue
 			}
 
 			// 处理接收到的消息，调用usecase层的HandleMessage方法
-			c.clientUseCase.HandleMessage(msg)
+			c.clientUseCase.HandleMessage(record.FromId, msg)
 		}
 	}
 }
 
+// rejectRecord 拒绝无法处理的 Record，并向发送方回复 USP Error 消息
+// 尽量从 payload 中提取 msg_id，便于 controller 关联请求
+func (c *WSClient) rejectRecord(record *api.Record, errCode uint32, errMsg string) {
+	msgId := ""
+	if noSessionContext := record.GetNoSessionContext(); noSessionContext != nil {
+		if msg, err := utils.DecodeUSPMessage(noSessionContext.GetPayload()); err == nil {
+			msgId = msg.GetHeader().GetMsgId()
+		}
+	}
+
+	c.clientUseCase.SendErrorMessage(record.FromId, msgId, errCode, errMsg)
+}
+
 // messageSendHandler handles sending messages from the message channel
-func (c *WSClient) messageSendHandler() {
+func (c *WSClient) messageSendHandler(conn *websocket.Conn, done <-chan struct{}) {
 	for {
 
 		select {
 		case <-c.ctx.Done():
 			return
+		case <-done:
+			return
 		case payload, ok := <-c.messageChannel:
 			if !ok {
 				return
 			}
 
+			// 空 payload 为发送队列刷新标记
+			if payload == nil {
+				if c.flushDone != nil {
+					close(c.flushDone)
+					c.flushDone = nil
+				}
+				continue
+			}
+
 			// 发送二进制消息
-			if c.conn == nil {
+			if conn == nil {
 				logger.Warnf("Cannot send message: connection is nil")
 				return
 			}
 
-			if err := c.conn.Write(c.ctx, websocket.MessageBinary, payload); err != nil {
+			if err := conn.Write(c.ctx, websocket.MessageBinary, payload); err != nil {
 				logger.Infof("Failed to send response: %v", err)
 				return
 			}
@@ -229,58 +372,57 @@ func (c *WSClient) messageSendHandler() {
 	}
 }
 
-// StartReconnectTicker starts the reconnect ticker
-func (c *WSClient) StartReconnectTicker() {
-	if c.reconnectTicker != nil {
-		c.reconnectTicker.Stop()
-	}
+// IsReconnecting 判断是否正在后台重连
+func (c *WSClient) IsReconnecting() bool {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	return c.reconnecting
+}
 
-	c.reconnectTicker = time.NewTicker(c.reconnectInterval)
+// startReconnect 启动后台重连，已在重连或连接已停止时不做处理
+func (c *WSClient) startReconnect() {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
 
-	go func() {
-		for {
-			select {
-			case <-c.ctx.Done():
-				return
-			case <-c.reconnectTicker.C:
-				c.Reconnect()
-			}
-		}
-	}()
+	if c.reconnecting || c.ctx.Err() != nil {
+		return
+	}
+	c.reconnecting = true
+	go c.reconnectLoop()
 }
 
-// Reconnect attempts to reconnect to the server
-func (c *WSClient) Reconnect() {
-	c.reconnectAttempts++
-	logger.Infof("Attempting to reconnect (attempt %d/%d)...", c.reconnectAttempts, c.maxReconnectAttempts)
+// reconnectLoop 按 TR-181 WebSocket MTP 的重连策略重连，直到连接成功或连接被停止
+// 第 n 次重连前等待 [m*(k/1000)^(n-1), m*(k/1000)^n] 范围内的随机时间，m 为 RetryMinimumWait，k 为 RetryIntervalMultiplier，n 最大取 10
+func (c *WSClient) reconnectLoop() {
+	for attempt := 1; ; attempt++ {
+		wait := c.retryWait(attempt)
+		logger.Infof("Reconnecting to controller %s in %v (attempt %d)", c.endpoint.EndpointID, wait, attempt)
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-time.After(wait):
+		}
 
-	// 尝试连接服务器
-	err := c.Connect()
-	if err != nil {
-		logger.Errorf("Reconnect failed: %v", err)
-
-		// 如果达到最大重连次数，停止重连
-		if c.reconnectAttempts >= c.maxReconnectAttempts {
-			logger.Errorf("Max reconnect attempts reached, giving up")
-			if c.reconnectTicker != nil {
-				c.reconnectTicker.Stop()
-				c.reconnectTicker = nil
-			}
-			c.cancel()
+		if err := c.Connect(); err != nil {
+			logger.Errorf("Reconnect to controller %s failed: %v", c.endpoint.EndpointID, err)
+			continue
 		}
-		return
-	}
 
-	// 重连成功，重置重连计数器
-	logger.Infof("Reconnected successfully")
-	c.reconnectAttempts = 0
+		c.connMu.Lock()
+		c.reconnecting = false
+		c.connMu.Unlock()
 
-	// 停止重连定时器
-	if c.reconnectTicker != nil {
-		c.reconnectTicker.Stop()
-		c.reconnectTicker = nil
+		logger.Infof("Reconnected to controller %s", c.endpoint.EndpointID)
+		c.StartMessageHandler()
+		return
 	}
+}
 
-	// 启动消息处理
-	c.StartMessageHandler()
+// retryWait 计算第 attempt 次重连前的等待时间
+func (c *WSClient) retryWait(attempt int) time.Duration {
+	multiplier := float64(c.endpoint.RetryIntervalMultiplier) / 1000
+	exponent := float64(min(attempt, maxRetryExponent))
+	minWait := float64(c.endpoint.RetryMinimumWait) * math.Pow(multiplier, exponent-1)
+	maxWait := float64(c.endpoint.RetryMinimumWait) * math.Pow(multiplier, exponent)
+	return time.Duration(minWait + rand.Float64()*(maxWait-minWait))
 }
@@ -0,0 +1,197 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"tr369-wss-client/client/model"
+	logger "tr369-wss-client/log"
+
+	"tr369-wss-client/config"
+)
+
+// ControllerConnections 管理 Agent 与各 controller 的 WebSocket 连接
+// 每个 controller 使用独立的连接和发送队列，消息按 to_id 放入对应 controller 的队列
+type ControllerConnections struct {
+	config        *config.Config
+	dataRepo      model.DataRepository
+	clientUseCase model.ClientUseCase
+
+	clients map[string]*WSClient // key 为 controller 的 EndpointID
+	mu      sync.Mutex
+	syncMu  sync.Mutex // 串行化连接的建立和断开
+}
+
+// NewControllerConnections creates the connection manager for all controllers
+func NewControllerConnections(
+	cfg *config.Config,
+	dataRepo model.DataRepository,
+	clientUseCase model.ClientUseCase,
+) *ControllerConnections {
+	return &ControllerConnections{
+		config:        cfg,
+		dataRepo:      dataRepo,
+		clientUseCase: clientUseCase,
+		clients:       make(map[string]*WSClient),
+	}
+}
+
+// Connect 连接所有启用的 controller，只有全部连接失败时返回错误
+// 连接失败的 controller 保留发送队列，在后台按退避策略重连
+func (m *ControllerConnections) Connect() error {
+	m.syncMu.Lock()
+	defer m.syncMu.Unlock()
+
+	_, err := m.connectClients(m.reconcile())
+	return err
+}
+
+// Disconnect closes all WebSocket connections without reconnecting
+func (m *ControllerConnections) Disconnect() {
+	for _, c := range m.snapshot() {
+		c.Stop()
+	}
+}
+
+// StartMessageHandler 启动所有已连接 controller 的消息处理
+func (m *ControllerConnections) StartMessageHandler() {
+	for _, c := range m.snapshot() {
+		if c.IsConnected() {
+			c.StartMessageHandler()
+		}
+	}
+}
+
+// Restart 模拟设备重启
+// 等待各 controller 发送队列中已有的消息（如 DisconnectRecord）发送完成后关闭连接，
+// 执行 powerCycle 回调（持久化并重新加载数据），再按重新加载的 Controller 表重新连接并启动消息处理
+func (m *ControllerConnections) Restart(powerCycle func()) error {
+	m.syncMu.Lock()
+	defer m.syncMu.Unlock()
+
+	clients := m.snapshot()
+	for _, c := range clients {
+		if c.IsConnected() {
+			c.flushSendQueue(restartFlushTimeout)
+		}
+	}
+	for _, c := range clients {
+		c.closeConnection("agent rebooting")
+	}
+	logger.Infof("Connections closed for reboot")
+
+	powerCycle()
+
+	connected, err := m.connectClients(m.reconcile())
+	if err != nil {
+		return fmt.Errorf("failed to reconnect after reboot: %w", err)
+	}
+	logger.Infof("Reconnected after reboot")
+	for _, c := range connected {
+		c.StartMessageHandler()
+	}
+	return nil
+}
+
+// Send 将消息放入 controller 的发送队列
+// toId 不是已连接的 controller 时，使用收到过该 endpoint 发送的 Record 的连接
+func (m *ControllerConnections) Send(toId string, payload []byte) error {
+	m.mu.Lock()
+	c, ok := m.clients[toId]
+	if !ok {
+		for _, other := range m.clients {
+			if other.HasPeer(toId) {
+				c, ok = other, true
+				break
+			}
+		}
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("no MTP connection to controller %s", toId)
+	}
+	return c.Send(payload)
+}
+
+// SyncControllers 按 Controller 表同步连接：连接新增或启用的 controller，断开删除或禁用的 controller
+func (m *ControllerConnections) SyncControllers() {
+	m.syncMu.Lock()
+	defer m.syncMu.Unlock()
+
+	connected, err := m.connectClients(m.reconcile())
+	if err != nil {
+		logger.Warnf("Failed to connect controllers: %v", err)
+	}
+	for _, c := range connected {
+		c.StartMessageHandler()
+	}
+}
+
+// reconcile 按 ClientUseCase.ControllerEndpoints 更新连接列表，返回需要建立连接的 controller（调用方需持有 syncMu）
+// 已删除、禁用或连接地址变化的 controller 断开连接并丢弃发送队列
+func (m *ControllerConnections) reconcile() []*WSClient {
+	endpoints := m.clientUseCase.ControllerEndpoints()
+	wanted := make(map[string]model.ControllerEndpoint, len(endpoints))
+	for _, endpoint := range endpoints {
+		wanted[endpoint.EndpointID] = endpoint
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for endpointId, c := range m.clients {
+		if endpoint, ok := wanted[endpointId]; ok && endpoint.URL == c.endpoint.URL {
+			continue
+		}
+		c.Stop()
+		delete(m.clients, endpointId)
+		logger.Infof("Disconnected from controller %s", endpointId)
+	}
+
+	var pending []*WSClient
+	for _, endpoint := range endpoints {
+		c, ok := m.clients[endpoint.EndpointID]
+		if !ok {
+			c = NewWSClient(m.config, endpoint, m.dataRepo, m.clientUseCase)
+			m.clients[endpoint.EndpointID] = c
+		}
+		if !c.IsConnected() && !c.IsReconnecting() {
+			pending = append(pending, c)
+		}
+	}
+	return pending
+}
+
+// connectClients 建立连接，返回连接成功的 controller；只有全部连接失败时返回错误
+// 连接失败的 controller 在后台重连
+func (m *ControllerConnections) connectClients(clients []*WSClient) ([]*WSClient, error) {
+	var connected []*WSClient
+	var errs []error
+	for _, c := range clients {
+		if err := c.Connect(); err != nil {
+			logger.Warnf("Failed to connect to controller %s: %v", c.endpoint.EndpointID, err)
+			errs = append(errs, fmt.Errorf("controller %s: %w", c.endpoint.EndpointID, err))
+			c.startReconnect()
+			continue
+		}
+		logger.Infof("Connected to controller %s", c.endpoint.EndpointID)
+		connected = append(connected, c)
+	}
+	if len(connected) == 0 && len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return connected, nil
+}
+
+// snapshot 返回当前所有 controller 的连接
+func (m *ControllerConnections) snapshot() []*WSClient {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	clients := make([]*WSClient, 0, len(m.clients))
+	for _, c := range m.clients {
+		clients = append(clients, c)
+	}
+	return clients
+}
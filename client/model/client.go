@@ -1,15 +1,28 @@
 package model
 
 import (
+	"time"
+
 	"tr369-wss-client/pkg/api"
 	tr181Model "tr369-wss-client/tr181/model"
 )
 
 // 路径常量定义
 const (
-	PathDevice       = "Device."
-	PathLocalAgent   = "Device.LocalAgent."
-	PathSubscription = "Device.LocalAgent.Subscription."
+	PathDevice          = "Device."
+	PathDeviceInfo      = "Device.DeviceInfo."
+	PathFirmware        = "Device.DeviceInfo.FirmwareImage."
+	PathLocalAgent      = "Device.LocalAgent."
+	PathController      = "Device.LocalAgent.Controller."
+	PathControllerTrust = "Device.LocalAgent.ControllerTrust."
+	PathSubscription    = "Device.LocalAgent.Subscription."
+	PathRequest         = "Device.LocalAgent.Request."
+	PathRebootCause     = "Device.X_TP_RebootCause."
+
+	PathSoftwareModules = "Device.SoftwareModules."
+	PathDeploymentUnit  = "Device.SoftwareModules.DeploymentUnit."
+	PathExecutionUnit   = "Device.SoftwareModules.ExecutionUnit."
+	PathExecEnv         = "Device.SoftwareModules.ExecEnv."
 )
 
 // ParamSetting 定义参数设置的通用接口
@@ -17,6 +30,7 @@ const (
 type ParamSetting interface {
 	GetParam() string
 	GetValue() string
+	GetRequired() bool
 }
 
 // SubscriptionParams 订阅参数结构体
@@ -26,16 +40,44 @@ type SubscriptionParams struct {
 	NotifType     string
 }
 
+// ControllerEndpoint 一个 controller 的 MTP 连接参数
+type ControllerEndpoint struct {
+	EndpointID string // controller 的 EndpointID，发送 Record 的 to_id
+	URL        string // WebSocket 连接地址
+	Instance   string // Device.LocalAgent.Controller.{i}. 实例路径，配置文件中的 controller 不在 Controller 表中时为空
+
+	RetryMinimumWait        time.Duration // 连接断开后第一次重连的最短等待时间（SessionRetryMinimumWaitInterval）
+	RetryIntervalMultiplier uint64        // 重连等待时间的增长倍数，千分比（SessionRetryIntervalMultiplier）
+}
+
+// WebSocket MTP 重连参数的默认值（TR-181 Controller.{i}.MTP.{i}.WebSocket.）
+const (
+	DefaultRetryMinimumWait        = 5 * time.Second
+	DefaultRetryIntervalMultiplier = 2000
+)
+
 // WSClient defines the interface for TR369 WebSocket client
+// 每个 controller 使用独立的 MTP 连接和发送队列
 type WSClient interface {
-	// Connect establishes a WebSocket connection to the server
+	// Connect establishes a WebSocket connection to every controller
 	Connect() error
 
-	// Disconnect closes the WebSocket connection
+	// Disconnect closes all WebSocket connections
 	Disconnect()
 
 	// StartMessageHandler starts the message handling goroutines
 	StartMessageHandler()
+
+	// Restart simulates a device reboot: it flushes pending messages, closes the
+	// connection, runs powerCycle and then reconnects
+	Restart(powerCycle func()) error
+
+	// Send queues the payload on the connection of the controller toId
+	Send(toId string, payload []byte) error
+
+	// SyncControllers connects to added or enabled controllers and disconnects
+	// removed or disabled ones according to ClientUseCase.ControllerEndpoints
+	SyncControllers()
 }
 
 // DataRepository 定义数据访问接口
@@ -48,12 +90,52 @@ type DataRepository interface {
 	GetParameters() map[string]interface{}
 
 	// SetValue 设置指定路径的值
-	// 返回: changed (是否发生变化), oldValue (旧值)
-	SetValue(path string, key string, value string) (changed bool, oldValue string)
+	// 返回: changed (是否发生变化), oldValue (旧值), err (路径无法写入时的错误)
+	SetValue(path string, key string, value string) (changed bool, oldValue string, err error)
 
 	// DeleteNode 删除指定路径的节点
 	DeleteNode(path string) (nodePath string, isFound bool)
 
+	// CreateObject 创建空的对象实例（对象已存在时不做修改）
+	CreateObject(path string)
+
+	// BeginTransaction 开始事务，提交前的修改不会持久化；已有事务未结束时返回错误
+	BeginTransaction() error
+
+	// CommitTransaction 提交事务并持久化修改
+	CommitTransaction()
+
+	// RollbackTransaction 回滚事务中的所有修改
+	RollbackTransaction()
+
+	// Savepoint 返回事务中的保存点，用于只回滚单个对象的修改
+	Savepoint() int
+
+	// RollbackToSavepoint 回滚保存点之后的修改，事务继续进行
+	RollbackToSavepoint(savepoint int)
+
+	// Lock 锁定数据模型，消息处理和后台任务（如异步命令）修改数据前需要加锁
+	Lock()
+
+	// Unlock 解锁数据模型，持有锁期间数据被修改时先调用数据变化处理函数
+	Unlock()
+
+	// SetDataChangeHandler 设置数据变化处理函数，用于使依赖数据的状态（如订阅监听器）随数据变化
+	// 处理函数在持有数据锁时调用，不能再加锁
+	SetDataChangeHandler(handler func())
+
+	// FlushDataChanges 数据被修改过时立即调用数据变化处理函数（调用方需持有数据锁），不必等到解锁
+	FlushDataChanges()
+
+	// Flush 立即将数据持久化到磁盘
+	Flush()
+
+	// Reload 从磁盘重新加载持久化的数据，并根据订阅重建监听器
+	Reload()
+
+	// FactoryReset 使用出厂数据模板覆盖持久化的数据，并根据出厂模板中的订阅重建监听器
+	FactoryReset() error
+
 	// Start 启动数据仓库（初始化和数据同步）
 	Start()
 }
@@ -67,15 +149,37 @@ type ListenerManager interface {
 	// RemoveListener 移除指定参数的监听器
 	RemoveListener(paramName string) error
 
+	// RemoveSubscription 移除指定订阅实例的所有监听器
+	RemoveSubscription(instance string) error
+
 	// ResetListener 重置所有监听器
 	ResetListener() error
 
 	// NotifyListeners 通知指定参数的所有监听器
 	NotifyListeners(paramName string, value interface{})
+
+	// RegisterHandler 注册通知类型对应的处理函数，数据仓库启动时据此恢复持久化的订阅
+	RegisterHandler(notifType string, handler tr181Model.Handler)
 }
 
 // ClientUseCase defines the interface for client use case
 type ClientUseCase interface {
-	// HandleMessage processes incoming USP messages
-	HandleMessage(msg *api.Msg)
+	// HandleMessage processes incoming USP messages sent by fromId
+	HandleMessage(fromId string, msg *api.Msg)
+
+	// IsSupportedVersion checks whether the USP record version is supported
+	IsSupportedVersion(version string) bool
+
+	// AcceptRecord validates the record to_id and from_id and reports whether the record should be processed
+	AcceptRecord(toId string, fromId string) bool
+
+	// SendErrorMessage sends a USP Error message to the given endpoint
+	SendErrorMessage(toId string, msgId string, errCode uint32, errMsg string)
+
+	// OnConnected is called after the connection to the controller endpointId has been
+	// established and the message handlers are running
+	OnConnected(endpointId string)
+
+	// ControllerEndpoints returns the enabled controllers the agent connects to
+	ControllerEndpoints() []ControllerEndpoint
 }
@@ -1,9 +1,110 @@
 package model
 
 const (
-	DeviceReboot = "Device.Reboot()"
+	DeviceReboot            = "Device.Reboot()"
+	DeviceFactoryReset      = "Device.FactoryReset()"
+	LocalAgentRequestCancel = "Device.LocalAgent.Request.{i}.Cancel()"
+	FirmwareImageDownload   = "Device.DeviceInfo.FirmwareImage.{i}.Download()"
+	FirmwareImageActivate   = "Device.DeviceInfo.FirmwareImage.{i}.Activate()"
+	InstallDU               = "Device.SoftwareModules.InstallDU()"
+	DeploymentUnitUpdate    = "Device.SoftwareModules.DeploymentUnit.{i}.Update()"
+	DeploymentUnitUninstall = "Device.SoftwareModules.DeploymentUnit.{i}.Uninstall()"
+	ExecutionUnitSetState   = "Device.SoftwareModules.ExecutionUnit.{i}.SetRequestedState()"
+	IPPing                  = "Device.IP.Diagnostics.IPPing()"
+	TraceRoute              = "Device.IP.Diagnostics.TraceRoute()"
+	DownloadDiagnostics     = "Device.IP.Diagnostics.DownloadDiagnostics()"
 )
 
 const (
-	BOOT = "Boot!"
+	BOOT             = "Boot!"
+	TransferComplete = "TransferComplete!"
+	DUStateChange    = "DUStateChange!"
+	Periodic         = "Periodic!"
+)
+
+// Boot! 事件的 Cause 取值
+const (
+	BootCauseLocalReboot        = "LocalReboot"
+	BootCauseRemoteReboot       = "RemoteReboot"
+	BootCauseLocalFactoryReset  = "LocalFactoryReset"
+	BootCauseRemoteFactoryReset = "RemoteFactoryReset"
+)
+
+// Device.DeviceInfo 中与重启相关的参数
+const (
+	ParamBootCount = "BootCount"
+)
+
+// Device.LocalAgent.Request.{i}.Status 取值
+const (
+	RequestStatusRequested = "Requested"
+	RequestStatusActive    = "Active"
+	RequestStatusCanceling = "Canceling"
+	RequestStatusCanceled  = "Canceled"
+	RequestStatusSuccess   = "Success"
+	RequestStatusError     = "Error"
+)
+
+// Device.DeviceInfo.FirmwareImage.{i}.Status 取值
+const (
+	FirmwareStatusNoImage          = "NoImage"
+	FirmwareStatusActive           = "Active"
+	FirmwareStatusDownloading      = "Downloading"
+	FirmwareStatusValidating       = "Validating"
+	FirmwareStatusAvailable        = "Available"
+	FirmwareStatusDownloadFailed   = "DownloadFailed"
+	FirmwareStatusValidationFailed = "ValidationFailed"
+	FirmwareStatusActivationFailed = "ActivationFailed"
+)
+
+// Activate() 的 TimeWindow.{i}.Mode 取值
+const (
+	TimeWindowModeAnyTime            = "AnyTime"
+	TimeWindowModeImmediately        = "Immediately"
+	TimeWindowModeWhenIdle           = "WhenIdle"
+	TimeWindowModeConfirmationNeeded = "ConfirmationNeeded"
+)
+
+// Device.SoftwareModules.DeploymentUnit.{i}.Status 取值
+const (
+	DUStatusInstalling   = "Installing"
+	DUStatusInstalled    = "Installed"
+	DUStatusUpdating     = "Updating"
+	DUStatusUninstalling = "Uninstalling"
+	DUStatusUninstalled  = "Uninstalled"
+)
+
+// DUStateChange! 事件的 CurrentState 和 OperationPerformed 取值
+const (
+	DUStateInstalled   = "Installed"
+	DUStateUninstalled = "Uninstalled"
+	DUStateFailed      = "Failed"
+
+	DUOperationInstall   = "Install"
+	DUOperationUpdate    = "Update"
+	DUOperationUninstall = "Uninstall"
+)
+
+// Device.SoftwareModules.ExecutionUnit.{i} 的 Status、RequestedState 和 ExecutionFaultCode 取值
+const (
+	EUStatusIdle     = "Idle"
+	EUStatusStarting = "Starting"
+	EUStatusActive   = "Active"
+	EUStatusStopping = "Stopping"
+
+	EUFaultNoFault        = "NoFault"
+	EUFaultFailureOnStart = "FailureOnStart"
+)
+
+// Device.IP.Diagnostics 诊断命令输出参数 Status 的取值
+const (
+	DiagStatusComplete              = "Complete"
+	DiagStatusCannotResolveHostName = "Error_CannotResolveHostName"
+	DiagStatusInternal              = "Error_Internal"
+	DiagStatusOther                 = "Error_Other"
+	DiagStatusMaxHopCountExceeded   = "Error_MaxHopCountExceeded"
+	DiagStatusInitConnectionFailed  = "Error_InitConnectionFailed"
+	DiagStatusNoResponse            = "Error_NoResponse"
+	DiagStatusTransferFailed        = "Error_TransferFailed"
+	DiagStatusTimeout               = "Error_Timeout"
 )
@@ -0,0 +1,28 @@
+package model
+
+// CommandDescriptor 声明式命令描述，用于模拟厂商自定义（如 X_TP_*）命令，无需编写 Go 代码
+// OutputArgs、Mutations 的键和值均为 text/template 模板，可以引用输入参数和数据模型参数值，
+// 如 "{{.Input.Host}}"、"{{param \"Device.DeviceInfo.SoftwareVersion\"}}"
+type CommandDescriptor struct {
+	Command    string                    `json:"command" yaml:"command"`         // 命令路径，多实例对象使用 {i}，如 "Device.IP.Diagnostics.X_TP_Iperf()"
+	Type       string                    `json:"type" yaml:"type"`               // sync 或 async，默认 sync
+	InputArgs  map[string]*CommandArgDef `json:"input_args" yaml:"input_args"`   // 输入参数定义
+	OutputArgs map[string]string         `json:"output_args" yaml:"output_args"` // 输出参数名 -> 取值模板
+	DurationMs int                       `json:"duration_ms" yaml:"duration_ms"` // 模拟的执行时长（毫秒）
+	Mutations  map[string]string         `json:"mutations" yaml:"mutations"`     // 命令成功后修改的参数，参数路径模板 -> 取值模板
+	Failure    *CommandFailureDef        `json:"failure" yaml:"failure"`         // 模拟执行失败，可选
+}
+
+// CommandArgDef 命令输入参数定义
+type CommandArgDef struct {
+	Type     string `json:"type" yaml:"type"`         // TR-106 数据类型，默认 string
+	Required bool   `json:"required" yaml:"required"` // 是否必须指定
+	Default  string `json:"default" yaml:"default"`   // 未指定时使用的默认值
+}
+
+// CommandFailureDef 命令按概率模拟执行失败
+type CommandFailureDef struct {
+	Probability float64 `json:"probability" yaml:"probability"` // 失败概率，取值 [0, 1]
+	ErrCode     uint32  `json:"err_code" yaml:"err_code"`       // 失败时返回的错误码，默认 7022
+	ErrMsg      string  `json:"err_msg" yaml:"err_msg"`         // 失败时返回的错误信息
+}
@@ -0,0 +1,102 @@
+package model
+
+// DefaultDataModelInstURI 默认数据模型 URI
+const DefaultDataModelInstURI = "urn:broadband-forum-org:tr-181-2-16-0-usp"
+
+// 对象访问类型
+const (
+	ObjAccessReadOnly   = "readOnly"
+	ObjAccessAddDelete  = "addDelete"
+	ObjAccessAddOnly    = "addOnly"
+	ObjAccessDeleteOnly = "deleteOnly"
+)
+
+// 参数访问类型
+const (
+	ParamAccessReadOnly  = "readOnly"
+	ParamAccessReadWrite = "readWrite"
+	ParamAccessWriteOnly = "writeOnly"
+)
+
+// 参数值类型（TR-106 数据类型）
+const (
+	ParamTypeString       = "string"
+	ParamTypeBoolean      = "boolean"
+	ParamTypeInt          = "int"
+	ParamTypeLong         = "long"
+	ParamTypeUnsignedInt  = "unsignedInt"
+	ParamTypeUnsignedLong = "unsignedLong"
+	ParamTypeDateTime     = "dateTime"
+	ParamTypeBase64       = "base64"
+	ParamTypeHexBinary    = "hexBinary"
+	ParamTypeDecimal      = "decimal"
+)
+
+// 参数值变化通知类型
+const (
+	ValueChangeAllowed    = "allowed"
+	ValueChangeWillIgnore = "willIgnore"
+)
+
+// 命令类型
+const (
+	CommandTypeSync  = "sync"
+	CommandTypeAsync = "async"
+)
+
+// SupportedParam 支持的参数描述
+type SupportedParam struct {
+	Name        string `json:"-"`
+	Type        string `json:"type"`
+	Access      string `json:"access"`
+	ValueChange string `json:"value_change"`
+}
+
+// SupportedCommand 支持的命令描述
+type SupportedCommand struct {
+	Name       string   `json:"-"`
+	Type       string   `json:"type"`
+	InputArgs  []string `json:"input_args"`
+	OutputArgs []string `json:"output_args"`
+}
+
+// SupportedEvent 支持的事件描述
+type SupportedEvent struct {
+	Name string   `json:"-"`
+	Args []string `json:"args"`
+}
+
+// SupportedObject 支持的对象描述
+// Path 使用 {i} 表示多实例对象，如 "Device.WiFi.SSID.{i}."
+type SupportedObject struct {
+	Path            string
+	Access          string
+	IsMultiInstance bool
+	Params          map[string]*SupportedParam
+	Commands        map[string]*SupportedCommand
+	Events          map[string]*SupportedEvent
+	UniqueKeys      [][]string
+}
+
+// SupportedDMRegistry 定义支持的数据模型注册表接口
+// 负责描述 Agent 支持的对象、参数、命令和事件
+type SupportedDMRegistry interface {
+	// Load 从节点文件和元数据文件构建注册表
+	Load() error
+
+	// AddCommandDescriptors 将声明式命令加入注册表
+	AddCommandDescriptors(descriptors []*CommandDescriptor)
+
+	// GetObject 获取对象描述，路径可以使用实例编号或 {i}
+	GetObject(objPath string) (*SupportedObject, bool)
+
+	// GetObjects 获取对象及其子对象描述（按路径排序）
+	// firstLevelOnly 为 true 时只返回对象本身及其直接子对象
+	GetObjects(objPath string, firstLevelOnly bool) ([]*SupportedObject, error)
+
+	// GetParam 获取参数描述，路径可以使用实例编号或 {i}
+	GetParam(paramPath string) (*SupportedParam, bool)
+
+	// DataModelInstURI 返回数据模型 URI
+	DataModelInstURI() string
+}
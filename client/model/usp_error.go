@@ -0,0 +1,167 @@
+package model
+
+import "fmt"
+
+// USP 错误码（TR-369 Error Codes）
+const (
+	// ErrCodeMessageFailed 消息处理失败（通用错误）
+	ErrCodeMessageFailed uint32 = 7000
+	// ErrCodeMessageNotSupported 消息类型不支持
+	ErrCodeMessageNotSupported uint32 = 7001
+	// ErrCodeRequestDenied 请求被拒绝
+	ErrCodeRequestDenied uint32 = 7002
+	// ErrCodeInternalError 内部错误
+	ErrCodeInternalError uint32 = 7003
+	// ErrCodeInvalidArguments 参数无效
+	ErrCodeInvalidArguments uint32 = 7004
+	// ErrCodeResourcesExceeded 资源超限
+	ErrCodeResourcesExceeded uint32 = 7005
+	// ErrCodePermissionDenied 权限不足
+	ErrCodePermissionDenied uint32 = 7006
+	// ErrCodeInvalidConfiguration 配置无效
+	ErrCodeInvalidConfiguration uint32 = 7007
+	// ErrCodeInvalidPathSyntax 路径语法错误
+	ErrCodeInvalidPathSyntax uint32 = 7008
+	// ErrCodeParamActionFailed 参数操作失败
+	ErrCodeParamActionFailed uint32 = 7009
+	// ErrCodeUnsupportedParam 参数不支持
+	ErrCodeUnsupportedParam uint32 = 7010
+	// ErrCodeInvalidType 类型无效
+	ErrCodeInvalidType uint32 = 7011
+	// ErrCodeInvalidValue 值无效
+	ErrCodeInvalidValue uint32 = 7012
+	// ErrCodeParamReadOnly 尝试修改只读参数
+	ErrCodeParamReadOnly uint32 = 7013
+	// ErrCodeValueConflict 值冲突
+	ErrCodeValueConflict uint32 = 7014
+	// ErrCodeOperationError CRUD 操作失败
+	ErrCodeOperationError uint32 = 7015
+	// ErrCodeObjectNotExist 对象不存在
+	ErrCodeObjectNotExist uint32 = 7016
+	// ErrCodeObjectNotCreated 对象无法创建
+	ErrCodeObjectNotCreated uint32 = 7017
+	// ErrCodeNotATable 对象不是多实例表
+	ErrCodeNotATable uint32 = 7018
+	// ErrCodeObjectNotCreatable 对象不允许创建
+	ErrCodeObjectNotCreatable uint32 = 7019
+	// ErrCodeObjectNotUpdated 对象无法更新
+	ErrCodeObjectNotUpdated uint32 = 7020
+	// ErrCodeRequiredParamFailed 必需参数设置失败
+	ErrCodeRequiredParamFailed uint32 = 7021
+	// ErrCodeCommandFailure 命令执行失败
+	ErrCodeCommandFailure uint32 = 7022
+	// ErrCodeCommandCanceled 命令被取消
+	ErrCodeCommandCanceled uint32 = 7023
+	// ErrCodeDeleteFailure 对象删除失败
+	ErrCodeDeleteFailure uint32 = 7024
+	// ErrCodeUniqueKeyConflict 唯一键冲突
+	ErrCodeUniqueKeyConflict uint32 = 7025
+	// ErrCodeInvalidPath 路径无效（不存在于支持的数据模型中）
+	ErrCodeInvalidPath uint32 = 7026
+	// ErrCodeInvalidCommandArgs 命令参数无效
+	ErrCodeInvalidCommandArgs uint32 = 7027
+
+	// ErrCodeInvalidUUID UUID 格式无效
+	ErrCodeInvalidUUID uint32 = 7222
+	// ErrCodeUnknownExecEnv 执行环境不存在
+	ErrCodeUnknownExecEnv uint32 = 7223
+	// ErrCodeDisabledExecEnv 执行环境未启用
+	ErrCodeDisabledExecEnv uint32 = 7224
+	// ErrCodeDUExecEnvMismatch 部署单元与执行环境不匹配
+	ErrCodeDUExecEnvMismatch uint32 = 7225
+	// ErrCodeDuplicateDU 部署单元已安装
+	ErrCodeDuplicateDU uint32 = 7226
+	// ErrCodeSystemResourcesExceeded 系统资源不足
+	ErrCodeSystemResourcesExceeded uint32 = 7227
+	// ErrCodeUnknownDU 部署单元不存在
+	ErrCodeUnknownDU uint32 = 7228
+	// ErrCodeInvalidDUState 部署单元状态不允许当前操作
+	ErrCodeInvalidDUState uint32 = 7229
+	// ErrCodeDUDowngradeNotPermitted 部署单元更新不允许降级
+	ErrCodeDUDowngradeNotPermitted uint32 = 7230
+	// ErrCodeDUVersionNotSpecified 部署单元更新未指定版本
+	ErrCodeDUVersionNotSpecified uint32 = 7231
+	// ErrCodeDUVersionExists 部署单元更新的版本已存在
+	ErrCodeDUVersionExists uint32 = 7232
+
+	// ErrCodeRecordNotParsed Record 无法解析
+	ErrCodeRecordNotParsed uint32 = 7100
+	// ErrCodeRecordFieldInvalid Record 字段无效（如不支持的协议版本）
+	ErrCodeRecordFieldInvalid uint32 = 7104
+)
+
+// errMessages 错误码对应的默认错误信息
+var errMessages = map[uint32]string{
+	ErrCodeMessageFailed:           "Message failed",
+	ErrCodeMessageNotSupported:     "Message not supported",
+	ErrCodeRequestDenied:           "Request denied",
+	ErrCodeInternalError:           "Internal error",
+	ErrCodeInvalidArguments:        "Invalid arguments",
+	ErrCodeResourcesExceeded:       "Resources exceeded",
+	ErrCodePermissionDenied:        "Permission denied",
+	ErrCodeInvalidConfiguration:    "Invalid configuration",
+	ErrCodeInvalidPathSyntax:       "Invalid path syntax",
+	ErrCodeParamActionFailed:       "Parameter action failed",
+	ErrCodeUnsupportedParam:        "Unsupported parameter",
+	ErrCodeInvalidType:             "Invalid type",
+	ErrCodeInvalidValue:            "Invalid value",
+	ErrCodeParamReadOnly:           "Attempt to update non-writeable parameter",
+	ErrCodeValueConflict:           "Value conflict",
+	ErrCodeOperationError:          "Operation error",
+	ErrCodeObjectNotExist:          "Object does not exist",
+	ErrCodeObjectNotCreated:        "Object could not be created",
+	ErrCodeNotATable:               "Object is not a table",
+	ErrCodeObjectNotCreatable:      "Attempt to create non-creatable object",
+	ErrCodeObjectNotUpdated:        "Object could not be updated",
+	ErrCodeRequiredParamFailed:     "Required parameter failed",
+	ErrCodeCommandFailure:          "Command failure",
+	ErrCodeCommandCanceled:         "Command canceled",
+	ErrCodeDeleteFailure:           "Delete failure",
+	ErrCodeUniqueKeyConflict:       "Object exists with duplicate key",
+	ErrCodeInvalidPath:             "Invalid path",
+	ErrCodeInvalidCommandArgs:      "Invalid command arguments",
+	ErrCodeInvalidUUID:             "Invalid UUID format",
+	ErrCodeUnknownExecEnv:          "Unknown execution environment",
+	ErrCodeDisabledExecEnv:         "Disabled execution environment",
+	ErrCodeDUExecEnvMismatch:       "Deployment unit to execution environment mismatch",
+	ErrCodeDuplicateDU:             "Duplicate deployment unit",
+	ErrCodeSystemResourcesExceeded: "System resources exceeded",
+	ErrCodeUnknownDU:               "Unknown deployment unit",
+	ErrCodeInvalidDUState:          "Invalid deployment unit state",
+	ErrCodeDUDowngradeNotPermitted: "Invalid deployment unit update: downgrade not permitted",
+	ErrCodeDUVersionNotSpecified:   "Invalid deployment unit update: version not specified",
+	ErrCodeDUVersionExists:         "Invalid deployment unit update: version already exists",
+	ErrCodeRecordNotParsed:         "Record could not be parsed",
+	ErrCodeRecordFieldInvalid:      "Invalid record value",
+}
+
+// ErrMessage 返回错误码对应的默认错误信息
+func ErrMessage(code uint32) string {
+	if msg, ok := errMessages[code]; ok {
+		return msg
+	}
+	return "Unknown error"
+}
+
+// USPError USP 错误，携带错误码和错误信息
+type USPError struct {
+	Code    uint32 // USP 错误码
+	Message string // 错误信息
+}
+
+// Error 实现 error 接口
+func (e *USPError) Error() string {
+	return fmt.Sprintf("%d %s", e.Code, e.Message)
+}
+
+// NewUSPError 创建 USP 错误，错误信息为默认信息加上详细描述
+func NewUSPError(code uint32, format string, args ...interface{}) *USPError {
+	message := ErrMessage(code)
+	if format != "" {
+		message = message + ": " + fmt.Sprintf(format, args...)
+	}
+	return &USPError{
+		Code:    code,
+		Message: message,
+	}
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"tr369-wss-client/client/model"
@@ -20,6 +21,19 @@ type BaseRepository struct {
 	PingTicker     *time.Ticker
 	Ctx            context.Context
 	Cancel         context.CancelFunc
+
+	inTransaction bool        // 是否处于事务中
+	txJournal     []undoEntry // 事务日志，用于回滚
+	txMu          sync.Mutex
+
+	dataMu sync.Mutex // 数据模型锁，保证消息处理、后台任务和定时保存互斥访问数据
+
+	listenerMu sync.RWMutex // 监听器表锁，通知在后台 goroutine 中遍历监听器，与订阅的增删并发
+
+	notifHandlers map[string]tr181Model.Handler // 通知类型对应的监听器处理函数，用于恢复订阅
+
+	dataChanged   bool   // 持有数据锁期间数据是否被修改
+	changeHandler func() // 数据变化处理函数，在解锁前调用
 }
 
 // NewRepository 创建新的仓库实例
@@ -43,6 +57,7 @@ func NewRepository(
 		PingTicker:     pingTicker,
 		Ctx:            ctx,
 		Cancel:         cancel,
+		notifHandlers:  make(map[string]tr181Model.Handler),
 	}
 
 	dataRepo := NewDataRepository(base)
@@ -60,6 +75,9 @@ func (repo *BaseRepository) Start() {
 	// 初始化默认参数值
 	loadDefaultTR181Nodes(repo.TR181DataModel, repo.Config)
 
+	// 根据持久化的订阅重建监听器
+	repo.restoreSubscriptions()
+
 	// 启动数据同步定时器
 	go repo.DataSynchronizationTick()
 }
@@ -73,9 +91,15 @@ func (repo *BaseRepository) DataSynchronizationTick() {
 				logger.Debugf("No data change detected, skipping save.")
 				continue
 			}
+			if repo.isInTransaction() {
+				logger.Debugf("Transaction in progress, skipping save.")
+				continue
+			}
+			repo.Lock()
 			common.SaveJsonFile(repo.TR181DataModel.Parameters, repo.Config.DataRefreshConfig.TR181DataModelPath)
 			repo.WriteCount = 0
 			repo.LastWriteTime = time.Now().UnixMilli()
+			repo.Unlock()
 			logger.Debugf("tick save data synchronized.")
 		case <-repo.Ctx.Done():
 			return
@@ -83,6 +107,41 @@ func (repo *BaseRepository) DataSynchronizationTick() {
 	}
 }
 
+// Lock 锁定数据模型
+func (repo *BaseRepository) Lock() {
+	repo.dataMu.Lock()
+}
+
+// Unlock 解锁数据模型，持有锁期间数据被修改时先调用数据变化处理函数
+func (repo *BaseRepository) Unlock() {
+	repo.FlushDataChanges()
+	repo.dataMu.Unlock()
+}
+
+// SetDataChangeHandler 设置数据变化处理函数（处理函数在持有数据锁时调用，不能再加锁）
+func (repo *BaseRepository) SetDataChangeHandler(handler func()) {
+	repo.dataMu.Lock()
+	defer repo.dataMu.Unlock()
+	repo.changeHandler = handler
+}
+
+// FlushDataChanges 数据被修改过时立即调用数据变化处理函数（调用方需持有数据锁）
+// 事务中的修改在提交或回滚后才处理
+func (repo *BaseRepository) FlushDataChanges() {
+	if !repo.dataChanged || repo.isInTransaction() {
+		return
+	}
+	repo.dataChanged = false
+	if repo.changeHandler != nil {
+		repo.changeHandler()
+	}
+}
+
+// markDataChanged 记录数据已被修改（调用方需持有数据锁）
+func (repo *BaseRepository) markDataChanged() {
+	repo.dataChanged = true
+}
+
 // SaveData 保存数据到磁盘
 func (repo *BaseRepository) SaveData() {
 	repo.WriteCount++
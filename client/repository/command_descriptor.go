@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+
+	"tr369-wss-client/client/model"
+	"tr369-wss-client/utils"
+)
+
+// commandDescriptorFile 命令描述文件结构
+type commandDescriptorFile struct {
+	Commands []*model.CommandDescriptor `json:"commands" yaml:"commands"`
+}
+
+// commandArgTypes 命令输入参数支持的数据类型
+var commandArgTypes = map[string]bool{
+	model.ParamTypeString:       true,
+	model.ParamTypeBoolean:      true,
+	model.ParamTypeInt:          true,
+	model.ParamTypeLong:         true,
+	model.ParamTypeUnsignedInt:  true,
+	model.ParamTypeUnsignedLong: true,
+	model.ParamTypeDateTime:     true,
+	model.ParamTypeBase64:       true,
+	model.ParamTypeHexBinary:    true,
+	model.ParamTypeDecimal:      true,
+}
+
+// LoadCommandDescriptors 加载并校验命令描述文件，扩展名为 .yaml/.yml 时按 YAML 解析，否则按 JSON 解析
+func LoadCommandDescriptors(path string) ([]*model.CommandDescriptor, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read command descriptors %s: %w", path, err)
+	}
+
+	var file commandDescriptorFile
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &file)
+	default:
+		err = json.Unmarshal(content, &file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode command descriptors %s: %w", path, err)
+	}
+
+	seen := make(map[string]bool)
+	descriptors := make([]*model.CommandDescriptor, 0, len(file.Commands))
+	for i, desc := range file.Commands {
+		if desc == nil {
+			continue
+		}
+		if err := normalizeCommandDescriptor(desc); err != nil {
+			return nil, fmt.Errorf("invalid command descriptor #%d in %s: %w", i+1, path, err)
+		}
+		if seen[desc.Command] {
+			return nil, fmt.Errorf("duplicate command descriptor %s in %s", desc.Command, path)
+		}
+		seen[desc.Command] = true
+		descriptors = append(descriptors, desc)
+	}
+	return descriptors, nil
+}
+
+// normalizeCommandDescriptor 校验命令描述并补全默认值
+func normalizeCommandDescriptor(desc *model.CommandDescriptor) error {
+	index := strings.LastIndex(desc.Command, ".")
+	if !strings.HasPrefix(desc.Command, "Device.") || index < 0 || !strings.HasSuffix(desc.Command, "()") || index+3 > len(desc.Command) {
+		return fmt.Errorf("invalid command path %q", desc.Command)
+	}
+
+	switch desc.Type {
+	case "":
+		desc.Type = model.CommandTypeSync
+	case model.CommandTypeSync, model.CommandTypeAsync:
+	default:
+		return fmt.Errorf("%s: invalid type %q", desc.Command, desc.Type)
+	}
+	if desc.DurationMs < 0 {
+		return fmt.Errorf("%s: duration_ms must be non-negative", desc.Command)
+	}
+	// 同步命令在持有数据锁的消息处理流程中执行，模拟时长会阻塞所有 controller 的请求和通知
+	if desc.DurationMs > 0 && desc.Type == model.CommandTypeSync {
+		return fmt.Errorf("%s: duration_ms is only supported for async commands", desc.Command)
+	}
+
+	for name, arg := range desc.InputArgs {
+		if arg == nil {
+			arg = &model.CommandArgDef{}
+			desc.InputArgs[name] = arg
+		}
+		if arg.Type == "" {
+			arg.Type = model.ParamTypeString
+		}
+		if !commandArgTypes[arg.Type] {
+			return fmt.Errorf("%s: input argument %s has invalid type %q", desc.Command, name, arg.Type)
+		}
+	}
+
+	if failure := desc.Failure; failure != nil {
+		if failure.Probability < 0 || failure.Probability > 1 {
+			return fmt.Errorf("%s: failure probability must be in [0, 1]", desc.Command)
+		}
+		if failure.ErrCode == 0 {
+			failure.ErrCode = model.ErrCodeCommandFailure
+		}
+		if failure.ErrCode < 7000 || failure.ErrCode > 7999 {
+			return fmt.Errorf("%s: failure err_code %d is not a USP error code", desc.Command, failure.ErrCode)
+		}
+	}
+	return nil
+}
+
+// AddCommandDescriptors 将命令描述加入注册表，使 GetSupportedDM 和输入参数校验可见
+func (r *SupportedDMRegistry) AddCommandDescriptors(descriptors []*model.CommandDescriptor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, desc := range descriptors {
+		index := strings.LastIndex(desc.Command, ".")
+		obj := r.ensureObject(desc.Command[:index+1])
+		name := desc.Command[index+1:]
+		obj.Commands[name] = &model.SupportedCommand{
+			Name:       name,
+			Type:       desc.Type,
+			InputArgs:  utils.SortedKeys(desc.InputArgs),
+			OutputArgs: utils.SortedKeys(desc.OutputArgs),
+		}
+	}
+}
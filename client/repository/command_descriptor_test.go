@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"tr369-wss-client/client/model"
+)
+
+// writeDescriptorFile 将命令描述写入临时目录中的 name 文件
+func writeDescriptorFile(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("write command descriptors: %v", err)
+	}
+	return path
+}
+
+func TestLoadCommandDescriptorsYAML(t *testing.T) {
+	path := writeDescriptorFile(t, "commands.yaml", `
+commands:
+  - command: Device.IP.Diagnostics.X_TP_Iperf()
+    type: async
+    input_args:
+      Host: {required: true}
+      Port: {type: unsignedInt, default: 5201}
+      Reverse:
+    output_args:
+      Status: Complete
+      Host: "{{.Input.Host}}"
+    duration_ms: 2000
+    failure:
+      probability: 0.05
+  - command: Device.LEDs.X_TP_SetTimeControl()
+    mutations:
+      X_TP_TimeControl.Enable: "{{.Input.Enable}}"
+`)
+
+	descriptors, err := LoadCommandDescriptors(path)
+	if err != nil {
+		t.Fatalf("LoadCommandDescriptors: %v", err)
+	}
+	if len(descriptors) != 2 {
+		t.Fatalf("got %d descriptors, want 2", len(descriptors))
+	}
+
+	iperf := descriptors[0]
+	wantArgs := map[string]*model.CommandArgDef{
+		"Host":    {Type: model.ParamTypeString, Required: true},
+		"Port":    {Type: model.ParamTypeUnsignedInt, Default: "5201"},
+		"Reverse": {Type: model.ParamTypeString},
+	}
+	if iperf.Type != model.CommandTypeAsync || iperf.DurationMs != 2000 || !reflect.DeepEqual(iperf.InputArgs, wantArgs) {
+		t.Errorf("X_TP_Iperf() = type %s duration %d input %v", iperf.Type, iperf.DurationMs, iperf.InputArgs)
+	}
+	if iperf.OutputArgs["Host"] != "{{.Input.Host}}" {
+		t.Errorf("X_TP_Iperf() output Host = %q", iperf.OutputArgs["Host"])
+	}
+	if iperf.Failure == nil || iperf.Failure.Probability != 0.05 || iperf.Failure.ErrCode != model.ErrCodeCommandFailure {
+		t.Errorf("X_TP_Iperf() failure = %+v", iperf.Failure)
+	}
+
+	timeControl := descriptors[1]
+	if timeControl.Type != model.CommandTypeSync || timeControl.Mutations["X_TP_TimeControl.Enable"] != "{{.Input.Enable}}" {
+		t.Errorf("X_TP_SetTimeControl() = type %s mutations %v", timeControl.Type, timeControl.Mutations)
+	}
+}
+
+func TestLoadCommandDescriptorsShipped(t *testing.T) {
+	descriptors, err := LoadCommandDescriptors("../../data/vendor_commands.json")
+	if err != nil {
+		t.Fatalf("LoadCommandDescriptors: %v", err)
+	}
+	if len(descriptors) == 0 {
+		t.Fatal("no command descriptors loaded")
+	}
+}
+
+func TestLoadCommandDescriptorsInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"invalid command path", `{"commands": [{"command": "Device.IP.Diagnostics.X_TP_Iperf"}]}`},
+		{"invalid type", `{"commands": [{"command": "Device.X_TP_Test()", "type": "later"}]}`},
+		{"timed sync command", `{"commands": [{"command": "Device.X_TP_Test()", "duration_ms": 100}]}`},
+		{"invalid argument type", `{"commands": [{"command": "Device.X_TP_Test()", "input_args": {"Count": {"type": "number"}}}]}`},
+		{"failure probability", `{"commands": [{"command": "Device.X_TP_Test()", "failure": {"probability": 2}}]}`},
+		{"failure error code", `{"commands": [{"command": "Device.X_TP_Test()", "failure": {"probability": 1, "err_code": 404}}]}`},
+		{"duplicate command", `{"commands": [{"command": "Device.X_TP_Test()"}, {"command": "Device.X_TP_Test()"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeDescriptorFile(t, "commands.json", tt.content)
+			if _, err := LoadCommandDescriptors(path); err == nil {
+				t.Errorf("LoadCommandDescriptors(%s) succeeded, want error", tt.content)
+			}
+		})
+	}
+}
@@ -3,7 +3,9 @@ package repository
 import (
 	"fmt"
 	"strings"
+	"time"
 
+	"tr369-wss-client/common"
 	logger "tr369-wss-client/log"
 	"tr369-wss-client/trtree"
 )
@@ -33,9 +35,21 @@ func (repo *DataRepository) GetParameters() map[string]interface{} {
 	return repo.TR181DataModel.Parameters
 }
 
-// SetValue 设置指定路径的值
-// 返回: changed (是否发生变化), oldValue (旧值)
-func (repo *DataRepository) SetValue(path string, key string, value string) (changed bool, oldValue string) {
+// SetValue 设置指定路径的值，路径中的搜索表达式必须唯一匹配一个实例
+// 返回: changed (是否发生变化), oldValue (旧值), err (路径无法写入时的错误)
+func (repo *DataRepository) SetValue(path string, key string, value string) (changed bool, oldValue string, err error) {
+	// 搜索表达式和引用跟随先解析为唯一的实例路径，事务日志需要记录实际修改的节点
+	if trtree.IsSearchPath(path) {
+		nodePaths, err := trtree.ResolveObjectPath(repo.TR181DataModel.Parameters, path)
+		if err != nil {
+			return false, "", fmt.Errorf("failed to set %s%s: %w", path, key, err)
+		}
+		if len(nodePaths) != 1 {
+			return false, "", fmt.Errorf("failed to set %s%s: path matches %d objects", path, key, len(nodePaths))
+		}
+		path = nodePaths[0]
+	}
+
 	// 先获取旧值
 	oldVal, err := repo.GetValue(path + key)
 	if err != nil {
@@ -45,18 +59,90 @@ func (repo *DataRepository) SetValue(path string, key string, value string) (cha
 		oldValue, _ = oldVal.(string)
 	}
 
-	// 保存到数据库
-	trtree.HandleSetRequest(repo.TR181DataModel.Parameters, path, key, value)
-	repo.SaveData()
+	// 保存到数据库（事务中的修改在提交时才持久化）
+	repo.journalWrite(path + key)
+	if err := trtree.HandleSetRequest(repo.TR181DataModel.Parameters, path, key, value); err != nil {
+		return false, oldValue, fmt.Errorf("failed to set %s%s: %w", path, key, err)
+	}
+	if !repo.isInTransaction() {
+		repo.SaveData()
+	}
 
 	// 判断是否有变化
 	changed = oldValue != value
-	return changed, oldValue
+	if changed {
+		repo.markDataChanged()
+	}
+	return changed, oldValue, nil
 }
 
 // DeleteNode 删除指定路径的节点
 func (repo *DataRepository) DeleteNode(path string) (nodePath string, isFound bool) {
-	return trtree.HandleDeleteRequest(repo.TR181DataModel.Parameters, path)
+	isSuccess, fpath := trtree.IsExistPath(repo.TR181DataModel.Parameters, path)
+	if !isSuccess {
+		return "", false
+	}
+	repo.journalWrite(fpath)
+
+	nodePath, isFound = trtree.HandleDeleteRequest(repo.TR181DataModel.Parameters, fpath)
+	if !isFound {
+		return nodePath, false
+	}
+	repo.markDataChanged()
+	if !repo.isInTransaction() {
+		repo.SaveData()
+	}
+	return nodePath, isFound
+}
+
+// CreateObject 创建空的对象实例（对象已存在时不做修改）
+func (repo *DataRepository) CreateObject(path string) {
+	if _, err := repo.GetValue(path); err == nil {
+		return
+	}
+
+	repo.journalWrite(path)
+	restoreNode(repo.TR181DataModel.Parameters, strings.Split(strings.TrimSuffix(path, "."), "."), make(map[string]interface{}))
+	repo.markDataChanged()
+	if !repo.isInTransaction() {
+		repo.SaveData()
+	}
+}
+
+// Flush 立即将数据持久化到磁盘
+func (repo *DataRepository) Flush() {
+	common.SaveJsonFile(repo.TR181DataModel.Parameters, repo.Config.DataRefreshConfig.TR181DataModelPath)
+	repo.WriteCount = 0
+	repo.LastWriteTime = time.Now().UnixMilli()
+	logger.Debugf("flush data synchronized.")
+}
+
+// Reload 从磁盘重新加载持久化的数据，并按重新加载的订阅重建监听器（相当于重启）
+func (repo *DataRepository) Reload() {
+	loadDefaultTR181Nodes(repo.TR181DataModel, repo.Config)
+	logger.Infof("data reloaded from %s", repo.Config.DataRefreshConfig.TR181DataModelPath)
+	repo.restoreSubscriptions()
+	repo.markDataChanged()
+}
+
+// FactoryReset 使用出厂数据模板覆盖持久化的数据
+// 出厂模板只读，数据写入 TR181DataModelPath；监听器按出厂模板中的订阅重建
+func (repo *DataRepository) FactoryReset() error {
+	templatePath := repo.Config.DataRefreshConfig.FactoryTemplatePath
+	if templatePath == "" {
+		return fmt.Errorf("factory template path is not configured")
+	}
+	template := common.LoadJsonFile(templatePath)
+	if template == nil {
+		return fmt.Errorf("failed to load factory template: %s", templatePath)
+	}
+
+	repo.TR181DataModel.Parameters = template
+	repo.restoreSubscriptions()
+	repo.markDataChanged()
+	repo.Flush()
+	logger.Infof("data restored from factory template %s", templatePath)
+	return nil
 }
 
 // Start 启动数据仓库（初始化和数据同步）
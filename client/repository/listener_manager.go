@@ -36,18 +36,44 @@ func (lm *ListenerManager) AddListener(paramName string, listener tr181Model.Lis
 		return err
 	}
 
+	lm.listenerMu.Lock()
+	defer lm.listenerMu.Unlock()
 	lm.TR181DataModel.Listeners[paramName] = append(lm.TR181DataModel.Listeners[paramName], listener)
 	return nil
 }
 
 // RemoveListener 移除指定参数的监听器
 func (lm *ListenerManager) RemoveListener(paramName string) error {
+	lm.listenerMu.Lock()
+	defer lm.listenerMu.Unlock()
 	delete(lm.TR181DataModel.Listeners, paramName)
 	return nil
 }
 
+// RemoveSubscription 移除指定订阅实例的所有监听器，不影响同一路径上其他订阅的监听器
+func (lm *ListenerManager) RemoveSubscription(instance string) error {
+	lm.listenerMu.Lock()
+	defer lm.listenerMu.Unlock()
+	for paramName, listeners := range lm.TR181DataModel.Listeners {
+		remaining := listeners[:0]
+		for _, listener := range listeners {
+			if listener.Instance != instance {
+				remaining = append(remaining, listener)
+			}
+		}
+		if len(remaining) == 0 {
+			delete(lm.TR181DataModel.Listeners, paramName)
+			continue
+		}
+		lm.TR181DataModel.Listeners[paramName] = remaining
+	}
+	return nil
+}
+
 // ResetListener 重置所有监听器
 func (lm *ListenerManager) ResetListener() error {
+	lm.listenerMu.Lock()
+	defer lm.listenerMu.Unlock()
 	lm.TR181DataModel.Listeners = make(map[string][]tr181Model.Listener)
 	return nil
 }
@@ -55,10 +81,12 @@ func (lm *ListenerManager) ResetListener() error {
 // NotifyListeners 通知匹配参数路径的所有监听器
 // 支持层级前缀匹配和通配符匹配
 // 匹配优先级：精确匹配 > 前缀匹配 > 通配符匹配
+// 在读锁内复制匹配的监听器，释放锁后再触发
 func (lm *ListenerManager) NotifyListeners(paramName string, value interface{}) {
 	var matchedListeners []matchedListener
 
 	// 遍历所有订阅，找出匹配的监听器
+	lm.listenerMu.RLock()
 	for subPath, listeners := range lm.TR181DataModel.Listeners {
 		result := lm.matcher.Match(subPath, paramName)
 		if result.Matched {
@@ -70,6 +98,7 @@ func (lm *ListenerManager) NotifyListeners(paramName string, value interface{})
 			}
 		}
 	}
+	lm.listenerMu.RUnlock()
 
 	// 如果没有匹配的监听器，直接返回
 	if len(matchedListeners) == 0 {
@@ -4,6 +4,7 @@ import (
 	"regexp"
 	"strings"
 	"tr369-wss-client/client/model"
+	"tr369-wss-client/trtree"
 )
 
 // MatchType 匹配类型
@@ -13,7 +14,7 @@ const (
 	MatchTypeNone     MatchType = iota // 不匹配
 	MatchTypeExact                     // 精确匹配
 	MatchTypePrefix                    // 前缀匹配（订阅路径是变化路径的前缀）
-	MatchTypeWildcard                  // 通配符匹配（{i} 匹配任意实例）
+	MatchTypeWildcard                  // 通配符匹配（{i} 和 * 匹配任意实例）
 )
 
 // String 返回匹配类型的字符串表示
@@ -48,7 +49,7 @@ func NewPathMatcher() *PathMatcher {
 }
 
 // Match 检查变化路径是否匹配订阅路径
-// subscriptionPath: 订阅的路径（可能包含 {i}）
+// subscriptionPath: 订阅的路径（可能包含 {i} 或 *）
 // changedPath: 发生变化的参数路径
 func (m *PathMatcher) Match(subscriptionPath, changedPath string) MatchResult {
 	// 1. 精确匹配
@@ -70,7 +71,7 @@ func (m *PathMatcher) Match(subscriptionPath, changedPath string) MatchResult {
 	}
 
 	// 3. 通配符匹配
-	if strings.Contains(subscriptionPath, model.WildcardPlaceholder) {
+	if strings.Contains(subscriptionPath, model.WildcardPlaceholder) || strings.Contains(subscriptionPath, "."+trtree.WildcardSegment+".") {
 		if m.matchWildcard(subscriptionPath, changedPath) {
 			return MatchResult{
 				Matched:     true,
@@ -87,12 +88,16 @@ func (m *PathMatcher) Match(subscriptionPath, changedPath string) MatchResult {
 }
 
 // matchWildcard 检查带通配符的路径匹配
-// pattern: 包含 {i} 的订阅路径
+// pattern: 包含 {i} 或 * 的订阅路径
 // path: 发生变化的参数路径
 func (m *PathMatcher) matchWildcard(pattern, path string) bool {
-	// 将 {i} 替换为正则表达式 \d+（匹配一个或多个数字）
+	// 将 {i} 和 * 替换为正则表达式 \d+（匹配一个或多个数字）
 	regexPattern := regexp.QuoteMeta(pattern)
 	regexPattern = strings.ReplaceAll(regexPattern, `\{i\}`, `\d+`)
+	// 相邻的 * 共用分隔符，需要重复替换
+	for strings.Contains(regexPattern, `\.\*\.`) {
+		regexPattern = strings.ReplaceAll(regexPattern, `\.\*\.`, `\.\d+\.`)
+	}
 
 	if strings.HasSuffix(pattern, ".") {
 		// 前缀匹配模式：订阅路径以 . 结尾，匹配所有子路径
@@ -0,0 +1,32 @@
+package repository
+
+import "testing"
+
+func TestPathMatcherMatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		pattern     string
+		changedPath string
+		want        MatchType
+	}{
+		{"exact", "Device.DeviceInfo.SoftwareVersion", "Device.DeviceInfo.SoftwareVersion", MatchTypeExact},
+		{"prefix", "Device.DeviceInfo.", "Device.DeviceInfo.SoftwareVersion", MatchTypePrefix},
+		{"placeholder", "Device.IP.Interface.{i}.Name", "Device.IP.Interface.3.Name", MatchTypeWildcard},
+		{"wildcard parameter", "Device.IP.Interface.*.Name", "Device.IP.Interface.12.Name", MatchTypeWildcard},
+		{"wildcard object prefix", "Device.IP.Interface.*.", "Device.IP.Interface.1.Stats.BytesSent", MatchTypeWildcard},
+		{"adjacent wildcards", "Device.IP.Interface.*.IPv4Address.*.IPAddress", "Device.IP.Interface.1.IPv4Address.2.IPAddress", MatchTypeWildcard},
+		{"wildcard needs instance number", "Device.IP.Interface.*.Name", "Device.IP.Interface.wan.Name", MatchTypeNone},
+		{"wildcard parameter not prefix", "Device.IP.Interface.*.Name", "Device.IP.Interface.1.NameSuffix", MatchTypeNone},
+		{"different path", "Device.DeviceInfo.SoftwareVersion", "Device.DeviceInfo.HardwareVersion", MatchTypeNone},
+	}
+
+	matcher := NewPathMatcher()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := matcher.Match(tt.pattern, tt.changedPath)
+			if got.MatchType != tt.want || got.Matched != (tt.want != MatchTypeNone) {
+				t.Errorf("Match(%q, %q) = %+v, want %s", tt.pattern, tt.changedPath, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+
+	logger "tr369-wss-client/log"
+	tr181Model "tr369-wss-client/tr181/model"
+	"tr369-wss-client/trtree"
+	"tr369-wss-client/utils"
+)
+
+// RegisterHandler 注册通知类型对应的监听器处理函数，启动时按订阅的 NotifType 恢复监听器
+func (lm *ListenerManager) RegisterHandler(notifType string, handler tr181Model.Handler) {
+	lm.notifHandlers[notifType] = handler
+}
+
+// restoreSubscriptions 根据持久化的 Device.LocalAgent.Subscription.{i} 重建监听器（调用方需持有数据锁或在启动阶段调用）
+// 非持久化（Persistent 不为 true）的订阅在启动时删除，Enable 为 false 的订阅保留但不注册监听器，
+// 格式错误或 ID 重复的订阅跳过；重建完成后在监听器锁内替换监听器表
+func (repo *BaseRepository) restoreSubscriptions() {
+	listeners := make(map[string][]tr181Model.Listener)
+	defer func() {
+		repo.listenerMu.Lock()
+		repo.TR181DataModel.Listeners = listeners
+		repo.listenerMu.Unlock()
+	}()
+
+	params := repo.TR181DataModel.Parameters
+	instances, err := trtree.ResolveObjectPath(params, tr181Model.DeviceLocalAgentSubscription+"*.")
+	if err != nil {
+		logger.Warnf("[USP] restore subscriptions error: %v", err)
+		return
+	}
+
+	var restored, dropped int
+	ids := make(map[string]string)
+	for _, instance := range instances {
+		if !utils.IsTrue(getStringParam(params, instance+"Persistent")) {
+			trtree.HandleDeleteRequest(params, instance)
+			dropped++
+			logger.Infof("[USP] RESTORE_SUBSCRIPTION: dropped non-persistent subscription path=%s", instance)
+			continue
+		}
+		if enable := getStringParam(params, instance+"Enable"); enable != "" && !utils.IsTrue(enable) {
+			logger.Debugf("[USP] RESTORE_SUBSCRIPTION: skipped disabled subscription path=%s", instance)
+			continue
+		}
+
+		id := getStringParam(params, instance+"ID")
+		if other, ok := ids[id]; ok {
+			logger.Warnf("[USP] RESTORE_SUBSCRIPTION: skipped duplicate subscription path=%s, id=%s, first=%s", instance, id, other)
+			continue
+		}
+		if err := repo.restoreSubscription(instance, listeners); err != nil {
+			logger.Warnf("[USP] RESTORE_SUBSCRIPTION: skipped malformed subscription path=%s, err=%v", instance, err)
+			continue
+		}
+		ids[id] = instance
+		restored++
+	}
+
+	if dropped > 0 {
+		repo.SaveData()
+	}
+	logger.Infof("[USP] RESTORE_SUBSCRIPTION: restored=%d, dropped=%d, total=%d", restored, dropped, len(instances))
+}
+
+// restoreSubscription 为单个订阅实例注册监听器到 listeners，所有路径校验通过后才注册
+func (repo *BaseRepository) restoreSubscription(instance string, listeners map[string][]tr181Model.Listener) error {
+	params := repo.TR181DataModel.Parameters
+	id := getStringParam(params, instance+"ID")
+	notifType := getStringParam(params, instance+"NotifType")
+	refList := getStringParam(params, instance+"ReferenceList")
+
+	if id == "" {
+		return fmt.Errorf("missing ID")
+	}
+	if len(trtree.SplitPathList(refList)) == 0 {
+		return fmt.Errorf("missing ReferenceList")
+	}
+	handler, ok := repo.notifHandlers[notifType]
+	if !ok {
+		return fmt.Errorf("unsupported NotifType %q", notifType)
+	}
+
+	validator := NewPathValidator()
+	for _, path := range trtree.SplitPathList(refList) {
+		if err := validator.ValidatePath(path); err != nil {
+			return fmt.Errorf("invalid ReferenceList %q: %w", refList, err)
+		}
+	}
+	// 搜索表达式和引用跟随按当前数据解析，通配符路径由监听器匹配
+	paths, err := trtree.ResolvePathList(params, refList)
+	if err != nil {
+		return fmt.Errorf("invalid ReferenceList %q: %w", refList, err)
+	}
+
+	for _, path := range paths {
+		listeners[path] = append(listeners[path], tr181Model.Listener{
+			SubscriptionId: id,
+			Instance:       instance,
+			Listener:       handler,
+		})
+	}
+	logger.Infof("[USP] RESTORE_SUBSCRIPTION: success id=%s, type=%s, ref=%s", id, notifType, refList)
+	return nil
+}
+
+// getStringParam 获取字符串参数值，不存在或类型不符时返回空字符串
+func getStringParam(params map[string]interface{}, path string) string {
+	value, _, found := trtree.FindKeyInMap(params, strings.Split(path, "."), "")
+	if !found {
+		return ""
+	}
+	str, _ := value.(string)
+	return str
+}
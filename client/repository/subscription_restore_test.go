@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"tr369-wss-client/config"
+	tr181Model "tr369-wss-client/tr181/model"
+)
+
+// subscriptionTestData 持久化的订阅：1 正常，2 非持久化，3 未启用，4 缺少 ReferenceList，5 不支持的 NotifType，6 使用引用，7 与 1 的 ID 重复
+const subscriptionTestData = `{
+	"Device": {
+		"DeviceInfo": {"SoftwareVersion": "1.0"},
+		"LocalAgent": {
+			"Controller": {"1": {"EndpointID": "ctrl-1"}},
+			"Subscription": {
+				"1": {"ID": "boot", "Enable": "true", "Persistent": "true", "NotifType": "Event", "ReferenceList": "Device.Boot!"},
+				"2": {"ID": "temp", "Enable": "true", "Persistent": "false", "NotifType": "ValueChange", "ReferenceList": "Device.DeviceInfo.SoftwareVersion"},
+				"3": {"ID": "off", "Enable": "false", "Persistent": "true", "NotifType": "ValueChange", "ReferenceList": "Device.DeviceInfo."},
+				"4": {"ID": "broken", "Enable": "1", "Persistent": "1", "NotifType": "ValueChange", "ReferenceList": ""},
+				"5": {"ID": "unknown", "Enable": "true", "Persistent": "true", "NotifType": "Periodic", "ReferenceList": "Device."},
+				"6": {"ID": "ref", "Enable": "true", "Persistent": "true", "NotifType": "ValueChange", "ReferenceList": "Device.LocalAgent.Controller.1.EndpointID"},
+				"7": {"ID": "boot", "Persistent": "true", "NotifType": "ValueChange", "ReferenceList": "Device.DeviceInfo.SoftwareVersion"}
+			}
+		}
+	}
+}`
+
+func TestRestoreSubscriptions(t *testing.T) {
+	repo := &BaseRepository{
+		Config: &config.Config{DataRefreshConfig: &config.DataRefreshConfig{
+			TR181DataModelPath: filepath.Join(t.TempDir(), "data.json"),
+		}},
+		TR181DataModel: &tr181Model.TR181DataModel{
+			Parameters: parseTestTree(t, subscriptionTestData),
+			Listeners:  map[string][]tr181Model.Listener{"Device.Stale.": nil},
+		},
+		notifHandlers: make(map[string]tr181Model.Handler),
+	}
+	lm := NewListenerManager(repo)
+	for _, notifType := range []string{tr181Model.ValueChange, tr181Model.Event} {
+		lm.RegisterHandler(notifType, func(string, interface{}) {})
+	}
+
+	repo.restoreSubscriptions()
+
+	var paths []string
+	for path, listeners := range repo.TR181DataModel.Listeners {
+		paths = append(paths, path)
+		if len(listeners) != 1 || listeners[0].Listener == nil {
+			t.Errorf("%s has %d listeners, want 1", path, len(listeners))
+		}
+	}
+	sort.Strings(paths)
+	want := []string{"Device.Boot!", "Device.LocalAgent.Controller.1.EndpointID"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("restored listener paths = %v, want %v", paths, want)
+	}
+	if id := repo.TR181DataModel.Listeners["Device.Boot!"][0].SubscriptionId; id != "boot" {
+		t.Errorf("Device.Boot! SubscriptionId = %q, want boot", id)
+	}
+
+	// 非持久化的订阅被删除，其他订阅保留
+	subscriptions := repo.TR181DataModel.Parameters["Device"].(map[string]interface{})["LocalAgent"].(map[string]interface{})["Subscription"].(map[string]interface{})
+	var instances []string
+	for instance := range subscriptions {
+		instances = append(instances, instance)
+	}
+	sort.Strings(instances)
+	if want := []string{"1", "3", "4", "5", "6", "7"}; !reflect.DeepEqual(instances, want) {
+		t.Errorf("remaining subscriptions = %v, want %v", instances, want)
+	}
+}
@@ -0,0 +1,371 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"tr369-wss-client/client/model"
+	"tr369-wss-client/common"
+	"tr369-wss-client/config"
+	logger "tr369-wss-client/log"
+	"tr369-wss-client/trtree"
+)
+
+// numberOfEntriesSuffix 多实例表实例数参数的后缀，如 RequestNumberOfEntries 对应 Request.{i}.
+const numberOfEntriesSuffix = "NumberOfEntries"
+
+// supportedObjectMeta 元数据文件中的对象描述
+type supportedObjectMeta struct {
+	Access     string                             `json:"access"`
+	UniqueKeys [][]string                         `json:"unique_keys"`
+	Params     map[string]*model.SupportedParam   `json:"params"`
+	Commands   map[string]*model.SupportedCommand `json:"commands"`
+	Events     map[string]*model.SupportedEvent   `json:"events"`
+}
+
+// supportedDMMeta 元数据文件结构
+type supportedDMMeta struct {
+	DataModelInstURI string                          `json:"data_model_inst_uri"`
+	Objects          map[string]*supportedObjectMeta `json:"objects"`
+}
+
+// SupportedDMRegistry 实现 model.SupportedDMRegistry 接口
+type SupportedDMRegistry struct {
+	Config           *config.Config
+	objects          map[string]*model.SupportedObject
+	dataModelInstURI string
+	mu               sync.RWMutex
+}
+
+// NewSupportedDMRegistry 创建 SupportedDMRegistry 实例
+func NewSupportedDMRegistry(cfg *config.Config) *SupportedDMRegistry {
+	return &SupportedDMRegistry{
+		Config:           cfg,
+		objects:          make(map[string]*model.SupportedObject),
+		dataModelInstURI: model.DefaultDataModelInstURI,
+	}
+}
+
+// Load 从节点文件和元数据文件构建注册表
+// 元数据先于节点文件加载：元数据声明的多实例表在节点文件中没有实例时同样按表处理
+func (r *SupportedDMRegistry) Load() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.objects = make(map[string]*model.SupportedObject)
+
+	metaPath := r.Config.DataModelConfig.SupportedMetaPath
+	if metaPath != "" {
+		if err := r.applyMetaFile(metaPath); err != nil {
+			return err
+		}
+	}
+
+	nodesPath := r.Config.DataModelConfig.SupportedNodesPath
+	nodes := common.LoadJsonFile(nodesPath)
+	if nodes == nil {
+		return fmt.Errorf("failed to load supported nodes: %s", nodesPath)
+	}
+	r.buildFromNodes(nodes, "")
+	r.addEmptyTables()
+
+	logger.Infof("[USP] supported data model loaded: %d objects", len(r.objects))
+	return nil
+}
+
+// GetObject 获取对象描述，路径可以使用实例编号或 {i}
+func (r *SupportedDMRegistry) GetObject(objPath string) (*model.SupportedObject, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	obj, ok := r.objects[r.normalizeObjPath(objPath)]
+	return obj, ok
+}
+
+// GetObjects 获取对象及其子对象描述（按路径排序）
+func (r *SupportedDMRegistry) GetObjects(objPath string, firstLevelOnly bool) ([]*model.SupportedObject, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	path := r.normalizeObjPath(objPath)
+	root, ok := r.objects[path]
+	if !ok {
+		return nil, fmt.Errorf("object not supported: %s", objPath)
+	}
+
+	result := []*model.SupportedObject{root}
+	for childPath, child := range r.objects {
+		if childPath == path || !strings.HasPrefix(childPath, path) {
+			continue
+		}
+		if firstLevelOnly && !isFirstLevelChild(path, childPath) {
+			continue
+		}
+		result = append(result, child)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+	return result, nil
+}
+
+// GetParam 获取参数描述，路径可以使用实例编号或 {i}
+func (r *SupportedDMRegistry) GetParam(paramPath string) (*model.SupportedParam, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	index := strings.LastIndex(paramPath, ".")
+	if index < 0 {
+		return nil, false
+	}
+
+	obj, ok := r.objects[r.normalizeObjPath(paramPath[:index+1])]
+	if !ok {
+		return nil, false
+	}
+	param, ok := obj.Params[paramPath[index+1:]]
+	return param, ok
+}
+
+// DataModelInstURI 返回数据模型 URI
+func (r *SupportedDMRegistry) DataModelInstURI() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.dataModelInstURI
+}
+
+// normalizeObjPath 将请求路径转换为注册表中的对象路径
+// 支持实例编号路径和不带 {i} 的多实例表路径，如 "Device.WiFi.SSID."
+func (r *SupportedDMRegistry) normalizeObjPath(objPath string) string {
+	path := trtree.ToSupportedPath(objPath)
+	if !strings.HasSuffix(path, ".") {
+		path += "."
+	}
+	if _, ok := r.objects[path]; !ok {
+		if _, ok := r.objects[path+trtree.InstancePlaceholder+"."]; ok {
+			return path + trtree.InstancePlaceholder + "."
+		}
+	}
+	return path
+}
+
+// buildFromNodes 递归遍历节点树，推导对象和参数结构
+func (r *SupportedDMRegistry) buildFromNodes(node map[string]interface{}, objPath string) {
+	if objPath != "" {
+		r.ensureObject(objPath)
+	}
+
+	for key, value := range node {
+		child, ok := value.(map[string]interface{})
+		if !ok {
+			if objPath != "" {
+				r.addDefaultParam(r.objects[objPath], key)
+			}
+			continue
+		}
+
+		tablePath := objPath + key + "." + trtree.InstancePlaceholder + "."
+		if !r.isTableNode(node, key, tablePath) {
+			r.buildFromNodes(child, objPath+key+".")
+			continue
+		}
+
+		// 多实例表：合并所有实例的结构
+		r.ensureObject(tablePath)
+		for _, instance := range child {
+			instanceNode, ok := instance.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			r.buildFromNodes(instanceNode, tablePath)
+			if _, ok := instanceNode["Alias"]; ok && len(r.objects[tablePath].UniqueKeys) == 0 {
+				r.objects[tablePath].UniqueKeys = [][]string{{"Alias"}}
+			}
+		}
+	}
+}
+
+// isTableNode 判断子节点是否为多实例表
+// 表中没有实例时无法从节点本身判断，按元数据中的 {i} 路径或父对象的 <表名>NumberOfEntries 参数识别
+func (r *SupportedDMRegistry) isTableNode(parent map[string]interface{}, key string, tablePath string) bool {
+	child, _ := parent[key].(map[string]interface{})
+	if len(child) > 0 {
+		return trtree.IsMultiInstanceNode(child)
+	}
+	if _, ok := r.objects[tablePath]; ok {
+		return true
+	}
+	_, ok := parent[key+numberOfEntriesSuffix]
+	return ok
+}
+
+// addEmptyTables 为 <表名>NumberOfEntries 参数对应、但节点文件中不存在的多实例表（如没有实例的 Request 表）添加对象描述
+func (r *SupportedDMRegistry) addEmptyTables() {
+	var tables []string
+	for objPath, obj := range r.objects {
+		for name := range obj.Params {
+			table := strings.TrimSuffix(name, numberOfEntriesSuffix)
+			if table == name || table == "" {
+				continue
+			}
+			if _, ok := r.objects[objPath+table+"."]; ok {
+				continue
+			}
+			tables = append(tables, objPath+table+"."+trtree.InstancePlaceholder+".")
+		}
+	}
+	for _, tablePath := range tables {
+		r.ensureObject(tablePath)
+	}
+}
+
+// ensureObject 获取或创建对象描述（同时创建缺失的父对象）
+func (r *SupportedDMRegistry) ensureObject(objPath string) *model.SupportedObject {
+	if obj, ok := r.objects[objPath]; ok {
+		return obj
+	}
+
+	if parentPath := parentObjectPath(objPath); parentPath != "" {
+		r.ensureObject(parentPath)
+	}
+
+	obj := &model.SupportedObject{
+		Path:     objPath,
+		Access:   model.ObjAccessReadOnly,
+		Params:   make(map[string]*model.SupportedParam),
+		Commands: make(map[string]*model.SupportedCommand),
+		Events:   make(map[string]*model.SupportedEvent),
+	}
+	if strings.HasSuffix(objPath, "."+trtree.InstancePlaceholder+".") {
+		obj.IsMultiInstance = true
+		obj.Access = model.ObjAccessAddDelete
+	}
+	r.objects[objPath] = obj
+	return obj
+}
+
+// addDefaultParam 根据参数名添加默认参数描述
+// 元数据中没有描述的参数默认可写（只读参数需在元数据中声明 readOnly），<表名>NumberOfEntries 由 Agent 维护，始终只读
+func (r *SupportedDMRegistry) addDefaultParam(obj *model.SupportedObject, name string) {
+	if _, ok := obj.Params[name]; ok {
+		return
+	}
+
+	param := &model.SupportedParam{
+		Name:        name,
+		Type:        model.ParamTypeString,
+		Access:      model.ParamAccessReadWrite,
+		ValueChange: model.ValueChangeAllowed,
+	}
+	if strings.HasSuffix(name, numberOfEntriesSuffix) {
+		param.Type = model.ParamTypeUnsignedInt
+		param.Access = model.ParamAccessReadOnly
+	}
+	obj.Params[name] = param
+}
+
+// applyMetaFile 加载元数据文件并覆盖推导出的默认描述
+func (r *SupportedDMRegistry) applyMetaFile(metaPath string) error {
+	content, err := os.ReadFile(metaPath)
+	if err != nil {
+		return fmt.Errorf("failed to read supported dm meta %s: %w", metaPath, err)
+	}
+
+	var meta supportedDMMeta
+	if err := json.Unmarshal(content, &meta); err != nil {
+		return fmt.Errorf("failed to decode supported dm meta %s: %w", metaPath, err)
+	}
+
+	if meta.DataModelInstURI != "" {
+		r.dataModelInstURI = meta.DataModelInstURI
+	}
+
+	for objPath, objMeta := range meta.Objects {
+		if objMeta == nil {
+			continue
+		}
+		obj := r.ensureObject(objPath)
+		if objMeta.Access != "" {
+			obj.Access = objMeta.Access
+		}
+		if len(objMeta.UniqueKeys) > 0 {
+			obj.UniqueKeys = objMeta.UniqueKeys
+		}
+		for name, param := range objMeta.Params {
+			mergeSupportedParam(obj, name, param)
+		}
+		for name, command := range objMeta.Commands {
+			if command == nil {
+				command = &model.SupportedCommand{}
+			}
+			command.Name = name
+			obj.Commands[name] = command
+		}
+		for name, event := range objMeta.Events {
+			if event == nil {
+				event = &model.SupportedEvent{}
+			}
+			event.Name = name
+			obj.Events[name] = event
+		}
+	}
+	return nil
+}
+
+// mergeSupportedParam 将元数据中的参数描述合并到对象中，元数据中列出但未指定 access 的参数可写
+func mergeSupportedParam(obj *model.SupportedObject, name string, meta *model.SupportedParam) {
+	param, ok := obj.Params[name]
+	if !ok {
+		param = &model.SupportedParam{
+			Name:        name,
+			Type:        model.ParamTypeString,
+			Access:      model.ParamAccessReadWrite,
+			ValueChange: model.ValueChangeAllowed,
+		}
+		obj.Params[name] = param
+	}
+	if meta == nil {
+		return
+	}
+	if meta.Type != "" {
+		param.Type = meta.Type
+	}
+	if meta.Access != "" {
+		param.Access = meta.Access
+	}
+	if meta.ValueChange != "" {
+		param.ValueChange = meta.ValueChange
+	}
+}
+
+// isFirstLevelChild 判断 childPath 是否为 parentPath 的直接子对象
+// 直接子对象形如 "X." 或 "X.{i}."
+func isFirstLevelChild(parentPath, childPath string) bool {
+	relative := strings.TrimSuffix(strings.TrimPrefix(childPath, parentPath), ".")
+	segments := strings.Split(relative, ".")
+	switch len(segments) {
+	case 1:
+		return true
+	case 2:
+		return segments[1] == trtree.InstancePlaceholder
+	}
+	return false
+}
+
+// parentObjectPath 返回对象的父对象路径，多实例对象跳过表名本身
+// 如 "Device.WiFi.SSID.{i}." -> "Device.WiFi."，"Device.WiFi." -> "Device."
+func parentObjectPath(objPath string) string {
+	segments := strings.Split(strings.TrimSuffix(objPath, "."), ".")
+	if segments[len(segments)-1] == trtree.InstancePlaceholder {
+		segments = segments[:len(segments)-1]
+	}
+	if len(segments) <= 1 {
+		return ""
+	}
+	return strings.Join(segments[:len(segments)-1], ".") + "."
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"os"
+	"testing"
+
+	"tr369-wss-client/client/model"
+	"tr369-wss-client/config"
+	logger "tr369-wss-client/log"
+)
+
+func TestMain(m *testing.M) {
+	logger.InitLogger()
+	os.Exit(m.Run())
+}
+
+// newTestSupportedDMRegistry 使用仓库自带的节点文件和元数据文件创建注册表
+func newTestSupportedDMRegistry(t *testing.T) *SupportedDMRegistry {
+	t.Helper()
+	registry := NewSupportedDMRegistry(&config.Config{
+		DataModelConfig: &config.DataModelConfig{
+			SupportedNodesPath: "../../data/default_tr181_nodes.json",
+			SupportedMetaPath:  "../../data/supported_dm_meta.json",
+		},
+	})
+	if err := registry.Load(); err != nil {
+		t.Fatalf("load supported dm: %v", err)
+	}
+	return registry
+}
+
+func TestSupportedDMRegistryParamAccess(t *testing.T) {
+	registry := newTestSupportedDMRegistry(t)
+
+	tests := []struct {
+		name   string
+		path   string
+		access string
+	}{
+		{"meta read-only", "Device.DeviceInfo.SoftwareVersion", model.ParamAccessReadOnly},
+		{"meta read-write", "Device.DeviceInfo.ProvisioningCode", model.ParamAccessReadWrite},
+		{"not in meta", "Device.NAT.PortMappingAllowedOrigins", model.ParamAccessReadWrite},
+		{"vendor param not in meta", "Device.X_TP_RebootCause.Cause", model.ParamAccessReadWrite},
+		{"table alias", "Device.Bridging.Bridge.1.Alias", model.ParamAccessReadWrite},
+		{"number of entries", "Device.NAT.InterfaceSettingNumberOfEntries", model.ParamAccessReadOnly},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			param, ok := registry.GetParam(tt.path)
+			if !ok {
+				t.Fatalf("GetParam(%s) not found", tt.path)
+			}
+			if param.Access != tt.access {
+				t.Errorf("GetParam(%s).Access = %s, want %s", tt.path, param.Access, tt.access)
+			}
+		})
+	}
+}
+
+func TestSupportedDMRegistryEmptyTable(t *testing.T) {
+	registry := newTestSupportedDMRegistry(t)
+
+	obj, ok := registry.GetObject("Device.Cellular.AccessPoint.{i}.")
+	if !ok {
+		t.Fatal("Device.Cellular.AccessPoint.{i}. not registered")
+	}
+	if !obj.IsMultiInstance || obj.Access != model.ObjAccessAddDelete {
+		t.Errorf("AccessPoint table = multi-instance %v access %s, want multi-instance addDelete", obj.IsMultiInstance, obj.Access)
+	}
+}
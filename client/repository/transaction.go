@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+
+	logger "tr369-wss-client/log"
+	"tr369-wss-client/trtree"
+)
+
+// ErrTransactionActive 已有事务未提交或回滚时再次开始事务
+var ErrTransactionActive = errors.New("transaction already active")
+
+// undoEntry 事务日志中的一条撤销记录
+type undoEntry struct {
+	segments []string    // 被修改节点的路径分段
+	value    interface{} // 修改前的值（参数值或对象子树）
+	existed  bool        // 修改前节点是否存在
+}
+
+// BeginTransaction 开始事务
+// 事务期间的修改会记录到事务日志中，提交前不会持久化；已有事务未结束时返回错误，不影响已有事务
+func (repo *DataRepository) BeginTransaction() error {
+	repo.txMu.Lock()
+	defer repo.txMu.Unlock()
+
+	if repo.inTransaction {
+		return ErrTransactionActive
+	}
+	repo.inTransaction = true
+	repo.txJournal = nil
+	return nil
+}
+
+// CommitTransaction 提交事务并持久化修改
+func (repo *DataRepository) CommitTransaction() {
+	repo.txMu.Lock()
+	changed := len(repo.txJournal) > 0
+	repo.inTransaction = false
+	repo.txJournal = nil
+	repo.txMu.Unlock()
+
+	if changed {
+		repo.SaveData()
+	}
+}
+
+// RollbackTransaction 回滚事务中的所有修改
+func (repo *DataRepository) RollbackTransaction() {
+	repo.txMu.Lock()
+	defer repo.txMu.Unlock()
+
+	repo.undoJournal(0)
+	logger.Infof("transaction rolled back: %d changes reverted", len(repo.txJournal))
+
+	repo.inTransaction = false
+	repo.txJournal = nil
+}
+
+// Savepoint 返回事务中的保存点（当前事务日志的长度），不在事务中时返回 0
+func (repo *DataRepository) Savepoint() int {
+	repo.txMu.Lock()
+	defer repo.txMu.Unlock()
+	return len(repo.txJournal)
+}
+
+// RollbackToSavepoint 回滚保存点之后的修改，事务继续进行
+// allow_partial=true 时用于撤销单个失败对象的修改，不影响同一事务中的其他对象
+func (repo *DataRepository) RollbackToSavepoint(savepoint int) {
+	repo.txMu.Lock()
+	defer repo.txMu.Unlock()
+
+	if !repo.inTransaction || savepoint < 0 || savepoint >= len(repo.txJournal) {
+		return
+	}
+	repo.undoJournal(savepoint)
+	logger.Infof("transaction rolled back to savepoint: %d changes reverted", len(repo.txJournal)-savepoint)
+	repo.txJournal = repo.txJournal[:savepoint]
+}
+
+// undoJournal 按倒序撤销事务日志中从 from 开始的修改（调用方需持有 txMu）
+func (repo *DataRepository) undoJournal(from int) {
+	for i := len(repo.txJournal) - 1; i >= from; i-- {
+		entry := repo.txJournal[i]
+		if entry.existed {
+			restoreNode(repo.TR181DataModel.Parameters, entry.segments, entry.value)
+		} else {
+			removeNode(repo.TR181DataModel.Parameters, entry.segments)
+		}
+	}
+}
+
+// isInTransaction 判断当前是否处于事务中
+func (repo *BaseRepository) isInTransaction() bool {
+	repo.txMu.Lock()
+	defer repo.txMu.Unlock()
+	return repo.inTransaction
+}
+
+// journalWrite 在写入路径前记录撤销信息
+// 路径不存在时记录第一个缺失的节点，回滚时整体删除
+func (repo *BaseRepository) journalWrite(path string) {
+	repo.txMu.Lock()
+	defer repo.txMu.Unlock()
+
+	if !repo.inTransaction {
+		return
+	}
+
+	segments := strings.Split(strings.TrimSuffix(path, "."), ".")
+	node := repo.TR181DataModel.Parameters
+	for i, segment := range segments {
+		value, ok := node[segment]
+		if !ok {
+			repo.txJournal = append(repo.txJournal, undoEntry{segments: segments[:i+1], existed: false})
+			return
+		}
+		if i == len(segments)-1 {
+			repo.txJournal = append(repo.txJournal, undoEntry{segments: segments, value: cloneValue(value), existed: true})
+			return
+		}
+		child, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+		node = child
+	}
+}
+
+// cloneValue 复制节点值，对象子树做深拷贝
+func cloneValue(value interface{}) interface{} {
+	if node, ok := value.(map[string]interface{}); ok {
+		return trtree.CloneTrtree(node)
+	}
+	return value
+}
+
+// restoreNode 将节点恢复为指定值（缺失的父节点会被创建）
+func restoreNode(data map[string]interface{}, segments []string, value interface{}) {
+	node := data
+	for _, segment := range segments[:len(segments)-1] {
+		child, ok := node[segment].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			node[segment] = child
+		}
+		node = child
+	}
+	node[segments[len(segments)-1]] = value
+}
+
+// removeNode 删除指定节点
+func removeNode(data map[string]interface{}, segments []string) {
+	node := data
+	for _, segment := range segments[:len(segments)-1] {
+		child, ok := node[segment].(map[string]interface{})
+		if !ok {
+			return
+		}
+		node = child
+	}
+	delete(node, segments[len(segments)-1])
+}
@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"tr369-wss-client/config"
+	tr181Model "tr369-wss-client/tr181/model"
+)
+
+// testData 事务测试使用的数据模型
+const testData = `{
+	"Device": {
+		"DeviceInfo": {"Description": "gateway", "ProvisioningCode": ""},
+		"LocalAgent": {
+			"Controller": {
+				"1": {"EndpointID": "ctrl-1", "Enable": "true", "Alias": "cpe-1"},
+				"2": {"EndpointID": "ctrl-2", "Enable": "false", "Alias": "cpe-2"}
+			}
+		}
+	}
+}`
+
+// newTestDataRepository 创建使用 testData 的数据仓库，持久化文件写入临时目录
+func newTestDataRepository(t *testing.T) *DataRepository {
+	t.Helper()
+	cfg := &config.Config{
+		DataRefreshConfig: &config.DataRefreshConfig{
+			TR181DataModelPath:  filepath.Join(t.TempDir(), "data.json"),
+			WriteCountThreshold: 1000,
+		},
+	}
+
+	return NewDataRepository(&BaseRepository{
+		Config:         cfg,
+		TR181DataModel: &tr181Model.TR181DataModel{Parameters: parseTestTree(t, testData)},
+	})
+}
+
+func parseTestTree(t *testing.T, content string) map[string]interface{} {
+	t.Helper()
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(content), &data); err != nil {
+		t.Fatalf("unmarshal test data: %v", err)
+	}
+	return data
+}
+
+func mustSet(t *testing.T, repo *DataRepository, path string, key string, value string) {
+	t.Helper()
+	if _, _, err := repo.SetValue(path, key, value); err != nil {
+		t.Fatalf("SetValue(%s%s): %v", path, key, err)
+	}
+}
+
+func mustBegin(t *testing.T, repo *DataRepository) {
+	t.Helper()
+	if err := repo.BeginTransaction(); err != nil {
+		t.Fatalf("BeginTransaction: %v", err)
+	}
+}
+
+func TestRollbackTransaction(t *testing.T) {
+	tests := []struct {
+		name  string
+		apply func(t *testing.T, repo *DataRepository)
+	}{
+		{
+			name: "update existing parameter",
+			apply: func(t *testing.T, repo *DataRepository) {
+				mustSet(t, repo, "Device.DeviceInfo.", "Description", "changed")
+			},
+		},
+		{
+			name: "add new parameter",
+			apply: func(t *testing.T, repo *DataRepository) {
+				mustSet(t, repo, "Device.DeviceInfo.", "SerialNumber", "SN1")
+			},
+		},
+		{
+			name: "create instance with parameters",
+			apply: func(t *testing.T, repo *DataRepository) {
+				repo.CreateObject("Device.LocalAgent.Controller.3.")
+				mustSet(t, repo, "Device.LocalAgent.Controller.3.", "EndpointID", "ctrl-3")
+				mustSet(t, repo, "Device.LocalAgent.Controller.3.", "Enable", "true")
+			},
+		},
+		{
+			name: "delete instance",
+			apply: func(t *testing.T, repo *DataRepository) {
+				if _, found := repo.DeleteNode("Device.LocalAgent.Controller.1."); !found {
+					t.Fatalf("DeleteNode: instance not found")
+				}
+			},
+		},
+		{
+			name: "update the same parameter twice",
+			apply: func(t *testing.T, repo *DataRepository) {
+				mustSet(t, repo, "Device.LocalAgent.Controller.2.", "Enable", "true")
+				mustSet(t, repo, "Device.LocalAgent.Controller.2.", "Enable", "1")
+			},
+		},
+		{
+			name: "update then delete instance",
+			apply: func(t *testing.T, repo *DataRepository) {
+				mustSet(t, repo, "Device.LocalAgent.Controller.2.", "Alias", "renamed")
+				repo.DeleteNode("Device.LocalAgent.Controller.2.")
+			},
+		},
+	}
+
+	want := parseTestTree(t, testData)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newTestDataRepository(t)
+			mustBegin(t, repo)
+			tt.apply(t, repo)
+			repo.RollbackTransaction()
+
+			if got := repo.GetParameters(); !reflect.DeepEqual(got, want) {
+				t.Errorf("data after rollback = %v, want %v", got, want)
+			}
+			if repo.isInTransaction() {
+				t.Errorf("still in transaction after rollback")
+			}
+		})
+	}
+}
+
+func TestRollbackToSavepoint(t *testing.T) {
+	repo := newTestDataRepository(t)
+	mustBegin(t, repo)
+
+	mustSet(t, repo, "Device.DeviceInfo.", "ProvisioningCode", "P1")
+	savepoint := repo.Savepoint()
+	mustSet(t, repo, "Device.DeviceInfo.", "Description", "changed")
+	repo.CreateObject("Device.LocalAgent.Controller.3.")
+	mustSet(t, repo, "Device.LocalAgent.Controller.3.", "EndpointID", "ctrl-3")
+
+	repo.RollbackToSavepoint(savepoint)
+	if !repo.isInTransaction() {
+		t.Fatalf("transaction ended by RollbackToSavepoint")
+	}
+	repo.CommitTransaction()
+
+	want := parseTestTree(t, testData)
+	want["Device"].(map[string]interface{})["DeviceInfo"].(map[string]interface{})["ProvisioningCode"] = "P1"
+	if got := repo.GetParameters(); !reflect.DeepEqual(got, want) {
+		t.Errorf("data after commit = %v, want %v", got, want)
+	}
+}
+
+func TestCommitTransaction(t *testing.T) {
+	repo := newTestDataRepository(t)
+	mustBegin(t, repo)
+	mustSet(t, repo, "Device.DeviceInfo.", "Description", "changed")
+	repo.CommitTransaction()
+
+	// 提交后回滚不再撤销已提交的修改
+	repo.RollbackTransaction()
+	if value, _ := repo.GetValue("Device.DeviceInfo.Description"); value != "changed" {
+		t.Errorf("Description after commit = %v, want %q", value, "changed")
+	}
+	if repo.WriteCount != 1 {
+		t.Errorf("WriteCount after commit = %d, want 1", repo.WriteCount)
+	}
+}
+
+func TestBeginTransactionWhileActive(t *testing.T) {
+	repo := newTestDataRepository(t)
+	mustBegin(t, repo)
+	mustSet(t, repo, "Device.DeviceInfo.", "Description", "changed")
+
+	if err := repo.BeginTransaction(); !errors.Is(err, ErrTransactionActive) {
+		t.Fatalf("second BeginTransaction() error = %v, want %v", err, ErrTransactionActive)
+	}
+
+	// 已有事务的日志保留，仍可完整回滚
+	repo.RollbackTransaction()
+	if got, want := repo.GetParameters(), parseTestTree(t, testData); !reflect.DeepEqual(got, want) {
+		t.Errorf("data after rollback = %v, want %v", got, want)
+	}
+	mustBegin(t, repo)
+	repo.CommitTransaction()
+}
+
+func TestDataChangeHandler(t *testing.T) {
+	repo := newTestDataRepository(t)
+	calls := 0
+	repo.SetDataChangeHandler(func() { calls++ })
+
+	// 值未变化时不调用
+	repo.Lock()
+	mustSet(t, repo, "Device.DeviceInfo.", "Description", "gateway")
+	repo.Unlock()
+	if calls != 0 {
+		t.Fatalf("handler called %d times for unchanged value, want 0", calls)
+	}
+
+	// 多次修改在解锁时合并为一次调用
+	repo.Lock()
+	mustSet(t, repo, "Device.DeviceInfo.", "Description", "router")
+	repo.DeleteNode("Device.LocalAgent.Controller.2.")
+	repo.Unlock()
+	if calls != 1 {
+		t.Fatalf("handler called %d times after changes, want 1", calls)
+	}
+
+	// 事务中的修改在提交后才处理
+	repo.Lock()
+	mustBegin(t, repo)
+	repo.CreateObject("Device.LocalAgent.Controller.3.")
+	repo.FlushDataChanges()
+	if calls != 1 {
+		t.Errorf("handler called during transaction")
+	}
+	repo.CommitTransaction()
+	repo.FlushDataChanges()
+	if calls != 2 {
+		t.Errorf("handler called %d times after commit, want 2", calls)
+	}
+	repo.Unlock()
+	if calls != 2 {
+		t.Errorf("handler called %d times after unlock, want 2 (changes already handled)", calls)
+	}
+}
@@ -0,0 +1,340 @@
+package usecase
+
+import (
+	"strconv"
+	"strings"
+
+	"tr369-wss-client/client/model"
+	logger "tr369-wss-client/log"
+	"tr369-wss-client/pkg/api"
+	"tr369-wss-client/trtree"
+	"tr369-wss-client/utils"
+)
+
+// permissionKind 权限适用的节点类型，对应 Permission 的 Param、Obj、InstantiatedObj 和 CommandEvent 参数
+type permissionKind string
+
+const (
+	permParam           permissionKind = "Param"
+	permObj             permissionKind = "Obj"
+	permInstantiatedObj permissionKind = "InstantiatedObj"
+	permCommandEvent    permissionKind = "CommandEvent"
+)
+
+// 权限标志在 "rwxn" 字符串中的位置
+const (
+	permRead    = 0 // r: Get、GetInstances、GetSupportedDM
+	permWrite   = 1 // w: Set、Add（Param、Obj），Delete（InstantiatedObj）
+	permExecute = 2 // x: Operate（CommandEvent）
+)
+
+// permissionFlags 各标志位对应的字符，第 4 位 n 为通知权限
+var permissionFlags = "rwxn"
+
+// permission 一条 Device.LocalAgent.ControllerTrust.Role.{i}.Permission.{i}
+type permission struct {
+	targets []string
+	order   uint64
+	flags   map[permissionKind]string
+}
+
+// accessRules 一个 controller 的访问权限，由 AssignedRole 和 InheritedRole 引用的角色组成
+// 同一角色中匹配路径的 Order 最大的权限生效，多个角色的权限取并集
+type accessRules struct {
+	roles [][]*permission
+}
+
+// accessRules 读取 controller 的角色权限（调用方需持有数据锁）
+// 不在 Controller 表中的 endpoint（包括配置文件中的 controller）使用 ControllerTrust.UntrustedRole，未配置时拒绝所有访问
+func (uc *ClientUseCase) accessRules(fromId string) *accessRules {
+	rules := &accessRules{}
+
+	var roleRefs []string
+	if instance := uc.controllerInstance(fromId); instance != "" {
+		roleRefs = append(trtree.SplitPathList(uc.getStringValue(instance+"AssignedRole")),
+			trtree.SplitPathList(uc.getStringValue(instance+"InheritedRole"))...)
+	} else {
+		roleRefs = trtree.SplitPathList(uc.getStringValue(model.PathControllerTrust + "UntrustedRole"))
+	}
+
+	seen := make(map[string]bool)
+	for _, role := range roleRefs {
+		role = strings.TrimSuffix(role, ".") + "."
+		if seen[role] || !strings.HasPrefix(role, model.PathControllerTrust+"Role.") {
+			continue
+		}
+		seen[role] = true
+		if permissions := uc.rolePermissions(role); len(permissions) > 0 {
+			rules.roles = append(rules.roles, permissions)
+		}
+	}
+	return rules
+}
+
+// rolePermissions 读取角色中启用的权限，角色不存在或已禁用时返回空（调用方需持有数据锁）
+// Targets 中的搜索表达式按当前数据解析
+func (uc *ClientUseCase) rolePermissions(role string) []*permission {
+	if !utils.IsTrue(uc.getStringValue(role + "Enable")) {
+		return nil
+	}
+
+	params := uc.DataRepo.GetParameters()
+	instances, _ := trtree.ResolveObjectPath(params, role+"Permission.*.")
+	var permissions []*permission
+	for _, instance := range instances {
+		if !utils.IsTrue(uc.getStringValue(instance + "Enable")) {
+			continue
+		}
+		targets, err := trtree.ResolvePathList(params, uc.getStringValue(instance+"Targets"))
+		if err != nil {
+			logger.Warnf("[USP] ControllerTrust permission ignored: path=%s, err=%v", instance, err)
+			continue
+		}
+		order, _ := strconv.ParseUint(uc.getStringValue(instance+"Order"), 10, 32)
+		permissions = append(permissions, &permission{
+			targets: targets,
+			order:   order,
+			flags: map[permissionKind]string{
+				permParam:           uc.getStringValue(instance + string(permParam)),
+				permObj:             uc.getStringValue(instance + string(permObj)),
+				permInstantiatedObj: uc.getStringValue(instance + string(permInstantiatedObj)),
+				permCommandEvent:    uc.getStringValue(instance + string(permCommandEvent)),
+			},
+		})
+	}
+	return permissions
+}
+
+// allowed 判断是否拥有路径的指定权限
+func (rules *accessRules) allowed(path string, kind permissionKind, flag int) bool {
+	for _, permissions := range rules.roles {
+		var effective *permission
+		for _, perm := range permissions {
+			if perm.matches(path) && (effective == nil || perm.order >= effective.order) {
+				effective = perm
+			}
+		}
+		if effective != nil && len(effective.flags[kind]) > flag && effective.flags[kind][flag] == permissionFlags[flag] {
+			return true
+		}
+	}
+	return false
+}
+
+// matches 判断权限的 Targets 是否包含路径
+func (perm *permission) matches(path string) bool {
+	for _, target := range perm.targets {
+		if matchPermissionTarget(target, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPermissionTarget 判断路径是否匹配权限目标
+// 以 "." 结尾的目标匹配该对象及其下的所有节点，否则只匹配同一参数、命令或事件；
+// 目标中的 "*" 匹配任意实例编号，请求路径中的 "*" 只能被目标中的 "*" 匹配
+func matchPermissionTarget(target, path string) bool {
+	partial := strings.HasSuffix(target, ".")
+	targetSegments := strings.Split(strings.TrimSuffix(target, "."), ".")
+	pathSegments := strings.Split(strings.TrimSuffix(path, "."), ".")
+	if len(targetSegments) > len(pathSegments) || (!partial && len(targetSegments) != len(pathSegments)) {
+		return false
+	}
+	if !partial && strings.HasSuffix(path, ".") {
+		return false
+	}
+
+	for i, segment := range targetSegments {
+		if segment == pathSegments[i] {
+			continue
+		}
+		if segment == trtree.WildcardSegment && trtree.IsInstanceKey(pathSegments[i]) {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// objectPermissionKind 返回对象路径适用的权限类型：实例为 InstantiatedObj，其他对象为 Obj
+func objectPermissionKind(objPath string) permissionKind {
+	segments := strings.Split(strings.TrimSuffix(objPath, "."), ".")
+	if last := segments[len(segments)-1]; trtree.IsInstanceKey(last) || last == trtree.WildcardSegment {
+		return permInstantiatedObj
+	}
+	return permObj
+}
+
+// authorizeRequest 在消息分发到处理函数之前检查 SET、ADD、DELETE 和 OPERATE 请求的权限（调用方需持有数据锁）
+// 任一路径没有权限时返回 7006 错误和被拒绝的路径；路径无法解析时不做检查，由处理函数返回相应的错误
+func (uc *ClientUseCase) authorizeRequest(fromId string, msg *api.Msg) (*model.USPError, []*api.Error_ParamError) {
+	rules := uc.accessRules(fromId)
+
+	var denied []string
+	deny := func(path string, kind permissionKind, flag int) {
+		if !rules.allowed(path, kind, flag) {
+			denied = append(denied, path)
+		}
+	}
+
+	request := msg.GetBody().GetRequest()
+	switch msg.GetHeader().GetMsgType() {
+	case api.Header_SET:
+		for _, updateObj := range request.GetSet().GetUpdateObjs() {
+			instances, _ := uc.resolveObjectPaths(updateObj.GetObjPath())
+			for _, instance := range instances {
+				for _, setting := range updateObj.GetParamSettings() {
+					deny(instance+setting.GetParam(), permParam, permWrite)
+				}
+			}
+		}
+	case api.Header_ADD:
+		for _, createObj := range request.GetAdd().GetCreateObjs() {
+			tablePath := createObj.GetObjPath()
+			deny(tablePath, permObj, permWrite)
+			for _, setting := range createObj.GetParamSettings() {
+				deny(tablePath+trtree.WildcardSegment+"."+setting.GetParam(), permParam, permWrite)
+			}
+		}
+	case api.Header_DELETE:
+		for _, objPath := range request.GetDelete().GetObjPaths() {
+			instances, _ := uc.resolveObjectPaths(objPath)
+			for _, instance := range instances {
+				deny(instance, permInstantiatedObj, permWrite)
+			}
+		}
+	case api.Header_OPERATE:
+		if _, reqs, uspErr := uc.resolveCommand(fromId, request.GetOperate()); uspErr == nil {
+			for _, req := range reqs {
+				deny(req.Command(), permCommandEvent, permExecute)
+			}
+		}
+	}
+
+	if len(denied) == 0 {
+		return nil, nil
+	}
+	paramErrs := make([]*api.Error_ParamError, 0, len(denied))
+	for _, path := range denied {
+		paramErrs = append(paramErrs, &api.Error_ParamError{
+			ParamPath: path,
+			ErrCode:   model.ErrCodePermissionDenied,
+			ErrMsg:    model.NewUSPError(model.ErrCodePermissionDenied, "%s", path).Message,
+		})
+	}
+	return model.NewUSPError(model.ErrCodePermissionDenied, "%s", denied[0]), paramErrs
+}
+
+// filterGetResp 从 GET 响应中移除没有读权限的参数
+// 参数全部被过滤的对象结果一并移除；请求的参数路径没有读权限时返回 7006
+func (rules *accessRules) filterGetResp(resp *api.GetResp) {
+	if resp == nil {
+		return
+	}
+
+	for _, reqPathResult := range resp.GetReqPathResults() {
+		if reqPathResult.GetErrCode() != 0 {
+			continue
+		}
+		denied := false
+		var kept []*api.GetResp_ResolvedPathResult
+		for _, resolved := range reqPathResult.GetResolvedPathResults() {
+			resolvedPath := resolved.GetResolvedPath()
+			if len(resolved.GetResultParams()) == 0 {
+				if rules.allowed(resolvedPath, objectPermissionKind(resolvedPath), permRead) {
+					kept = append(kept, resolved)
+				}
+				continue
+			}
+			for name := range resolved.GetResultParams() {
+				if !rules.allowed(resolvedPath+name, permParam, permRead) {
+					delete(resolved.ResultParams, name)
+					denied = true
+				}
+			}
+			if len(resolved.GetResultParams()) > 0 {
+				kept = append(kept, resolved)
+			}
+		}
+		reqPathResult.ResolvedPathResults = kept
+		if reqPathResult.ResolvedPathResults == nil {
+			reqPathResult.ResolvedPathResults = []*api.GetResp_ResolvedPathResult{}
+		}
+
+		if denied && len(kept) == 0 && !strings.HasSuffix(reqPathResult.GetRequestedPath(), ".") {
+			uspErr := model.NewUSPError(model.ErrCodePermissionDenied, "%s", reqPathResult.GetRequestedPath())
+			reqPathResult.ErrCode = uspErr.Code
+			reqPathResult.ErrMsg = uspErr.Message
+		}
+	}
+}
+
+// filterGetInstancesResp 从 GET_INSTANCES 响应中移除没有读权限的实例，以及没有读权限的唯一键
+func (rules *accessRules) filterGetInstancesResp(resp *api.GetInstancesResp) {
+	if resp == nil {
+		return
+	}
+
+	for _, reqPathResult := range resp.GetReqPathResults() {
+		var kept []*api.GetInstancesResp_CurrInstance
+		for _, instance := range reqPathResult.GetCurrInsts() {
+			instPath := instance.GetInstantiatedObjPath()
+			if !rules.allowed(instPath, permInstantiatedObj, permRead) {
+				continue
+			}
+			for key := range instance.GetUniqueKeys() {
+				if !rules.allowed(instPath+key, permParam, permRead) {
+					delete(instance.UniqueKeys, key)
+				}
+			}
+			kept = append(kept, instance)
+		}
+		reqPathResult.CurrInsts = kept
+	}
+}
+
+// filterGetSupportedDMResp 从 GET_SUPPORTED_DM 响应中移除没有读权限的对象、参数、命令和事件
+// 支持的数据模型路径中的 "{i}" 按通配符 "*" 匹配权限目标
+func (rules *accessRules) filterGetSupportedDMResp(resp *api.GetSupportedDMResp) {
+	if resp == nil {
+		return
+	}
+
+	for _, reqObjResult := range resp.GetReqObjResults() {
+		var kept []*api.GetSupportedDMResp_SupportedObjectResult
+		for _, obj := range reqObjResult.GetSupportedObjs() {
+			objPath := strings.ReplaceAll(obj.GetSupportedObjPath(), "{i}", trtree.WildcardSegment)
+			if !rules.allowed(objPath, permObj, permRead) {
+				continue
+			}
+
+			var params []*api.GetSupportedDMResp_SupportedParamResult
+			for _, param := range obj.GetSupportedParams() {
+				if rules.allowed(objPath+param.GetParamName(), permParam, permRead) {
+					params = append(params, param)
+				}
+			}
+			obj.SupportedParams = params
+
+			var commands []*api.GetSupportedDMResp_SupportedCommandResult
+			for _, command := range obj.GetSupportedCommands() {
+				if rules.allowed(objPath+command.GetCommandName(), permCommandEvent, permRead) {
+					commands = append(commands, command)
+				}
+			}
+			obj.SupportedCommands = commands
+
+			var events []*api.GetSupportedDMResp_SupportedEventResult
+			for _, event := range obj.GetSupportedEvents() {
+				if rules.allowed(objPath+event.GetEventName(), permCommandEvent, permRead) {
+					events = append(events, event)
+				}
+			}
+			obj.SupportedEvents = events
+
+			kept = append(kept, obj)
+		}
+		reqObjResult.SupportedObjs = kept
+	}
+}
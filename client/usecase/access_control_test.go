@@ -0,0 +1,292 @@
+package usecase
+
+import (
+	"sort"
+	"testing"
+
+	"tr369-wss-client/client/model"
+	"tr369-wss-client/pkg/api"
+)
+
+func TestMatchPermissionTarget(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		path   string
+		want   bool
+	}{
+		{name: "object target matches itself", target: "Device.DeviceInfo.", path: "Device.DeviceInfo.", want: true},
+		{name: "object target matches parameter", target: "Device.DeviceInfo.", path: "Device.DeviceInfo.SerialNumber", want: true},
+		{name: "object target matches sub-object", target: "Device.DeviceInfo.", path: "Device.DeviceInfo.FirmwareImage.1.Status", want: true},
+		{name: "object target matches command", target: "Device.", path: "Device.Reboot()", want: true},
+		{name: "object target does not match parent", target: "Device.DeviceInfo.", path: "Device.", want: false},
+		{name: "object target does not match sibling prefix", target: "Device.DeviceInfo.", path: "Device.DeviceInfoX.Name", want: false},
+		{name: "parameter target matches parameter", target: "Device.DeviceInfo.SerialNumber", path: "Device.DeviceInfo.SerialNumber", want: true},
+		{name: "parameter target does not match other parameter", target: "Device.DeviceInfo.SerialNumber", path: "Device.DeviceInfo.Description", want: false},
+		{name: "parameter target does not match object", target: "Device.DeviceInfo.SerialNumber", path: "Device.DeviceInfo.SerialNumber.", want: false},
+		{name: "parameter target does not match child", target: "Device.DeviceInfo.FirmwareImage", path: "Device.DeviceInfo.FirmwareImage.1.Status", want: false},
+		{name: "event target", target: "Device.Boot!", path: "Device.Boot!", want: true},
+		{name: "wildcard target matches instance", target: "Device.LocalAgent.Controller.*.Alias", path: "Device.LocalAgent.Controller.3.Alias", want: true},
+		{name: "wildcard target matches wildcard path", target: "Device.LocalAgent.Controller.*.", path: "Device.LocalAgent.Controller.*.Alias", want: true},
+		{name: "wildcard target does not match non-instance", target: "Device.LocalAgent.*.", path: "Device.LocalAgent.Controller.", want: false},
+		{name: "instance target does not match wildcard path", target: "Device.LocalAgent.Controller.1.", path: "Device.LocalAgent.Controller.*.Alias", want: false},
+		{name: "instance target does not match other instance", target: "Device.LocalAgent.Controller.1.", path: "Device.LocalAgent.Controller.2.Alias", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchPermissionTarget(tt.target, tt.path); got != tt.want {
+				t.Errorf("matchPermissionTarget(%q, %q) = %v, want %v", tt.target, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+// accessControlTestData ctrl-a 同时拥有 Role.1（只读）和 Role.2（可写 DeviceInfo），ctrl-b 只有 Role.1；
+// Role.1 中 Order 更大的权限禁止读取 SerialNumber、Interface.2 和实例的 Alias；配置文件中的 controller（ctrl-1）不在 Controller 表中
+const accessControlTestData = `{
+	"Device": {
+		"DeviceInfo": {"SerialNumber": "SN-1", "SoftwareVersion": "1.0", "Description": "router"},
+		"IP": {
+			"Interface": {
+				"1": {"Alias": "wan", "Name": "eth0"},
+				"2": {"Alias": "lan", "Name": "br-lan"}
+			}
+		},
+		"LocalAgent": {
+			"Controller": {
+				"1": {"Enable": "true", "EndpointID": "ctrl-a", "AssignedRole": "Device.LocalAgent.ControllerTrust.Role.1", "InheritedRole": "Device.LocalAgent.ControllerTrust.Role.2"},
+				"2": {"Enable": "true", "EndpointID": "ctrl-b", "AssignedRole": "Device.LocalAgent.ControllerTrust.Role.1"}
+			},
+			"ControllerTrust": {
+				"UntrustedRole": "",
+				"Role": {
+					"1": {"Enable": "true", "Name": "Reader", "Permission": {
+						"1": {"Enable": "true", "Order": "1", "Targets": "Device.", "Param": "r---", "Obj": "r---", "InstantiatedObj": "r---", "CommandEvent": "r---"},
+						"2": {"Enable": "true", "Order": "2", "Targets": "Device.DeviceInfo.SerialNumber", "Param": "----"},
+						"3": {"Enable": "true", "Order": "3", "Targets": "Device.IP.Interface.2.", "Param": "----", "InstantiatedObj": "----"},
+						"4": {"Enable": "true", "Order": "4", "Targets": "Device.IP.Interface.*.Alias", "Param": "----"}
+					}},
+					"2": {"Enable": "true", "Name": "Writer", "Permission": {
+						"1": {"Enable": "true", "Order": "1", "Targets": "Device.DeviceInfo.", "Param": "rw--", "Obj": "r---"}
+					}},
+					"3": {"Enable": "true", "Name": "Untrusted", "Permission": {
+						"1": {"Enable": "true", "Order": "1", "Targets": "Device.DeviceInfo.", "Param": "r---", "Obj": "r---"}
+					}}
+				}
+			}
+		}
+	}
+}`
+
+// setUntrustedRole 设置 ControllerTrust.UntrustedRole
+func setUntrustedRole(t *testing.T, uc *ClientUseCase, role string) {
+	t.Helper()
+	uc.DataRepo.Lock()
+	defer uc.DataRepo.Unlock()
+	if _, _, err := uc.DataRepo.SetValue(model.PathControllerTrust, "UntrustedRole", role); err != nil {
+		t.Fatalf("set UntrustedRole: %v", err)
+	}
+}
+
+func TestAccessRulesAllowed(t *testing.T) {
+	tests := []struct {
+		name          string
+		fromId        string
+		untrustedRole string
+		path          string
+		kind          permissionKind
+		flag          int
+		want          bool
+	}{
+		{"read granted by role", "ctrl-b", "", "Device.DeviceInfo.SoftwareVersion", permParam, permRead, true},
+		{"higher order denies read", "ctrl-b", "", "Device.DeviceInfo.SerialNumber", permParam, permRead, false},
+		{"lower order does not override", "ctrl-b", "", "Device.IP.Interface.2.Name", permParam, permRead, false},
+		{"other instance keeps lower order", "ctrl-b", "", "Device.IP.Interface.1.Name", permParam, permRead, true},
+		{"write not granted", "ctrl-b", "", "Device.DeviceInfo.Description", permParam, permWrite, false},
+		{"roles are unioned for read", "ctrl-a", "", "Device.DeviceInfo.SerialNumber", permParam, permRead, true},
+		{"roles are unioned for write", "ctrl-a", "", "Device.DeviceInfo.Description", permParam, permWrite, true},
+		{"union does not extend other targets", "ctrl-a", "", "Device.IP.Interface.1.Name", permParam, permWrite, false},
+		{"configured controller denied by default", "ctrl-1", "", "Device.DeviceInfo.SoftwareVersion", permParam, permRead, false},
+		{"configured controller uses untrusted role", "ctrl-1", "Device.LocalAgent.ControllerTrust.Role.3", "Device.DeviceInfo.SoftwareVersion", permParam, permRead, true},
+		{"untrusted role limits targets", "ctrl-1", "Device.LocalAgent.ControllerTrust.Role.3", "Device.IP.Interface.1.Name", permParam, permRead, false},
+		{"untrusted role limits flags", "ctrl-1", "Device.LocalAgent.ControllerTrust.Role.3", "Device.DeviceInfo.Description", permParam, permWrite, false},
+		{"unknown endpoint uses untrusted role", "ctrl-x", "Device.LocalAgent.ControllerTrust.Role.3", "Device.DeviceInfo.SoftwareVersion", permParam, permRead, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, _ := newTestUseCase(t, accessControlTestData)
+			setUntrustedRole(t, uc, tt.untrustedRole)
+
+			uc.DataRepo.Lock()
+			defer uc.DataRepo.Unlock()
+			if got := uc.accessRules(tt.fromId).allowed(tt.path, tt.kind, tt.flag); got != tt.want {
+				t.Errorf("allowed(%s, %s, %c) for %s = %v, want %v", tt.path, tt.kind, permissionFlags[tt.flag], tt.fromId, got, tt.want)
+			}
+		})
+	}
+}
+
+// setRequest 构建修改 Device.DeviceInfo. 参数的 SET 请求
+func setRequest(msgId string, values map[string]string) *api.Msg {
+	updateObj := &api.Set_UpdateObject{ObjPath: model.PathDeviceInfo}
+	for param, value := range values {
+		updateObj.ParamSettings = append(updateObj.ParamSettings, &api.Set_UpdateParamSetting{Param: param, Value: value, Required: true})
+	}
+	return &api.Msg{
+		Header: &api.Header{MsgId: msgId, MsgType: api.Header_SET},
+		Body: &api.Body{MsgBody: &api.Body_Request{Request: &api.Request{ReqType: &api.Request_Set{
+			Set: &api.Set{UpdateObjs: []*api.Set_UpdateObject{updateObj}},
+		}}}},
+	}
+}
+
+func TestAuthorizeRequestPermissionDenied(t *testing.T) {
+	uc, _ := newTestUseCase(t, accessControlTestData)
+
+	uc.HandleMessage("ctrl-b", setRequest("set-1", map[string]string{"Description": "denied", "SoftwareVersion": "2.0"}))
+	sent := drainSentMessages(t, uc)
+	if len(sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(sent))
+	}
+	errBody := sent[0].msg.GetBody().GetError()
+	if errBody == nil || errBody.GetErrCode() != model.ErrCodePermissionDenied {
+		t.Fatalf("response = %v, want Error %d", sent[0].msg, model.ErrCodePermissionDenied)
+	}
+	var deniedPaths []string
+	for _, paramErr := range errBody.GetParamErrs() {
+		if paramErr.GetErrCode() != model.ErrCodePermissionDenied {
+			t.Errorf("param error %s code = %d, want %d", paramErr.GetParamPath(), paramErr.GetErrCode(), model.ErrCodePermissionDenied)
+		}
+		deniedPaths = append(deniedPaths, paramErr.GetParamPath())
+	}
+	sort.Strings(deniedPaths)
+	if len(deniedPaths) != 2 || deniedPaths[0] != "Device.DeviceInfo.Description" || deniedPaths[1] != "Device.DeviceInfo.SoftwareVersion" {
+		t.Errorf("denied paths = %v, want Description and SoftwareVersion", deniedPaths)
+	}
+
+	uc.DataRepo.Lock()
+	description := uc.getStringValue(model.PathDeviceInfo + "Description")
+	uc.DataRepo.Unlock()
+	if description != "router" {
+		t.Errorf("Description = %q after denied SET, want router", description)
+	}
+
+	// 拥有写权限的 controller 的请求正常处理
+	uc.HandleMessage("ctrl-a", setRequest("set-2", map[string]string{"Description": "allowed"}))
+	sent = drainSentMessages(t, uc)
+	if len(sent) != 1 || sent[0].msg.GetBody().GetResponse().GetSetResp() == nil {
+		t.Fatalf("sent %v, want one SET response", sent)
+	}
+	uc.DataRepo.Lock()
+	description = uc.getStringValue(model.PathDeviceInfo + "Description")
+	uc.DataRepo.Unlock()
+	if description != "allowed" {
+		t.Errorf("Description = %q after allowed SET, want allowed", description)
+	}
+}
+
+func TestFilterGetInstancesResp(t *testing.T) {
+	tests := []struct {
+		name   string
+		fromId string
+		want   map[string][]string // 实例路径 -> 返回的唯一键
+	}{
+		{"denied instance and unique key removed", "ctrl-b", map[string][]string{"Device.IP.Interface.1.": nil}},
+		{"configured controller denied by default", "ctrl-1", map[string][]string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, _ := newTestUseCase(t, accessControlTestData)
+			uc.HandleMessage(tt.fromId, &api.Msg{
+				Header: &api.Header{MsgId: "instances-1", MsgType: api.Header_GET_INSTANCES},
+				Body: &api.Body{MsgBody: &api.Body_Request{Request: &api.Request{ReqType: &api.Request_GetInstances{
+					GetInstances: &api.GetInstances{ObjPaths: []string{"Device.IP.Interface."}},
+				}}}},
+			})
+			sent := drainSentMessages(t, uc)
+			if len(sent) != 1 {
+				t.Fatalf("sent %d messages, want 1", len(sent))
+			}
+			results := sent[0].msg.GetBody().GetResponse().GetGetInstancesResp().GetReqPathResults()
+			if len(results) != 1 {
+				t.Fatalf("got %d requested path results, want 1", len(results))
+			}
+
+			got := make(map[string][]string)
+			for _, instance := range results[0].GetCurrInsts() {
+				var keys []string
+				for key := range instance.GetUniqueKeys() {
+					keys = append(keys, key)
+				}
+				got[instance.GetInstantiatedObjPath()] = keys
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("instances = %v, want %v", got, tt.want)
+			}
+			for path, keys := range tt.want {
+				if gotKeys, ok := got[path]; !ok || len(gotKeys) != len(keys) {
+					t.Errorf("instance %s unique keys = %v (present %v), want %v", path, gotKeys, ok, keys)
+				}
+			}
+		})
+	}
+}
+
+func TestFilterGetSupportedDMResp(t *testing.T) {
+	tests := []struct {
+		name       string
+		fromId     string
+		wantObj    bool
+		wantParams map[string]bool // 参数名 -> 是否返回
+	}{
+		{"denied parameter removed", "ctrl-b", true, map[string]bool{"SoftwareVersion": true, "SerialNumber": false}},
+		{"roles are unioned", "ctrl-a", true, map[string]bool{"SoftwareVersion": true, "SerialNumber": true}},
+		{"configured controller denied by default", "ctrl-1", false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, _ := newTestUseCase(t, accessControlTestData)
+			uc.HandleMessage(tt.fromId, &api.Msg{
+				Header: &api.Header{MsgId: "dm-1", MsgType: api.Header_GET_SUPPORTED_DM},
+				Body: &api.Body{MsgBody: &api.Body_Request{Request: &api.Request{ReqType: &api.Request_GetSupportedDm{
+					GetSupportedDm: &api.GetSupportedDM{ObjPaths: []string{model.PathDeviceInfo}, FirstLevelOnly: true, ReturnParams: true},
+				}}}},
+			})
+			sent := drainSentMessages(t, uc)
+			if len(sent) != 1 {
+				t.Fatalf("sent %d messages, want 1", len(sent))
+			}
+			results := sent[0].msg.GetBody().GetResponse().GetGetSupportedDmResp().GetReqObjResults()
+			if len(results) != 1 {
+				t.Fatalf("got %d requested object results, want 1", len(results))
+			}
+
+			var deviceInfo *api.GetSupportedDMResp_SupportedObjectResult
+			for _, obj := range results[0].GetSupportedObjs() {
+				if obj.GetSupportedObjPath() == model.PathDeviceInfo {
+					deviceInfo = obj
+				}
+			}
+			if (deviceInfo != nil) != tt.wantObj {
+				t.Fatalf("%s returned = %v, want %v", model.PathDeviceInfo, deviceInfo != nil, tt.wantObj)
+			}
+			if deviceInfo == nil {
+				return
+			}
+
+			params := make(map[string]bool)
+			for _, param := range deviceInfo.GetSupportedParams() {
+				params[param.GetParamName()] = true
+			}
+			for name, want := range tt.wantParams {
+				if params[name] != want {
+					t.Errorf("parameter %s returned = %v, want %v", name, params[name], want)
+				}
+			}
+		})
+	}
+}
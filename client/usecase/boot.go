@@ -0,0 +1,276 @@
+package usecase
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"tr369-wss-client/client/model"
+	logger "tr369-wss-client/log"
+	"tr369-wss-client/trtree"
+	"tr369-wss-client/utils"
+)
+
+// SetWSClient 设置 WebSocket 客户端，模拟重启时用于断开和重新建立连接
+func (uc *ClientUseCase) SetWSClient(wsClient model.WSClient) {
+	uc.wsClient = wsClient
+}
+
+// reboot 模拟设备重启
+func (uc *ClientUseCase) reboot(cause string, commandKey string) error {
+	return uc.restart(cause, commandKey, nil, false)
+}
+
+// factoryReset 恢复出厂设置：使用出厂数据模板还原数据并清除订阅，然后按重启流程重启
+func (uc *ClientUseCase) factoryReset(cause string, commandKey string) error {
+	return uc.restart(cause, commandKey, uc.restoreFactoryDefaults, false)
+}
+
+// bootEvent 等待 controller 确认的 Boot! 事件
+type bootEvent struct {
+	cause           string
+	commandKey      string
+	firmwareUpdated bool
+	msgId           string // 已发送且要求 NOTIFY_RESP 的 Notify 的 msg_id，未发送时为空
+}
+
+// PowerOn 进程启动时执行的开机流程（在连接 controller 之前调用）
+// factoryReset 为 true 时先恢复出厂设置（如命令行参数），Boot! 的 Cause 为 LocalFactoryReset，否则为 LocalReboot；
+// 记录重启原因、更新 BootCount 并开始调度 Periodic! 和订阅过期，Boot! 事件在连接建立后发送
+func (uc *ClientUseCase) PowerOn(factoryReset bool) error {
+	cause := model.BootCauseLocalReboot
+	if factoryReset {
+		cause = model.BootCauseLocalFactoryReset
+	}
+	logger.Infof("[USP] power on: cause=%s", cause)
+
+	uc.DataRepo.Lock()
+	var resetErr error
+	if factoryReset {
+		if resetErr = uc.restoreFactoryDefaults(); resetErr != nil {
+			logger.Warnf("[USP] power on factory reset error: err=%v", resetErr)
+		}
+	}
+	uc.recordRebootCause(cause, "")
+	uc.incrementBootCount()
+	uc.DataRepo.Flush()
+	uc.reschedulePeriodicNotifs()
+	uc.scheduleSubscriptionExpiries()
+	uc.setPendingBoots(bootEvent{cause: cause})
+	uc.DataRepo.Unlock()
+	return resetErr
+}
+
+// setPendingBoots 为每个启用的 controller 记录等待发送的 Boot! 事件，取代尚未确认的 Boot!（调用方需持有数据锁）
+func (uc *ClientUseCase) setPendingBoots(event bootEvent) {
+	uc.bootMu.Lock()
+	defer uc.bootMu.Unlock()
+
+	uc.pendingBoots = make(map[string]*bootEvent)
+	for _, endpoint := range uc.controllerEndpoints() {
+		boot := event
+		uc.pendingBoots[endpoint.EndpointID] = &boot
+	}
+}
+
+// OnConnected 与 controller 的连接建立后调用，向该 controller 发送尚未确认的 Boot! 事件
+// 要求 NOTIFY_RESP 的 Boot! 在收到响应后才清除，连接断开重连时如果该 Notify 已不再重试则重新发送；
+// 不要求响应的 Boot! 在发送后清除
+func (uc *ClientUseCase) OnConnected(endpointId string) {
+	uc.bootMu.Lock()
+	boot := uc.pendingBoots[endpointId]
+	uc.bootMu.Unlock()
+	if boot == nil {
+		return
+	}
+
+	uc.notifMu.Lock()
+	_, retrying := uc.pendingNotifs[boot.msgId]
+	uc.notifMu.Unlock()
+	if retrying {
+		return
+	}
+	uc.sendBootEvent(endpointId, boot)
+}
+
+// bootSent 记录发送给 controller 的 Boot! Notify，不要求响应时清除等待中的 Boot!
+func (uc *ClientUseCase) bootSent(endpointId string, boot *bootEvent, msgId string, awaitResp bool) {
+	uc.bootMu.Lock()
+	defer uc.bootMu.Unlock()
+
+	if uc.pendingBoots[endpointId] != boot {
+		return
+	}
+	if !awaitResp {
+		delete(uc.pendingBoots, endpointId)
+		return
+	}
+	boot.msgId = msgId
+}
+
+// completeBoot 收到 Boot! Notify 的 NOTIFY_RESP 后清除等待中的 Boot!
+func (uc *ClientUseCase) completeBoot(endpointId string, msgId string) {
+	uc.bootMu.Lock()
+	defer uc.bootMu.Unlock()
+
+	if boot, ok := uc.pendingBoots[endpointId]; ok && boot.msgId == msgId {
+		delete(uc.pendingBoots, endpointId)
+		logger.Infof("[USP] Boot! acknowledged: controller=%s, msgId=%s", endpointId, msgId)
+	}
+}
+
+// restart 模拟设备重启
+// 发送 DisconnectRecord 并断开连接，持久化数据后重新加载（相当于断电重启），reset 不为空时
+// 在重新加载前修改数据（如还原出厂数据、切换固件镜像）；在 Device.X_TP_RebootCause 中记录重启原因、更新 BootCount
+// 并按重新加载的数据调度 Periodic! 和订阅过期，
+// 重新连接后由 OnConnected 向各 controller 发送 Boot! 事件，firmwareUpdated 为 Boot! 的 FirmwareUpdated 参数；调用方不能持有数据锁
+func (uc *ClientUseCase) restart(cause string, commandKey string, reset func() error, firmwareUpdated bool) error {
+	logger.Infof("[USP] reboot: cause=%s, commandKey=%s", cause, commandKey)
+
+	var resetErr error
+	powerCycle := func() {
+		uc.DataRepo.Lock()
+		defer uc.DataRepo.Unlock()
+
+		if reset != nil {
+			if resetErr = reset(); resetErr != nil {
+				logger.Warnf("[USP] reboot reset error: cause=%s, err=%v", cause, resetErr)
+			}
+		}
+		uc.DataRepo.Flush()
+		uc.DataRepo.Reload()
+		uc.recordRebootCause(cause, commandKey)
+		uc.incrementBootCount()
+		uc.DataRepo.Flush()
+		uc.reschedulePeriodicNotifs()
+		uc.scheduleSubscriptionExpiries()
+		uc.setPendingBoots(bootEvent{cause: cause, commandKey: commandKey, firmwareUpdated: firmwareUpdated && resetErr == nil})
+	}
+
+	if uc.wsClient == nil {
+		powerCycle()
+		for _, endpoint := range uc.ControllerEndpoints() {
+			uc.OnConnected(endpoint.EndpointID)
+		}
+		return resetErr
+	}
+
+	uc.sendDisconnectRecord("agent rebooting")
+	if err := uc.wsClient.Restart(powerCycle); err != nil {
+		return err
+	}
+	return resetErr
+}
+
+// restoreFactoryDefaults 使用出厂数据模板还原数据（调用方需持有数据锁）
+// 运行时添加的订阅随数据一起被清除，监听器按出厂模板中自带的订阅（如 Boot! 事件订阅）重建
+func (uc *ClientUseCase) restoreFactoryDefaults() error {
+	if err := uc.DataRepo.FactoryReset(); err != nil {
+		return err
+	}
+	logger.Infof("[USP] factory defaults restored")
+	return nil
+}
+
+// recordRebootCause 记录重启原因（调用方需持有数据锁）
+// Reason 为本地触发的原因（如按键），远程重启时清空
+func (uc *ClientUseCase) recordRebootCause(cause string, commandKey string) {
+	values := map[string]string{
+		"Cause":      cause,
+		"CommandKey": commandKey,
+		"Reason":     "",
+	}
+	for key, value := range values {
+		if _, _, err := uc.DataRepo.SetValue(model.PathRebootCause, key, value); err != nil {
+			logger.Warnf("[USP] record reboot cause failed: path=%s%s, err=%v", model.PathRebootCause, key, err)
+		}
+	}
+}
+
+// incrementBootCount 启动次数加一（调用方需持有数据锁）
+func (uc *ClientUseCase) incrementBootCount() {
+	count, _ := strconv.Atoi(uc.getStringValue(model.PathDeviceInfo + model.ParamBootCount))
+	uc.DataRepo.SetValue(model.PathDeviceInfo, model.ParamBootCount, strconv.Itoa(count+1))
+}
+
+// sendDisconnectRecord 向每个连接的 controller 发送 DisconnectRecord（调用方不能持有数据锁）
+func (uc *ClientUseCase) sendDisconnectRecord(reason string) {
+	for _, endpoint := range uc.ControllerEndpoints() {
+		toId := endpoint.EndpointID
+		rec := utils.CreateDisconnectRecord(uc.negotiatedVersion(toId), toId, uc.Config.WebsocketConfig.EndpointId, reason, 0)
+		payload, err := utils.EncodeUspRecord(rec)
+		if err != nil {
+			logger.Warnf("[USP] DISCONNECT record encode error: controller=%s, err=%v", toId, err)
+			continue
+		}
+		if err := uc.transmit(toId, payload); err != nil {
+			logger.Warnf("[USP] DISCONNECT record error: controller=%s, err=%v", toId, err)
+			continue
+		}
+		logger.Infof("[USP] send DISCONNECT record: controller=%s, reason=%s", toId, reason)
+	}
+}
+
+// sendBootEvent 向 controller 发送 Boot! 事件（调用方不能持有数据锁）
+// ParameterMap 按接收方 controller 的 BootParameter 构建
+func (uc *ClientUseCase) sendBootEvent(endpointId string, boot *bootEvent) {
+	uc.DataRepo.Lock()
+	parameterMap := uc.bootParameterMap(endpointId)
+	uc.DataRepo.Unlock()
+
+	params := map[string]string{
+		"CommandKey":      boot.commandKey,
+		"Cause":           boot.cause,
+		"FirmwareUpdated": strconv.FormatBool(boot.firmwareUpdated),
+		"ParameterMap":    parameterMap,
+	}
+	uc.notifyControllerEvent(endpointId, model.PathDevice, model.BOOT, params, func(msgId string, awaitResp bool) {
+		uc.bootSent(endpointId, boot, msgId, awaitResp)
+	})
+}
+
+// bootParameterMap 根据 Device.LocalAgent.Controller.{i}.BootParameter.{i} 构建发送给 controller 的 Boot! 事件的 ParameterMap
+// 使用 EndpointID 与接收方 controller 匹配的 BootParameter，配置文件中的 controller 不在 Controller 表中时
+// 使用所有启用的 controller；ParameterName 支持对象路径、通配符和搜索表达式，结果编码为 JSON 对象
+func (uc *ClientUseCase) bootParameterMap(endpointId string) string {
+	params := uc.DataRepo.GetParameters()
+
+	var selected []string
+	if controller := uc.controllerInstance(endpointId); controller != "" {
+		selected = append(selected, controller)
+	} else if endpointId == uc.Config.WebsocketConfig.ControllerId {
+		controllers, _ := trtree.ResolveObjectPath(params, model.PathController+"*.")
+		for _, controller := range controllers {
+			if utils.IsTrue(uc.getStringValue(controller + "Enable")) {
+				selected = append(selected, controller)
+			}
+		}
+	}
+
+	values := make(map[string]string)
+	for _, controller := range selected {
+		bootParams, _ := trtree.ResolveObjectPath(params, controller+"BootParameter.*.")
+		for _, bootParam := range bootParams {
+			name := uc.getStringValue(bootParam + "ParameterName")
+			if !utils.IsTrue(uc.getStringValue(bootParam+"Enable")) || name == "" {
+				continue
+			}
+			results, err := trtree.ResolveGetPath(params, name, 0)
+			if err != nil {
+				logger.Warnf("[USP] Boot! parameter resolve error: path=%s, err=%v", name, err)
+				continue
+			}
+			for _, result := range results {
+				for param, value := range result.GetResultParams() {
+					values[result.GetResolvedPath()+param] = value
+				}
+			}
+		}
+	}
+
+	content, err := json.Marshal(values)
+	if err != nil {
+		logger.Warnf("[USP] Boot! ParameterMap encode error: err=%v", err)
+		return "{}"
+	}
+	return string(content)
+}
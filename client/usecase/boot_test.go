@@ -0,0 +1,158 @@
+package usecase
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"tr369-wss-client/client/model"
+)
+
+// bootTestData 重启测试使用的数据，Boot! 订阅发送给配置文件中的 controller
+const bootTestData = `{
+	"Device": {
+		"DeviceInfo": {"BootCount": "3"},
+		"X_TP_RebootCause": {"Cause": "LocalReboot", "CommandKey": "", "Reason": "Button"},
+		"LocalAgent": {
+			"Subscription": {
+				"1": {"Enable": "true", "ID": "boot", "NotifType": "Event", "ReferenceList": "Device.Boot!", "Persistent": "true"}
+			}
+		}
+	}
+}`
+
+func TestReboot(t *testing.T) {
+	uc, lm := newTestUseCase(t, bootTestData)
+
+	if err := uc.reboot(model.BootCauseRemoteReboot, "cmd-1"); err != nil {
+		t.Fatalf("reboot: %v", err)
+	}
+
+	uc.DataRepo.Lock()
+	want := map[string]string{
+		model.PathRebootCause + "Cause":             model.BootCauseRemoteReboot,
+		model.PathRebootCause + "CommandKey":        "cmd-1",
+		model.PathRebootCause + "Reason":            "",
+		model.PathDeviceInfo + model.ParamBootCount: "4",
+	}
+	for path, value := range want {
+		if got := uc.getStringValue(path); got != value {
+			t.Errorf("%s = %q, want %q", path, got, value)
+		}
+	}
+	uc.DataRepo.Unlock()
+
+	boots := lm.eventsNamed(model.BOOT)
+	if len(boots) != 1 {
+		t.Fatalf("got %d Boot! events, want 1", len(boots))
+	}
+	boot := boots[0]
+	if boot.Params["Cause"] != model.BootCauseRemoteReboot || boot.Params["CommandKey"] != "cmd-1" {
+		t.Errorf("Boot! params = %v, want Cause %s CommandKey cmd-1", boot.Params, model.BootCauseRemoteReboot)
+	}
+}
+
+func TestFactoryReset(t *testing.T) {
+	uc, _ := newTestUseCase(t, bootTestData)
+
+	template := `{"Device": {"DeviceInfo": {"BootCount": "0", "ProvisioningCode": "factory"}, "X_TP_RebootCause": {"Cause": "", "CommandKey": "", "Reason": ""}}}`
+	templatePath := filepath.Join(t.TempDir(), "factory.json")
+	if err := os.WriteFile(templatePath, []byte(template), 0644); err != nil {
+		t.Fatalf("write factory template: %v", err)
+	}
+	uc.Config.DataRefreshConfig.FactoryTemplatePath = templatePath
+
+	if err := uc.factoryReset(model.BootCauseRemoteFactoryReset, "cmd-2"); err != nil {
+		t.Fatalf("factoryReset: %v", err)
+	}
+
+	uc.DataRepo.Lock()
+	defer uc.DataRepo.Unlock()
+	want := map[string]string{
+		model.PathDeviceInfo + "ProvisioningCode":   "factory",
+		model.PathDeviceInfo + model.ParamBootCount: "1",
+		model.PathRebootCause + "Cause":             model.BootCauseRemoteFactoryReset,
+		model.PathRebootCause + "CommandKey":        "cmd-2",
+	}
+	for path, value := range want {
+		if got := uc.getStringValue(path); got != value {
+			t.Errorf("%s = %q, want %q", path, got, value)
+		}
+	}
+}
+
+func TestFactoryResetCommandWithoutTemplate(t *testing.T) {
+	uc, _ := newTestUseCase(t, bootTestData)
+
+	_, uspErr := uc.factoryResetCommand(context.Background(), &commandRequest{ObjPath: model.PathDevice, Name: "FactoryReset()"})
+	if uspErr == nil || uspErr.Code != model.ErrCodeCommandFailure {
+		t.Fatalf("factoryResetCommand without template = %v, want error %d", uspErr, model.ErrCodeCommandFailure)
+	}
+
+	uc.DataRepo.Lock()
+	defer uc.DataRepo.Unlock()
+	if got := uc.getStringValue(model.PathDeviceInfo + model.ParamBootCount); got != "3" {
+		t.Errorf("BootCount = %q after failed factory reset, want 3", got)
+	}
+}
+
+// deliverBoot 模拟订阅 subscriptionId 投递最近一次发给 controller endpointId 的 Boot! 事件
+func deliverBoot(t *testing.T, uc *ClientUseCase, lm *recordingListenerManager, endpointId string, subscriptionId string) {
+	t.Helper()
+	boots := lm.controllerEvents(endpointId, model.BOOT)
+	if len(boots) == 0 {
+		t.Fatal("no Boot! event to deliver")
+	}
+	uc.HandleEvent(subscriptionId, boots[len(boots)-1])
+}
+
+func TestPowerOnBootAfterConnect(t *testing.T) {
+	uc, lm := newTestUseCase(t, bootTestData)
+
+	if err := uc.PowerOn(false); err != nil {
+		t.Fatalf("PowerOn: %v", err)
+	}
+	if boots := lm.eventsNamed(model.BOOT); len(boots) != 0 {
+		t.Fatalf("got %d Boot! events before connect, want 0", len(boots))
+	}
+
+	// Boot! 发送后重连不再发送
+	uc.OnConnected("ctrl-1")
+	deliverBoot(t, uc, lm, "ctrl-1", "boot")
+	uc.OnConnected("ctrl-1")
+	boots := lm.eventsNamed(model.BOOT)
+	if len(boots) != 1 {
+		t.Fatalf("got %d Boot! events after reconnect, want 1", len(boots))
+	}
+	if boots[0].Params["Cause"] != model.BootCauseLocalReboot {
+		t.Errorf("Boot! Cause = %s, want %s", boots[0].Params["Cause"], model.BootCauseLocalReboot)
+	}
+
+	uc.DataRepo.Lock()
+	defer uc.DataRepo.Unlock()
+	if got := uc.getStringValue(model.PathRebootCause + "Cause"); got != model.BootCauseLocalReboot {
+		t.Errorf("RebootCause Cause = %q, want %s", got, model.BootCauseLocalReboot)
+	}
+	if got := uc.getStringValue(model.PathDeviceInfo + model.ParamBootCount); got != "4" {
+		t.Errorf("BootCount = %q, want 4", got)
+	}
+}
+
+func TestRebootReplacesPendingBoot(t *testing.T) {
+	uc, lm := newTestUseCase(t, bootTestData)
+
+	if err := uc.PowerOn(false); err != nil {
+		t.Fatalf("PowerOn: %v", err)
+	}
+	if err := uc.reboot(model.BootCauseRemoteReboot, "cmd-1"); err != nil {
+		t.Fatalf("reboot: %v", err)
+	}
+	deliverBoot(t, uc, lm, "ctrl-1", "boot")
+	uc.OnConnected("ctrl-1")
+
+	boots := lm.eventsNamed(model.BOOT)
+	if len(boots) != 1 || boots[0].Params["Cause"] != model.BootCauseRemoteReboot {
+		t.Fatalf("Boot! events = %v, want one RemoteReboot", boots)
+	}
+}
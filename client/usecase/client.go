@@ -2,6 +2,9 @@ package usecase
 
 import (
 	"context"
+	"net/http"
+	"sync"
+	"time"
 	"tr369-wss-client/client/model"
 	"tr369-wss-client/config"
 	logger "tr369-wss-client/log"
@@ -11,11 +14,34 @@ import (
 
 // ClientUseCase 客户端业务逻辑处理器
 type ClientUseCase struct {
-	Config         *config.Config
-	DataRepo       model.DataRepository  // 数据访问接口
-	ListenerMgr    model.ListenerManager // 监听器管理接口
-	ctx            context.Context
-	messageChannel chan []byte // 消息发送通道
+	Config      *config.Config
+	DataRepo    model.DataRepository      // 数据访问接口
+	ListenerMgr model.ListenerManager     // 监听器管理接口
+	SupportedDM model.SupportedDMRegistry // 支持的数据模型注册表
+	wsClient    model.WSClient            // 各 controller 的 WebSocket 连接，按 EndpointID 发送消息，模拟重启时使用
+	httpClient  *http.Client              // 文件传输（如固件下载）使用的 HTTP 客户端
+	pingers     pingerFactory             // 诊断命令创建 pinger 的函数，测试时替换为不访问网络的实现
+	ctx         context.Context
+
+	negotiatedVersions map[string]string // 每个 controller 协商后的协议版本
+	versionMu          sync.RWMutex
+
+	commands       map[string]*commandDef    // 支持的命令，key 为命令路径（实例编号用 {i} 表示）
+	activeRequests map[string]*activeRequest // 正在执行的异步命令，key 为 Request 实例路径
+	requestMu      sync.Mutex
+
+	bootTime     time.Time             // 启动时间，PeriodicNotifTime 未知时作为 Periodic! 的参考时间
+	pendingBoots map[string]*bootEvent // 等待各 controller 确认的 Boot! 事件，key 为 EndpointID
+	bootMu       sync.Mutex
+
+	periodicTimers map[string]*periodicTimer // Periodic! 定时器，key 为 controller 的 EndpointID
+	periodicMu     sync.Mutex
+
+	pendingNotifs map[string]*pendingNotif // 等待 NOTIFY_RESP 的通知，key 为 msg_id
+	notifMu       sync.Mutex
+
+	subscriptionExpiries map[string]*subscriptionExpiry // 订阅的 TimeToLive 过期定时器，key 为订阅实例路径
+	expiryMu             sync.Mutex
 }
 
 // NewClientUseCase creates a new client use case instance
@@ -24,59 +50,108 @@ func NewClientUseCase(
 	cfg *config.Config,
 	dataRepo model.DataRepository,
 	listenerMgr model.ListenerManager,
-	messageChannel chan []byte,
+	supportedDM model.SupportedDMRegistry,
 ) *ClientUseCase {
-	return &ClientUseCase{
-		ctx:            ctx,
-		Config:         cfg,
-		DataRepo:       dataRepo,
-		ListenerMgr:    listenerMgr,
-		messageChannel: messageChannel,
+	uc := &ClientUseCase{
+		ctx:         ctx,
+		Config:      cfg,
+		DataRepo:    dataRepo,
+		ListenerMgr: listenerMgr,
+		SupportedDM: supportedDM,
+
+		negotiatedVersions: make(map[string]string),
+		activeRequests:     make(map[string]*activeRequest),
+		bootTime:           time.Now(),
+		periodicTimers:     make(map[string]*periodicTimer),
+		pendingNotifs:      make(map[string]*pendingNotif),
+
+		subscriptionExpiries: make(map[string]*subscriptionExpiry),
 	}
+	uc.registerCommands()
+	uc.registerNotifHandlers()
+	dataRepo.SetDataChangeHandler(uc.refreshDynamicSubscriptions)
+	return uc
 }
 
 // HandleMessage processes incoming USP messages
-func (uc *ClientUseCase) HandleMessage(msg *api.Msg) {
-	// 防御性检查：检查 msg 是否为 nil
-	if msg == nil {
-		logger.Warnf("[USP] received nil message, ignoring")
+func (uc *ClientUseCase) HandleMessage(fromId string, msg *api.Msg) {
+	// 防御性检查：缺少消息头时无法分发，回复 Error 避免 controller 等待超时
+	if msg == nil || msg.Header == nil {
+		logger.Warnf("[USP] received message with nil header from %s", fromId)
+		uc.SendErrorMessage(fromId, "", model.ErrCodeInvalidArguments,
+			model.NewUSPError(model.ErrCodeInvalidArguments, "message header is missing").Message)
 		return
 	}
 
-	// 防御性检查：检查 msg.Header 是否为 nil
-	if msg.Header == nil {
-		logger.Warnf("[USP] received message with nil header, ignoring")
+	// 消息处理期间锁定数据模型，避免与异步命令等后台任务并发修改
+	uc.DataRepo.Lock()
+	defer uc.DataRepo.Unlock()
+
+	// 禁用的 controller 不接收任何消息，其请求直接丢弃
+	if !uc.controllerEnabled(fromId) {
+		logger.Warnf("[USP] dropped message from disabled controller %s: type=%v, msgId=%s", fromId, msg.Header.MsgType, msg.Header.MsgId)
 		return
 	}
 
-	// 根据消息类型处理不同的请求
+	// 按 ControllerTrust 的角色权限检查写操作和命令，被拒绝的请求不会分发到处理函数
+	if uspErr, paramErrs := uc.authorizeRequest(fromId, msg); uspErr != nil {
+		logger.Warnf("[USP] permission denied: controller=%s, type=%v, msgId=%s, err=%v", fromId, msg.Header.MsgType, msg.Header.MsgId, uspErr)
+		if err := uc.HandleMTPMsgTransmitTo(fromId, utils.CreateErrorMessage(msg.Header.MsgId, uspErr.Code, uspErr.Message, paramErrs)); err != nil {
+			logger.Warnf("[USP] ERROR message error: msgId=%s, err=%v", msg.Header.MsgId, err)
+		}
+		return
+	}
+
+	// 根据消息类型处理不同的请求，响应发送给请求的发送方
 	switch msg.Header.MsgType {
 	case api.Header_GET:
-		uc.HandleGetRequest(msg)
+		uc.HandleGetRequest(fromId, msg)
 	case api.Header_SET:
-		uc.HandleSetRequest(msg)
+		uc.HandleSetRequest(fromId, msg)
 	case api.Header_ADD:
-		uc.HandleAddRequest(msg)
+		uc.HandleAddRequest(fromId, msg)
 	case api.Header_DELETE:
-		uc.HandleDeleteRequest(msg)
+		uc.HandleDeleteRequest(fromId, msg)
 	case api.Header_OPERATE:
-		uc.HandleOperateRequest(msg)
+		uc.HandleOperateRequest(fromId, msg)
+	case api.Header_GET_SUPPORTED_DM:
+		uc.HandleGetSupportedDMRequest(fromId, msg)
+	case api.Header_GET_INSTANCES:
+		uc.HandleGetInstancesRequest(fromId, msg)
+	case api.Header_GET_SUPPORTED_PROTO:
+		uc.HandleGetSupportedProtoRequest(fromId, msg)
 	case api.Header_NOTIFY_RESP:
 		uc.HandleNotifyResp(msg)
+	case api.Header_ERROR:
+		uc.HandleErrorMessage(fromId, msg)
 	default:
 		logger.Warnf("[USP] UNKNOWN: unsupported message type=%v, msgId=%s", msg.Header.MsgType, msg.Header.MsgId)
+		// 不对响应类消息回复 Error，避免与 controller 之间形成消息循环
+		if !isResponseMsgType(msg.Header.MsgType) {
+			uc.SendErrorMessage(fromId, msg.Header.MsgId, model.ErrCodeMessageNotSupported,
+				model.NewUSPError(model.ErrCodeMessageNotSupported, "%v", msg.Header.MsgType).Message)
+		}
 	}
 }
 
-// HandleMTPMsgTransmit 发送 MTP 消息
-func (uc *ClientUseCase) HandleMTPMsgTransmit(msg *api.Msg) error {
-	rec := utils.CreateUspRecordNoSession(uc.Config.Tr369Config.Version, uc.Config.WebsocketConfig.EndpointId, uc.Config.WebsocketConfig.ControllerId, msg)
+// HandleMTPMsgTransmitTo 发送 MTP 消息到指定 controller
+// Record 版本使用与该 controller 协商后的协议版本
+func (uc *ClientUseCase) HandleMTPMsgTransmitTo(toId string, msg *api.Msg) error {
+	rec := utils.CreateUspRecordNoSession(uc.negotiatedVersion(toId), toId, uc.Config.WebsocketConfig.EndpointId, msg)
 	payload, err := utils.EncodeUspRecord(rec)
 	if err != nil {
 		return err
 	}
 
-	// 发送消息到通道
-	uc.messageChannel <- payload
-	return nil
+	// 响应超过消息大小上限时改为回复 Error，避免 controller 无法接收而等待超时
+	maxSize := uc.Config.WebsocketConfig.MaxMessageSize
+	msgType := msg.GetHeader().GetMsgType()
+	if maxSize > 0 && int64(len(payload)) > maxSize && msgType != api.Header_ERROR && isResponseMsgType(msgType) {
+		logger.Warnf("[USP] response too large: msgId=%s, size=%d, limit=%d", msg.GetHeader().GetMsgId(), len(payload), maxSize)
+		return uc.HandleMTPMsgTransmitTo(toId, utils.CreateErrorMessage(msg.GetHeader().GetMsgId(), model.ErrCodeResourcesExceeded,
+			model.NewUSPError(model.ErrCodeResourcesExceeded, "response size %d exceeds limit %d", len(payload), maxSize).Message, nil))
+	}
+
+	// 发送消息到 controller 的发送队列
+	return uc.transmit(toId, payload)
 }
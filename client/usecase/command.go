@@ -1,81 +1,40 @@
 package usecase
 
 import (
+	"context"
+
 	"tr369-wss-client/client/model"
-	logger "tr369-wss-client/log"
-	"tr369-wss-client/pkg/api"
-	"tr369-wss-client/utils"
 )
 
-// HandleCommand handles command
-func (uc *ClientUseCase) HandleCommand(operate *api.Operate, msgId string) {
-	command := operate.GetCommand()
-
-	switch command {
-	case model.DeviceReboot:
-		// 处理boot
-		uc.HandleDeviceBoot(operate, msgId)
+// rebootCommand Device.Reboot() 模拟重启设备（异步命令）
+// 命令在设备重新连接并发送 Boot! 事件后完成
+func (uc *ClientUseCase) rebootCommand(ctx context.Context, req *commandRequest) (map[string]string, *model.USPError) {
+	select {
+	case <-ctx.Done():
+		return nil, commandCanceled(req)
 	default:
-		logger.Infof("[USP] unknown command: %s", command)
+	}
 
+	if err := uc.reboot(model.BootCauseRemoteReboot, req.CommandKey); err != nil {
+		return nil, model.NewUSPError(model.ErrCodeCommandFailure, "reboot failed: %v", err)
 	}
+	return map[string]string{}, nil
 }
 
-func (uc *ClientUseCase) HandleDeviceBoot(operate *api.Operate, msgId string) {
-
-	// 构建resp
-	var operationResults []*api.OperateResp_OperationResult
-
-	operationResult := &api.OperateResp_OperationResult{
-		ExecutedCommand: operate.GetCommand(),
-		OperationResp: &api.OperateResp_OperationResult_ReqObjPath{
-			ReqObjPath: "Device.LocalAgent.Request.1",
-		},
+// factoryResetCommand Device.FactoryReset() 恢复出厂设置（异步命令）
+// 命令在设备重新连接并发送 Cause 为 RemoteFactoryReset 的 Boot! 事件后完成
+func (uc *ClientUseCase) factoryResetCommand(ctx context.Context, req *commandRequest) (map[string]string, *model.USPError) {
+	select {
+	case <-ctx.Done():
+		return nil, commandCanceled(req)
+	default:
 	}
-	operationResults = append(operationResults, operationResult)
-
-	msg := utils.CreateOperateResponseMessage(msgId, operationResults)
-	logger.Infof("[USP] send OPERATE response: %s", msg.String())
 
-	err := uc.HandleMTPMsgTransmit(msg)
-	if err != nil {
-		logger.Warnf("[USP] OPERATE error: msgId=%s, err=%v", msgId, err)
+	if uc.Config.DataRefreshConfig.FactoryTemplatePath == "" {
+		return nil, model.NewUSPError(model.ErrCodeCommandFailure, "factory template is not configured")
 	}
-
-	// 发送Boot! 事件
-	// 构建boot事件参数
-	params := map[string]string{
-		"CommandKey":      "",
-		"Cause":           "RemoteReboot",
-		"Reason":          "",
-		"FirmwareUpdated": "false",
-		"ParameterMap":    "",
+	if err := uc.factoryReset(model.BootCauseRemoteFactoryReset, req.CommandKey); err != nil {
+		return nil, model.NewUSPError(model.ErrCodeCommandFailure, "factory reset failed: %v", err)
 	}
-
-	uc.notifyEvent(model.DeviceReboot, model.BOOT, params)
+	return map[string]string{}, nil
 }
-
-//func (uc *ClientUseCase) HandleUrlUpgrade(operate *api.Operate, msgId string) {
-//
-//	// 构建resp
-//	var operationResults []*api.OperateResp_OperationResult
-//
-//	operationResult := &api.OperateResp_OperationResult{
-//		ExecutedCommand: operate.GetCommand(),
-//		OperationResp: &api.OperateResp_OperationResult_ReqObjPath{
-//			ReqObjPath: "Device.LocalAgent.Request.1",
-//		},
-//	}
-//	operationResults = append(operationResults, operationResult)
-//
-//	msg := utils.CreateOperateResponseMessage(msgId, operationResults)
-//	logger.Infof("[USP] send OPERATE response: %s", msg.String())
-//
-//	err := uc.HandleMTPMsgTransmit(msg)
-//	if err != nil {
-//		logger.Warnf("[USP] OPERATE error: msgId=%s, err=%v", msgId, err)
-//	}
-//
-//	// 发送升级事件
-//	uc.notifyOperComplete("", operate.GetCommandKey(), operate.GetCommand())
-//}
@@ -0,0 +1,254 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"text/template"
+	"time"
+
+	"tr369-wss-client/client/model"
+	logger "tr369-wss-client/log"
+)
+
+// descriptorCommand 由命令描述生成的命令，模板在注册时解析
+type descriptorCommand struct {
+	desc       *model.CommandDescriptor
+	outputArgs map[string]*template.Template
+	mutations  []*descriptorMutation
+}
+
+// descriptorMutation 命令成功后修改的参数
+type descriptorMutation struct {
+	path  *template.Template
+	value *template.Template
+}
+
+// descriptorTemplateData 模板中可以引用的数据
+type descriptorTemplateData struct {
+	ObjPath    string            // 命令所属对象的实际路径
+	Command    string            // 命令的完整路径
+	CommandKey string            // controller 指定的 CommandKey
+	Input      map[string]string // 输入参数（已补全默认值）
+}
+
+// RegisterCommandDescriptors 注册声明式命令，与内置命令重名的描述会被忽略
+func (uc *ClientUseCase) RegisterCommandDescriptors(descriptors []*model.CommandDescriptor) error {
+	for _, desc := range descriptors {
+		if _, ok := uc.commands[desc.Command]; ok {
+			logger.Warnf("[USP] command descriptor ignored, built-in command exists: %s", desc.Command)
+			continue
+		}
+
+		cmd, err := uc.newDescriptorCommand(desc)
+		if err != nil {
+			return fmt.Errorf("command descriptor %s: %w", desc.Command, err)
+		}
+		async := desc.Type == model.CommandTypeAsync
+		uc.commands[desc.Command] = &commandDef{
+			async: async,
+			handler: func(ctx context.Context, req *commandRequest) (map[string]string, *model.USPError) {
+				return uc.runDescriptorCommand(ctx, cmd, req, async)
+			},
+			validate: func(inputArgs map[string]string) *model.USPError {
+				_, uspErr := descriptorInput(desc, inputArgs)
+				return uspErr
+			},
+		}
+		logger.Infof("[USP] command descriptor registered: %s (%s)", desc.Command, desc.Type)
+	}
+	return nil
+}
+
+// newDescriptorCommand 解析命令描述中的模板
+func (uc *ClientUseCase) newDescriptorCommand(desc *model.CommandDescriptor) (*descriptorCommand, error) {
+	cmd := &descriptorCommand{
+		desc:       desc,
+		outputArgs: make(map[string]*template.Template),
+	}
+	for name, text := range desc.OutputArgs {
+		tmpl, err := uc.parseDescriptorTemplate(name, text)
+		if err != nil {
+			return nil, err
+		}
+		cmd.outputArgs[name] = tmpl
+	}
+	for path, value := range desc.Mutations {
+		pathTmpl, err := uc.parseDescriptorTemplate("mutation path", path)
+		if err != nil {
+			return nil, err
+		}
+		valueTmpl, err := uc.parseDescriptorTemplate(path, value)
+		if err != nil {
+			return nil, err
+		}
+		cmd.mutations = append(cmd.mutations, &descriptorMutation{path: pathTmpl, value: valueTmpl})
+	}
+	return cmd, nil
+}
+
+// parseDescriptorTemplate 解析模板，支持的函数：
+//   - param：获取参数值，不以 "Device." 开头的路径相对于命令所属对象，如 {{param "Status"}}
+//   - now：当前 UTC 时间（RFC3339）
+//   - randInt：[min, max] 范围内的随机整数
+//
+// param 在渲染时才绑定命令所属对象，这里使用占位实现完成语法检查
+func (uc *ClientUseCase) parseDescriptorTemplate(name string, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=zero").Funcs(template.FuncMap{
+		"param":   func(string) string { return "" },
+		"now":     func() string { return time.Now().UTC().Format(time.RFC3339) },
+		"randInt": func(minValue int, maxValue int) int { return minValue + rand.Intn(max(maxValue-minValue+1, 1)) },
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template %q: %w", text, err)
+	}
+	return tmpl, nil
+}
+
+// runDescriptorCommand 执行声明式命令：补全输入参数默认值、等待模拟时长、按概率模拟失败，
+// 成功时修改数据模型并返回输出参数；输入参数已在分发前校验。
+// 异步命令的修改通过 updateParams 写入（与其他后台任务一致），同步命令使用 setParams 并发送 ValueChange 通知。
+// 同步命令在消息处理流程中执行（已持有数据锁），加载时保证同步命令没有模拟时长，不会阻塞其他请求
+func (uc *ClientUseCase) runDescriptorCommand(ctx context.Context, cmd *descriptorCommand, req *commandRequest, async bool) (map[string]string, *model.USPError) {
+	input, uspErr := descriptorInput(cmd.desc, req.InputArgs)
+	if uspErr != nil {
+		return nil, uspErr
+	}
+
+	if cmd.desc.DurationMs > 0 && !sleepContext(ctx, time.Duration(cmd.desc.DurationMs)*time.Millisecond) {
+		return nil, commandCanceled(req)
+	}
+
+	if failure := cmd.desc.Failure; failure != nil && rand.Float64() < failure.Probability {
+		message := failure.ErrMsg
+		if message == "" {
+			message = "simulated failure"
+		}
+		return nil, model.NewUSPError(failure.ErrCode, "%s: %s", req.Command(), message)
+	}
+
+	data := &descriptorTemplateData{
+		ObjPath:    req.ObjPath,
+		Command:    req.Command(),
+		CommandKey: req.CommandKey,
+		Input:      input,
+	}
+	// 同步命令已持有数据锁，异步命令在后台执行，需要自行加锁
+	withDataLock := func(fn func()) {
+		if async {
+			uc.DataRepo.Lock()
+			defer uc.DataRepo.Unlock()
+		}
+		fn()
+	}
+
+	var mutations map[string]map[string]string
+	withDataLock(func() { mutations, uspErr = uc.resolveDescriptorMutations(cmd, req, data) })
+	if uspErr != nil {
+		return nil, uspErr
+	}
+	for objPath, values := range mutations {
+		if async {
+			uc.updateParams(objPath, values)
+		} else {
+			uc.notifyValueChanges(uc.setParams(objPath, values))
+		}
+	}
+
+	var outputArgs map[string]string
+	withDataLock(func() { outputArgs, uspErr = uc.renderDescriptorOutputs(cmd, req, data) })
+	return outputArgs, uspErr
+}
+
+// resolveDescriptorMutations 渲染并校验所有修改目标，返回对象路径 -> 参数名 -> 新值（调用方需持有数据锁）
+// 所有修改目标都校验通过后才写入数据，任一目标不存在时不修改任何参数
+func (uc *ClientUseCase) resolveDescriptorMutations(cmd *descriptorCommand, req *commandRequest, data *descriptorTemplateData) (map[string]map[string]string, *model.USPError) {
+	mutations := make(map[string]map[string]string)
+	for _, mutation := range cmd.mutations {
+		path, err := uc.renderDescriptorTemplate(mutation.path, req.ObjPath, data)
+		if err != nil {
+			return nil, model.NewUSPError(model.ErrCodeCommandFailure, "render mutation path: %v", err)
+		}
+		if !strings.HasPrefix(path, "Device.") {
+			path = req.ObjPath + path
+		}
+		index := strings.LastIndex(path, ".")
+		if index == len(path)-1 {
+			return nil, model.NewUSPError(model.ErrCodeCommandFailure, "mutation target %s is not a parameter", path)
+		}
+		objPath := path[:index+1]
+		obj, err := uc.DataRepo.GetValue(objPath)
+		if _, isObject := obj.(map[string]interface{}); err != nil || !isObject {
+			return nil, model.NewUSPError(model.ErrCodeCommandFailure, "mutation target %s does not exist", path)
+		}
+		value, err := uc.renderDescriptorTemplate(mutation.value, req.ObjPath, data)
+		if err != nil {
+			return nil, model.NewUSPError(model.ErrCodeCommandFailure, "render mutation %s: %v", path, err)
+		}
+		if mutations[objPath] == nil {
+			mutations[objPath] = make(map[string]string)
+		}
+		mutations[objPath][path[index+1:]] = value
+	}
+	return mutations, nil
+}
+
+// renderDescriptorOutputs 渲染输出参数（调用方需持有数据锁），在修改之后渲染，可以引用修改后的值
+func (uc *ClientUseCase) renderDescriptorOutputs(cmd *descriptorCommand, req *commandRequest, data *descriptorTemplateData) (map[string]string, *model.USPError) {
+	outputArgs := make(map[string]string, len(cmd.outputArgs))
+	for name, tmpl := range cmd.outputArgs {
+		value, err := uc.renderDescriptorTemplate(tmpl, req.ObjPath, data)
+		if err != nil {
+			return nil, model.NewUSPError(model.ErrCodeCommandFailure, "render output argument %s: %v", name, err)
+		}
+		outputArgs[name] = value
+	}
+	return outputArgs, nil
+}
+
+// renderDescriptorTemplate 渲染模板，param 函数读取当前数据模型（调用方需持有数据锁）
+func (uc *ClientUseCase) renderDescriptorTemplate(tmpl *template.Template, objPath string, data *descriptorTemplateData) (string, error) {
+	clone, err := tmpl.Clone()
+	if err != nil {
+		return "", err
+	}
+	clone.Funcs(template.FuncMap{
+		"param": func(path string) string {
+			if !strings.HasPrefix(path, "Device.") {
+				path = objPath + path
+			}
+			return uc.getStringValue(path)
+		},
+	})
+
+	var buf bytes.Buffer
+	if err := clone.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// descriptorInput 校验输入参数的必选项和数据类型，返回补全默认值后的输入参数
+func descriptorInput(desc *model.CommandDescriptor, inputArgs map[string]string) (map[string]string, *model.USPError) {
+	input := make(map[string]string, len(desc.InputArgs))
+	for name, value := range inputArgs {
+		input[name] = value
+	}
+
+	for name, arg := range desc.InputArgs {
+		value, ok := input[name]
+		if !ok {
+			if arg.Required {
+				return nil, model.NewUSPError(model.ErrCodeInvalidCommandArgs, "missing required input argument %s", name)
+			}
+			input[name] = arg.Default
+			continue
+		}
+		if !isValidParamValue(arg.Type, value) {
+			return nil, model.NewUSPError(model.ErrCodeInvalidCommandArgs, "%s expects %s, got %q", name, arg.Type, value)
+		}
+	}
+	return input, nil
+}
@@ -0,0 +1,124 @@
+package usecase
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"tr369-wss-client/client/model"
+)
+
+// descriptorTestData 声明式命令测试使用的数据
+const descriptorTestData = `{
+	"Device": {
+		"LEDs": {
+			"X_TP_TimeControl": {"Enable": "false", "start_hour": "22"},
+			"LED": {"1": {"CurrentCycleElement": {"Color": "000000"}}}
+		}
+	}
+}`
+
+// runDescriptor 注册 desc 并在 objPath 上执行 X_TP_Test()，同步命令在数据锁内执行（与消息处理流程一致）
+func runDescriptor(t *testing.T, uc *ClientUseCase, desc *model.CommandDescriptor, objPath string, inputArgs map[string]string) (map[string]string, *model.USPError) {
+	t.Helper()
+	if err := uc.RegisterCommandDescriptors([]*model.CommandDescriptor{desc}); err != nil {
+		t.Fatalf("RegisterCommandDescriptors: %v", err)
+	}
+	def := uc.commands[desc.Command]
+	if uspErr := def.validate(inputArgs); uspErr != nil {
+		return nil, uspErr
+	}
+
+	req := &commandRequest{ObjPath: objPath, Name: "X_TP_Test()", InputArgs: inputArgs}
+	if !def.async {
+		uc.DataRepo.Lock()
+		defer uc.DataRepo.Unlock()
+	}
+	return def.handler(context.Background(), req)
+}
+
+func TestDescriptorCommandMutations(t *testing.T) {
+	tests := []struct {
+		name      string
+		cmdType   string
+		objPath   string
+		mutations map[string]string
+		errCode   uint32
+		changes   map[string][]string
+		output    string
+	}{
+		{
+			name:      "sync relative path",
+			cmdType:   model.CommandTypeSync,
+			objPath:   "Device.LEDs.",
+			mutations: map[string]string{"X_TP_TimeControl.Enable": "{{.Input.Enable}}", "X_TP_TimeControl.start_hour": "22"},
+			changes:   map[string][]string{"Device.LEDs.X_TP_TimeControl.Enable": {"true"}, "Device.LEDs.X_TP_TimeControl.start_hour": nil},
+			output:    "true",
+		},
+		{
+			name:      "async instance path",
+			cmdType:   model.CommandTypeAsync,
+			objPath:   "Device.LEDs.LED.1.",
+			mutations: map[string]string{"CurrentCycleElement.Color": "00FF00", "Device.LEDs.X_TP_TimeControl.Enable": "{{.Input.Enable}}"},
+			changes: map[string][]string{
+				"Device.LEDs.LED.1.CurrentCycleElement.Color": {"00FF00"},
+				"Device.LEDs.X_TP_TimeControl.Enable":         {"true"},
+			},
+			output: "true",
+		},
+		{
+			name:      "missing target leaves data unchanged",
+			cmdType:   model.CommandTypeAsync,
+			objPath:   "Device.LEDs.",
+			mutations: map[string]string{"X_TP_TimeControl.Enable": "true", "X_TP_Missing.Enable": "true"},
+			errCode:   model.ErrCodeCommandFailure,
+			changes:   map[string][]string{"Device.LEDs.X_TP_TimeControl.Enable": nil},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, lm := newTestUseCase(t, descriptorTestData)
+
+			desc := &model.CommandDescriptor{
+				Command:    tt.objPath + "X_TP_Test()",
+				Type:       tt.cmdType,
+				InputArgs:  map[string]*model.CommandArgDef{"Enable": {Type: model.ParamTypeBoolean, Required: true}},
+				OutputArgs: map[string]string{"Enable": `{{param "Device.LEDs.X_TP_TimeControl.Enable"}}`},
+				Mutations:  tt.mutations,
+			}
+			outputArgs, uspErr := runDescriptor(t, uc, desc, tt.objPath, map[string]string{"Enable": "true"})
+			if tt.errCode != 0 {
+				if uspErr == nil || uspErr.Code != tt.errCode {
+					t.Fatalf("command error = %v, want code %d", uspErr, tt.errCode)
+				}
+			} else if uspErr != nil {
+				t.Fatalf("command error: %v", uspErr)
+			}
+
+			for paramPath, want := range tt.changes {
+				if got := lm.valueChanges(paramPath); !reflect.DeepEqual(got, want) {
+					t.Errorf("%s changes = %v, want %v", paramPath, got, want)
+				}
+			}
+			if outputArgs["Enable"] != tt.output {
+				t.Errorf("output Enable = %q, want %q", outputArgs["Enable"], tt.output)
+			}
+		})
+	}
+}
+
+func TestDescriptorCommandValidate(t *testing.T) {
+	uc, _ := newTestUseCase(t, descriptorTestData)
+
+	desc := &model.CommandDescriptor{
+		Command:   "Device.LEDs.X_TP_Test()",
+		Type:      model.CommandTypeSync,
+		InputArgs: map[string]*model.CommandArgDef{"Enable": {Type: model.ParamTypeBoolean, Required: true}},
+	}
+	for _, inputArgs := range []map[string]string{{}, {"Enable": "maybe"}} {
+		if _, uspErr := runDescriptor(t, uc, desc, "Device.LEDs.", inputArgs); uspErr == nil || uspErr.Code != model.ErrCodeInvalidCommandArgs {
+			t.Errorf("X_TP_Test(%v) error = %v, want code %d", inputArgs, uspErr, model.ErrCodeInvalidCommandArgs)
+		}
+	}
+}
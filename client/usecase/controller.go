@@ -0,0 +1,214 @@
+package usecase
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"tr369-wss-client/client/model"
+	"tr369-wss-client/trtree"
+	"tr369-wss-client/utils"
+)
+
+// controllerParams 影响 controller 连接和 Periodic! 调度的 Controller 参数
+var controllerParams = map[string]bool{
+	"Enable":                true,
+	"EndpointID":            true,
+	"PeriodicNotifInterval": true,
+	"PeriodicNotifTime":     true,
+}
+
+// isControllerParam 判断参数变化是否需要重新建立 controller 连接或重新调度 Periodic!
+// 包括 Controller.{i} 的 Enable、EndpointID、Periodic! 参数和 Controller.{i}.MTP.{i} 下的所有参数
+func isControllerParam(paramPath string) bool {
+	if !strings.HasPrefix(paramPath, model.PathController) {
+		return false
+	}
+	segments := strings.Split(strings.TrimPrefix(paramPath, model.PathController), ".")
+	if len(segments) == 2 {
+		return controllerParams[segments[1]]
+	}
+	return len(segments) > 2 && segments[1] == "MTP"
+}
+
+// ControllerEndpoints 返回 Agent 需要连接的 controller
+func (uc *ClientUseCase) ControllerEndpoints() []model.ControllerEndpoint {
+	uc.DataRepo.Lock()
+	defer uc.DataRepo.Unlock()
+	return uc.controllerEndpoints()
+}
+
+// controllerEndpoints 返回启用的、可以通过 WebSocket 连接的 controller（调用方需持有数据锁）
+// 配置文件中的 controller 使用配置的 ServerURL，不在 Controller 表中时同样连接；
+// 其他 controller 使用第一个启用的 WebSocket MTP 的 Host、Port 和 Path
+func (uc *ClientUseCase) controllerEndpoints() []model.ControllerEndpoint {
+	configId := uc.Config.WebsocketConfig.ControllerId
+	controllers, _ := trtree.ResolveObjectPath(uc.DataRepo.GetParameters(), model.PathController+"*.")
+
+	var endpoints []model.ControllerEndpoint
+	seen := make(map[string]bool)
+	for _, controller := range controllers {
+		endpointId := uc.getStringValue(controller + "EndpointID")
+		if endpointId == "" || seen[endpointId] || !utils.IsTrue(uc.getStringValue(controller+"Enable")) {
+			continue
+		}
+		mtp := uc.controllerWebSocketMTP(controller)
+		connectUrl := uc.controllerURL(mtp)
+		if endpointId == configId {
+			connectUrl = uc.Config.WebsocketConfig.ServerURL
+		}
+		if connectUrl == "" {
+			continue
+		}
+		seen[endpointId] = true
+		endpoint := model.ControllerEndpoint{EndpointID: endpointId, URL: connectUrl, Instance: controller}
+		uc.setRetryParams(&endpoint, mtp)
+		endpoints = append(endpoints, endpoint)
+	}
+
+	if !seen[configId] && uc.controllerInstance(configId) == "" {
+		endpoint := model.ControllerEndpoint{EndpointID: configId, URL: uc.Config.WebsocketConfig.ServerURL}
+		uc.setRetryParams(&endpoint, "")
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints
+}
+
+// controllerWebSocketMTP 返回 controller 第一个启用的、配置了 Host 的 WebSocket MTP 实例路径，没有时返回空字符串（调用方需持有数据锁）
+func (uc *ClientUseCase) controllerWebSocketMTP(controller string) string {
+	mtps, _ := trtree.ResolveObjectPath(uc.DataRepo.GetParameters(), controller+"MTP.*.")
+	for _, mtp := range mtps {
+		if utils.IsTrue(uc.getStringValue(mtp+"Enable")) && uc.getStringValue(mtp+"Protocol") == "WebSocket" && uc.getStringValue(mtp+"WebSocket.Host") != "" {
+			return mtp
+		}
+	}
+	return ""
+}
+
+// controllerURL 根据 WebSocket MTP 构建连接地址，mtp 为空时返回空字符串（调用方需持有数据锁）
+func (uc *ClientUseCase) controllerURL(mtp string) string {
+	if mtp == "" {
+		return ""
+	}
+	host := uc.getStringValue(mtp + "WebSocket.Host")
+	scheme := "ws"
+	if utils.IsTrue(uc.getStringValue(mtp + "WebSocket.EnableEncryption")) {
+		scheme = "wss"
+	}
+	if port := uc.getStringValue(mtp + "WebSocket.Port"); port != "" {
+		host = net.JoinHostPort(host, port)
+	}
+	connectUrl := url.URL{Scheme: scheme, Host: host, Path: "/" + strings.TrimPrefix(uc.getStringValue(mtp+"WebSocket.Path"), "/")}
+	return connectUrl.String()
+}
+
+// setRetryParams 按 WebSocket MTP 的 SessionRetryMinimumWaitInterval 和 SessionRetryIntervalMultiplier 设置重连参数，
+// 没有 MTP 或参数无效时使用 TR-181 的默认值（调用方需持有数据锁）
+func (uc *ClientUseCase) setRetryParams(endpoint *model.ControllerEndpoint, mtp string) {
+	endpoint.RetryMinimumWait = model.DefaultRetryMinimumWait
+	endpoint.RetryIntervalMultiplier = model.DefaultRetryIntervalMultiplier
+	if mtp == "" {
+		return
+	}
+	if wait, err := strconv.ParseUint(uc.getStringValue(mtp+"WebSocket.SessionRetryMinimumWaitInterval"), 10, 32); err == nil && wait > 0 {
+		endpoint.RetryMinimumWait = time.Duration(wait) * time.Second
+	}
+	if multiplier, err := strconv.ParseUint(uc.getStringValue(mtp+"WebSocket.SessionRetryIntervalMultiplier"), 10, 32); err == nil && multiplier >= 1000 {
+		endpoint.RetryIntervalMultiplier = multiplier
+	}
+}
+
+// controllerInstance 根据 EndpointID 查找 controller 实例路径，不存在时返回空字符串（调用方需持有数据锁）
+func (uc *ClientUseCase) controllerInstance(endpointId string) string {
+	if endpointId == "" {
+		return ""
+	}
+	controllers, _ := trtree.ResolveObjectPath(uc.DataRepo.GetParameters(), model.PathController+"*.")
+	for _, controller := range controllers {
+		if uc.getStringValue(controller+"EndpointID") == endpointId {
+			return controller
+		}
+	}
+	return ""
+}
+
+// controllerSettings 返回 controller 的配置（如 Periodic!、通知重试参数）所在的实例路径（调用方需持有数据锁）
+// 配置文件中的 controller 不在 Controller 表中时使用第一个启用的 controller 的配置
+func (uc *ClientUseCase) controllerSettings(endpointId string) string {
+	if instance := uc.controllerInstance(endpointId); instance != "" || endpointId != uc.Config.WebsocketConfig.ControllerId {
+		return instance
+	}
+	controllers, _ := trtree.ResolveObjectPath(uc.DataRepo.GetParameters(), model.PathController+"*.")
+	for _, controller := range controllers {
+		if utils.IsTrue(uc.getStringValue(controller + "Enable")) {
+			return controller
+		}
+	}
+	return ""
+}
+
+// controllerEnabled 判断是否可以与 controller 通信（调用方需持有数据锁）
+// Controller 表中禁用的 controller 不接收任何消息；不在表中的 endpoint（如配置文件中的 controller）视为启用
+func (uc *ClientUseCase) controllerEnabled(endpointId string) bool {
+	instance := uc.controllerInstance(endpointId)
+	return instance == "" || utils.IsTrue(uc.getStringValue(instance+"Enable"))
+}
+
+// subscriptionRecipient 返回订阅接收方 controller 的 EndpointID（调用方需持有数据锁）
+// Recipient 为空时发送给配置文件中的 controller；接收方不存在或已禁用时返回空字符串，不发送通知
+func (uc *ClientUseCase) subscriptionRecipient(subscription string) string {
+	recipient := uc.getStringValue(subscription + "Recipient")
+	if recipient == "" {
+		if configId := uc.Config.WebsocketConfig.ControllerId; uc.controllerEnabled(configId) {
+			return configId
+		}
+		return ""
+	}
+
+	if !strings.HasSuffix(recipient, ".") {
+		recipient += "."
+	}
+	if !strings.HasPrefix(recipient, model.PathController) {
+		return ""
+	}
+	if obj, err := uc.DataRepo.GetValue(recipient); err != nil {
+		return ""
+	} else if _, isObject := obj.(map[string]interface{}); !isObject {
+		return ""
+	}
+	if !utils.IsTrue(uc.getStringValue(recipient + "Enable")) {
+		return ""
+	}
+	return uc.recipientEndpoint(recipient)
+}
+
+// recipientEndpoint 返回发送给 Controller 实例的消息使用的 EndpointID（调用方需持有数据锁）
+// 配置文件中的 controller 不在 Controller 表中时，代替提供其配置的 controller（见 controllerSettings）接收消息，
+// 该 controller 自身可以通过 WebSocket MTP 连接时除外
+func (uc *ClientUseCase) recipientEndpoint(controller string) string {
+	configId := uc.Config.WebsocketConfig.ControllerId
+	if uc.controllerInstance(configId) == "" && controller == uc.controllerSettings(configId) && uc.controllerWebSocketMTP(controller) == "" {
+		return configId
+	}
+	return uc.getStringValue(controller + "EndpointID")
+}
+
+// controllersChanged Controller 表变化后重新调度 Periodic! 并同步 controller 连接（调用方需持有数据锁）
+// 连接在后台建立，避免消息处理期间等待网络
+func (uc *ClientUseCase) controllersChanged() {
+	uc.reschedulePeriodicNotifs()
+	if uc.wsClient != nil {
+		go uc.wsClient.SyncControllers()
+	}
+}
+
+// transmit 将编码后的 Record 放入 controller 的发送队列
+func (uc *ClientUseCase) transmit(toId string, payload []byte) error {
+	if uc.wsClient == nil {
+		return fmt.Errorf("no MTP connection to controller %s", toId)
+	}
+	return uc.wsClient.Send(toId, payload)
+}
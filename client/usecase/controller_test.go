@@ -0,0 +1,119 @@
+package usecase
+
+import (
+	"testing"
+
+	"tr369-wss-client/client/model"
+	"tr369-wss-client/pkg/api"
+)
+
+// controllerTestData 配置文件中的 controller（ctrl-1）不在 Controller 表中：
+// Controller.1 只有 MQTT MTP，Controller.2 可以通过 WebSocket 连接，Controller.3 已禁用
+const controllerTestData = `{
+	"Device": {
+		"DeviceInfo": {"BootCount": "0"},
+		"X_TP_RebootCause": {"Cause": "", "CommandKey": "", "Reason": ""},
+		"LocalAgent": {
+			"Controller": {
+				"1": {"Enable": "true", "EndpointID": "usp::controller", "USPNotifRetryMinimumWaitInterval": "3600",
+					"MTP": {"1": {"Enable": "true", "Protocol": "MQTT", "WebSocket": {"Host": ""}}}},
+				"2": {"Enable": "true", "EndpointID": "ctrl-ws", "USPNotifRetryMinimumWaitInterval": "3600",
+					"MTP": {"1": {"Enable": "true", "Protocol": "WebSocket", "WebSocket": {"Host": "127.0.0.1", "Port": "1", "Path": "usp"}}}},
+				"3": {"Enable": "false", "EndpointID": "ctrl-off"}
+			},
+			"Subscription": {
+				"1": {"Enable": "true", "ID": "boot-1", "NotifType": "Event", "ReferenceList": "Device.Boot!", "Recipient": "Device.LocalAgent.Controller.1", "Persistent": "true", "NotifRetry": "true"},
+				"2": {"Enable": "true", "ID": "boot-2", "NotifType": "Event", "ReferenceList": "Device.Boot!", "Recipient": "Device.LocalAgent.Controller.2", "Persistent": "true", "NotifRetry": "true"}
+			}
+		}
+	}
+}`
+
+func TestSubscriptionRecipient(t *testing.T) {
+	tests := []struct {
+		name      string
+		recipient string
+		setup     map[string]string // 测试前对 Controller.2 的修改
+		want      string
+	}{
+		{"empty recipient", "", nil, "ctrl-1"},
+		{"configured controller stands in", "Device.LocalAgent.Controller.1", nil, "ctrl-1"},
+		{"controller with WebSocket MTP", "Device.LocalAgent.Controller.2.", nil, "ctrl-ws"},
+		{"configured controller in table", "Device.LocalAgent.Controller.1", map[string]string{"EndpointID": "ctrl-1"}, "usp::controller"},
+		{"disabled controller", "Device.LocalAgent.Controller.3", nil, ""},
+		{"missing controller", "Device.LocalAgent.Controller.9", nil, ""},
+		{"not a controller", "Device.DeviceInfo.", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, _ := newTestUseCase(t, controllerTestData)
+			subscription := model.PathSubscription + "1."
+
+			uc.DataRepo.Lock()
+			defer uc.DataRepo.Unlock()
+			for param, value := range tt.setup {
+				uc.DataRepo.SetValue(model.PathController+"2.", param, value)
+			}
+			uc.DataRepo.SetValue(subscription, "Recipient", tt.recipient)
+			if got := uc.subscriptionRecipient(subscription); got != tt.want {
+				t.Errorf("subscriptionRecipient() with Recipient %q = %q, want %q", tt.recipient, got, tt.want)
+			}
+		})
+	}
+}
+
+// pendingBootIds 返回等待确认 Boot! 的 controller
+func pendingBootIds(uc *ClientUseCase) map[string]bool {
+	uc.bootMu.Lock()
+	defer uc.bootMu.Unlock()
+
+	ids := make(map[string]bool)
+	for endpointId := range uc.pendingBoots {
+		ids[endpointId] = true
+	}
+	return ids
+}
+
+func TestBootAcknowledgedPerController(t *testing.T) {
+	uc, lm := newTestUseCase(t, controllerTestData)
+
+	if err := uc.PowerOn(false); err != nil {
+		t.Fatalf("PowerOn: %v", err)
+	}
+	if got := pendingBootIds(uc); len(got) != 2 || !got["ctrl-1"] || !got["ctrl-ws"] {
+		t.Fatalf("pending Boot! controllers = %v, want ctrl-1 and ctrl-ws", got)
+	}
+
+	// 发给 ctrl-1 的 Boot! 只通过 Recipient 映射到 ctrl-1 的订阅发送
+	uc.OnConnected("ctrl-1")
+	deliverBoot(t, uc, lm, "ctrl-1", "boot-2")
+	if sent := drainSentMessages(t, uc); len(sent) != 0 {
+		t.Fatalf("Boot! for ctrl-1 sent %d messages through the ctrl-ws subscription, want 0", len(sent))
+	}
+	deliverBoot(t, uc, lm, "ctrl-1", "boot-1")
+	sent := drainSentMessages(t, uc)
+	if len(sent) != 1 || sent[0].toId != "ctrl-1" {
+		t.Fatalf("sent %v, want one Boot! notify to ctrl-1", sent)
+	}
+
+	// ctrl-1 确认后只清除 ctrl-1 的 Boot!
+	uc.HandleMessage("ctrl-1", &api.Msg{
+		Header: &api.Header{MsgId: sent[0].msg.GetHeader().GetMsgId(), MsgType: api.Header_NOTIFY_RESP},
+		Body: &api.Body{MsgBody: &api.Body_Response{Response: &api.Response{RespType: &api.Response_NotifyResp{
+			NotifyResp: &api.NotifyResp{SubscriptionId: "boot-1"},
+		}}}},
+	})
+	if got := pendingBootIds(uc); len(got) != 1 || !got["ctrl-ws"] {
+		t.Fatalf("pending Boot! controllers after NOTIFY_RESP = %v, want ctrl-ws", got)
+	}
+
+	uc.OnConnected("ctrl-1")
+	if boots := lm.controllerEvents("ctrl-1", model.BOOT); len(boots) != 1 {
+		t.Errorf("got %d Boot! events for ctrl-1 after NOTIFY_RESP, want 1", len(boots))
+	}
+	uc.OnConnected("ctrl-ws")
+	if boots := lm.controllerEvents("ctrl-ws", model.BOOT); len(boots) != 1 {
+		t.Errorf("got %d Boot! events for ctrl-ws, want 1", len(boots))
+	}
+}
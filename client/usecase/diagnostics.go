@@ -0,0 +1,343 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"tr369-wss-client/client/model"
+	logger "tr369-wss-client/log"
+	"tr369-wss-client/utils"
+)
+
+// pingInterval IPPing() 相邻两次回显请求的间隔
+const pingInterval = time.Second
+
+// simulatedCoreHops TraceRoute() 模拟的运营商网络路由跳（位于默认网关和目标之间）
+var simulatedCoreHops = []string{"100.64.0.1", "203.0.113.1"}
+
+// pinger 发送 ICMP 回显请求并返回往返时间，由 utils.ICMPPinger 实现，测试时可替换
+type pinger interface {
+	Echo(dst net.IP, seq int, dataSize int, timeout time.Duration) (time.Duration, error)
+	Close() error
+}
+
+// pingerFactory 创建 pinger，dscp 大于 0 时设置发送报文的 DSCP
+type pingerFactory func(ipv6 bool, dscp int) (pinger, error)
+
+// newPinger 创建诊断命令使用的 pinger，未设置 pingers 时使用 utils.NewICMPPinger
+func (uc *ClientUseCase) newPinger(ipv6 bool, dscp int) (pinger, error) {
+	if uc.pingers != nil {
+		return uc.pingers(ipv6, dscp)
+	}
+	icmpPinger, err := utils.NewICMPPinger(ipv6, dscp)
+	if err != nil {
+		return nil, err
+	}
+	return icmpPinger, nil
+}
+
+// diagTarget 诊断命令的目标地址
+type diagTarget struct {
+	host    string // 输入参数 Host
+	ip      net.IP // 解析后的地址
+	ipv6    bool
+	srcAddr string // 访问目标使用的本机地址（IPAddressUsed）
+}
+
+// ipPingCommand Device.IP.Diagnostics.IPPing() 向 Host 发送 ICMP 回显请求（异步命令）
+// 模拟设备不区分 Interface，总是使用系统路由选择的出接口
+func (uc *ClientUseCase) ipPingCommand(ctx context.Context, req *commandRequest) (map[string]string, *model.USPError) {
+	args := req.InputArgs
+	repetitions, uspErr := parseUintArg(args, "NumberOfRepetitions", 4, 1, 1<<16)
+	if uspErr != nil {
+		return nil, uspErr
+	}
+	timeout, uspErr := parseUintArg(args, "Timeout", 1000, 1, 1<<32-1)
+	if uspErr != nil {
+		return nil, uspErr
+	}
+	dataSize, uspErr := parseUintArg(args, "DataBlockSize", 56, 1, 65535)
+	if uspErr != nil {
+		return nil, uspErr
+	}
+	dscp, uspErr := parseUintArg(args, "DSCP", 0, 0, 63)
+	if uspErr != nil {
+		return nil, uspErr
+	}
+
+	target, status, uspErr := resolveDiagTarget(ctx, args["Host"], args["ProtocolVersion"])
+	if uspErr != nil || status != "" {
+		return map[string]string{"Status": status}, uspErr
+	}
+
+	pinger, err := uc.newPinger(target.ipv6, int(dscp))
+	if err != nil {
+		logger.Warnf("[USP] IPPing failed: host=%s, err=%v", target.host, err)
+		return map[string]string{"Status": model.DiagStatusInternal}, nil
+	}
+	defer pinger.Close()
+
+	var rtts []time.Duration
+	failures := 0
+	for seq := 0; seq < int(repetitions); seq++ {
+		if seq > 0 && !sleepContext(ctx, pingInterval) {
+			return nil, commandCanceled(req)
+		}
+		rtt, err := pinger.Echo(target.ip, seq, int(dataSize), time.Duration(timeout)*time.Millisecond)
+		if err != nil {
+			if !errors.Is(err, utils.ErrICMPTimeout) {
+				logger.Warnf("[USP] IPPing echo failed: host=%s, seq=%d, err=%v", target.host, seq, err)
+			}
+			failures++
+			continue
+		}
+		rtts = append(rtts, rtt)
+	}
+
+	outputArgs := map[string]string{
+		"Status":        model.DiagStatusComplete,
+		"IPAddressUsed": target.srcAddr,
+		"SuccessCount":  strconv.Itoa(len(rtts)),
+		"FailureCount":  strconv.Itoa(failures),
+	}
+	minRTT, maxRTT, avgRTT := rttStatistics(rtts)
+	outputArgs["MinimumResponseTime"] = formatMilliseconds(minRTT)
+	outputArgs["MaximumResponseTime"] = formatMilliseconds(maxRTT)
+	outputArgs["AverageResponseTime"] = formatMilliseconds(avgRTT)
+	outputArgs["MinimumResponseTimeDetailed"] = formatMicroseconds(minRTT)
+	outputArgs["MaximumResponseTimeDetailed"] = formatMicroseconds(maxRTT)
+	outputArgs["AverageResponseTimeDetailed"] = formatMicroseconds(avgRTT)
+	logger.Infof("[USP] IPPing completed: host=%s, ip=%s, success=%d, failure=%d, avg=%s", target.host, target.ip, len(rtts), failures, avgRTT)
+	return outputArgs, nil
+}
+
+// traceRouteCommand Device.IP.Diagnostics.TraceRoute() 探测到 Host 的路由（异步命令）
+// 注意：路由跳是模拟的，并非真实路由。模拟设备不发送 TTL 递增的探测报文：路由跳为数据模型中的默认网关加上
+// simulatedCoreHops，只有最后一跳（目标本身）通过 ICMP 回显实际测量，中间跳的往返时间按跳数比例由目标的
+// 往返时间推算；目标为环回地址或 IPv6 地址时只有目标一跳
+func (uc *ClientUseCase) traceRouteCommand(ctx context.Context, req *commandRequest) (map[string]string, *model.USPError) {
+	args := req.InputArgs
+	tries, uspErr := parseUintArg(args, "NumberOfTries", 3, 1, 3)
+	if uspErr != nil {
+		return nil, uspErr
+	}
+	timeout, uspErr := parseUintArg(args, "Timeout", 5000, 1, 1<<32-1)
+	if uspErr != nil {
+		return nil, uspErr
+	}
+	dataSize, uspErr := parseUintArg(args, "DataBlockSize", 56, 1, 65535)
+	if uspErr != nil {
+		return nil, uspErr
+	}
+	dscp, uspErr := parseUintArg(args, "DSCP", 0, 0, 63)
+	if uspErr != nil {
+		return nil, uspErr
+	}
+	maxHops, uspErr := parseUintArg(args, "MaxHopCount", 30, 1, 64)
+	if uspErr != nil {
+		return nil, uspErr
+	}
+
+	target, status, uspErr := resolveDiagTarget(ctx, args["Host"], args["ProtocolVersion"])
+	if uspErr != nil || status != "" {
+		return map[string]string{"Status": status}, uspErr
+	}
+
+	pinger, err := uc.newPinger(target.ipv6, int(dscp))
+	if err != nil {
+		logger.Warnf("[USP] TraceRoute failed: host=%s, err=%v", target.host, err)
+		return map[string]string{"Status": model.DiagStatusInternal}, nil
+	}
+	defer pinger.Close()
+
+	var rtts []time.Duration
+	for seq := 0; seq < int(tries); seq++ {
+		rtt, err := pinger.Echo(target.ip, seq, int(dataSize), time.Duration(timeout)*time.Millisecond)
+		if ctx.Err() != nil {
+			return nil, commandCanceled(req)
+		}
+		if err == nil {
+			rtts = append(rtts, rtt)
+		}
+	}
+
+	hops := []string{}
+	if !target.ip.IsLoopback() && !target.ipv6 {
+		uc.DataRepo.Lock()
+		if gateway := uc.defaultGateway(); gateway != "" {
+			hops = append(hops, gateway)
+		}
+		uc.DataRepo.Unlock()
+		hops = append(hops, simulatedCoreHops...)
+	}
+
+	// 中间跳的往返时间按完整路由（含目标）的跳数推算，不受 MaxHopCount 截断影响
+	totalHops := len(hops) + 1
+
+	// 目标没有应答时探测会一直进行到 MaxHopCount
+	status = model.DiagStatusComplete
+	reached := len(rtts) > 0 && len(hops) < int(maxHops)
+	if !reached {
+		status = model.DiagStatusMaxHopCountExceeded
+		if len(hops) > int(maxHops) {
+			hops = hops[:maxHops]
+		}
+	}
+
+	outputArgs := map[string]string{
+		"Status":        status,
+		"IPAddressUsed": target.srcAddr,
+		"ResponseTime":  "0",
+	}
+	for i, hop := range hops {
+		setRouteHop(outputArgs, i+1, hop, hop, scaleRTTs(rtts, i+1, totalHops))
+	}
+	if reached {
+		host := target.host
+		if net.ParseIP(host) != nil {
+			host = target.ip.String()
+		}
+		setRouteHop(outputArgs, len(hops)+1, host, target.ip.String(), rtts)
+		_, _, avgRTT := rttStatistics(rtts)
+		outputArgs["ResponseTime"] = formatMilliseconds(avgRTT)
+	}
+	logger.Infof("[USP] TraceRoute completed: host=%s, ip=%s, status=%s, simulatedHops=%d", target.host, target.ip, status, len(hops))
+	return outputArgs, nil
+}
+
+// resolveDiagTarget 按 ProtocolVersion（Any/IPv4/IPv6）解析诊断目标
+// 输入参数错误返回 USP 错误，无法解析主机名时返回 Error_CannotResolveHostName 状态
+func resolveDiagTarget(ctx context.Context, host string, protocolVersion string) (*diagTarget, string, *model.USPError) {
+	if host == "" {
+		return nil, "", model.NewUSPError(model.ErrCodeInvalidCommandArgs, "Host is required")
+	}
+
+	network := "ip"
+	switch protocolVersion {
+	case "", "Any":
+	case "IPv4":
+		network = "ip4"
+	case "IPv6":
+		network = "ip6"
+	default:
+		return nil, "", model.NewUSPError(model.ErrCodeInvalidCommandArgs, "unsupported ProtocolVersion %q", protocolVersion)
+	}
+
+	ips, err := net.DefaultResolver.LookupIP(ctx, network, host)
+	if err != nil || len(ips) == 0 {
+		logger.Warnf("[USP] cannot resolve diagnostics host: host=%s, err=%v", host, err)
+		return nil, model.DiagStatusCannotResolveHostName, nil
+	}
+
+	target := &diagTarget{host: host, ip: ips[0]}
+	if ip4 := target.ip.To4(); ip4 != nil {
+		target.ip = ip4
+	} else {
+		target.ipv6 = true
+	}
+	target.srcAddr = sourceAddress(target.ip)
+	return target, "", nil
+}
+
+// sourceAddress 获取系统访问 dst 时选择的本机地址（UDP connect 不发送报文）
+func sourceAddress(dst net.IP) string {
+	conn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: dst, Port: 9})
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP.String()
+}
+
+// defaultGateway 获取 Device.Routing 中第一个非空的 IPv4 网关地址（调用方需持有数据锁）
+func (uc *ClientUseCase) defaultGateway() string {
+	paths, err := uc.resolveObjectPaths("Device.Routing.Router.*.IPv4Forwarding.*.")
+	if err != nil {
+		return ""
+	}
+	for _, path := range paths {
+		if gateway := uc.getStringValue(path + "GatewayIPAddress"); gateway != "" {
+			return gateway
+		}
+	}
+	return ""
+}
+
+// setRouteHop 设置 TraceRoute() 输出参数 RouteHops.{i}
+func setRouteHop(outputArgs map[string]string, index int, host string, address string, rtts []time.Duration) {
+	prefix := "RouteHops." + strconv.Itoa(index) + "."
+	times := make([]string, 0, len(rtts))
+	for _, rtt := range rtts {
+		times = append(times, formatMilliseconds(rtt))
+	}
+	outputArgs[prefix+"Host"] = host
+	outputArgs[prefix+"HostAddress"] = address
+	outputArgs[prefix+"ErrorCode"] = "0"
+	outputArgs[prefix+"RTTimes"] = strings.Join(times, ",")
+}
+
+// scaleRTTs 按跳数比例推算中间跳的往返时间
+func scaleRTTs(rtts []time.Duration, hop int, totalHops int) []time.Duration {
+	scaled := make([]time.Duration, 0, len(rtts))
+	for _, rtt := range rtts {
+		scaled = append(scaled, rtt*time.Duration(hop)/time.Duration(totalHops))
+	}
+	return scaled
+}
+
+// rttStatistics 计算往返时间的最小值、最大值和平均值，没有成功的回显时均为 0
+func rttStatistics(rtts []time.Duration) (minRTT, maxRTT, avgRTT time.Duration) {
+	if len(rtts) == 0 {
+		return 0, 0, 0
+	}
+	var total time.Duration
+	minRTT = rtts[0]
+	for _, rtt := range rtts {
+		minRTT = min(minRTT, rtt)
+		maxRTT = max(maxRTT, rtt)
+		total += rtt
+	}
+	return minRTT, maxRTT, total / time.Duration(len(rtts))
+}
+
+// formatMilliseconds 将时长格式化为毫秒数（向上取整，非零的往返时间不会显示为 0）
+func formatMilliseconds(d time.Duration) string {
+	return strconv.FormatInt(int64((d+time.Millisecond-1)/time.Millisecond), 10)
+}
+
+// formatMicroseconds 将时长格式化为微秒数
+func formatMicroseconds(d time.Duration) string {
+	return strconv.FormatInt(d.Microseconds(), 10)
+}
+
+// parseUintArg 解析 unsignedInt 类型的命令输入参数，未指定时返回默认值，超出 [minValue, maxValue] 时返回错误
+func parseUintArg(args map[string]string, name string, defaultValue uint64, minValue uint64, maxValue uint64) (uint64, *model.USPError) {
+	value, ok := args[name]
+	if !ok || value == "" {
+		return defaultValue, nil
+	}
+	number, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, model.NewUSPError(model.ErrCodeInvalidCommandArgs, "%s expects unsignedInt, got %q", name, value)
+	}
+	if number < minValue || number > maxValue {
+		return 0, model.NewUSPError(model.ErrCodeInvalidCommandArgs, "%s out of range [%d:%d]: %d", name, minValue, maxValue, number)
+	}
+	return number, nil
+}
+
+// sleepContext 等待 d，ctx 结束时提前返回 false
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,256 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"tr369-wss-client/client/model"
+	"tr369-wss-client/utils"
+)
+
+// diagnosticsTestData 默认网关为 192.168.1.1
+const diagnosticsTestData = `{
+	"Device": {
+		"Routing": {"Router": {"1": {"IPv4Forwarding": {"1": {"GatewayIPAddress": "192.168.1.1"}}}}}
+	}
+}`
+
+// fakePinger 不访问网络的 pinger，rtts 中没有的序号模拟超时
+type fakePinger struct {
+	rtts   map[int]time.Duration
+	echoes []int
+	closed bool
+}
+
+func (p *fakePinger) Echo(dst net.IP, seq int, dataSize int, timeout time.Duration) (time.Duration, error) {
+	p.echoes = append(p.echoes, seq)
+	if rtt, ok := p.rtts[seq]; ok {
+		return rtt, nil
+	}
+	return 0, utils.ErrICMPTimeout
+}
+
+func (p *fakePinger) Close() error {
+	p.closed = true
+	return nil
+}
+
+// useFakePinger 让诊断命令使用 fakePinger
+func useFakePinger(uc *ClientUseCase, rtts map[int]time.Duration) *fakePinger {
+	fake := &fakePinger{rtts: rtts}
+	uc.pingers = func(ipv6 bool, dscp int) (pinger, error) {
+		return fake, nil
+	}
+	return fake
+}
+
+// diagRequest 创建 Device.IP.Diagnostics 下的命令请求
+func diagRequest(name string, inputArgs map[string]string) *commandRequest {
+	return &commandRequest{ObjPath: "Device.IP.Diagnostics.", Name: name, InputArgs: inputArgs}
+}
+
+func TestIPPing(t *testing.T) {
+	tests := []struct {
+		name        string
+		repetitions string
+		rtts        map[int]time.Duration
+		want        map[string]string
+	}{
+		{
+			name:        "all replies",
+			repetitions: "2",
+			rtts:        map[int]time.Duration{0: 10 * time.Millisecond, 1: 30 * time.Millisecond},
+			want: map[string]string{
+				"SuccessCount": "2", "FailureCount": "0",
+				"MinimumResponseTime": "10", "MaximumResponseTime": "30", "AverageResponseTime": "20",
+				"AverageResponseTimeDetailed": "20000",
+			},
+		},
+		{
+			name:        "partial replies",
+			repetitions: "2",
+			rtts:        map[int]time.Duration{1: 1500 * time.Microsecond},
+			want: map[string]string{
+				"SuccessCount": "1", "FailureCount": "1",
+				"MinimumResponseTime": "2", "AverageResponseTimeDetailed": "1500",
+			},
+		},
+		{
+			name:        "no reply",
+			repetitions: "1",
+			want: map[string]string{
+				"SuccessCount": "0", "FailureCount": "1",
+				"MinimumResponseTime": "0", "AverageResponseTime": "0",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, _ := newTestUseCase(t, diagnosticsTestData)
+			fake := useFakePinger(uc, tt.rtts)
+
+			outputArgs, uspErr := uc.ipPingCommand(context.Background(), diagRequest("IPPing()", map[string]string{
+				"Host":                "127.0.0.1",
+				"NumberOfRepetitions": tt.repetitions,
+			}))
+			if uspErr != nil {
+				t.Fatalf("IPPing() error: %v", uspErr)
+			}
+			if outputArgs["Status"] != model.DiagStatusComplete {
+				t.Errorf("Status = %s, want %s", outputArgs["Status"], model.DiagStatusComplete)
+			}
+			for key, value := range tt.want {
+				if outputArgs[key] != value {
+					t.Errorf("%s = %q, want %q", key, outputArgs[key], value)
+				}
+			}
+			if strconv.Itoa(len(fake.echoes)) != tt.repetitions || !fake.closed {
+				t.Errorf("sent %d echoes, closed %v; want %s echoes, closed", len(fake.echoes), fake.closed, tt.repetitions)
+			}
+		})
+	}
+}
+
+func TestIPPingInvalidArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args map[string]string
+	}{
+		{"missing host", map[string]string{}},
+		{"zero repetitions", map[string]string{"Host": "127.0.0.1", "NumberOfRepetitions": "0"}},
+		{"timeout not a number", map[string]string{"Host": "127.0.0.1", "Timeout": "soon"}},
+		{"DSCP out of range", map[string]string{"Host": "127.0.0.1", "DSCP": "64"}},
+		{"unsupported protocol version", map[string]string{"Host": "127.0.0.1", "ProtocolVersion": "IPv5"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, _ := newTestUseCase(t, diagnosticsTestData)
+			fake := useFakePinger(uc, nil)
+
+			_, uspErr := uc.ipPingCommand(context.Background(), diagRequest("IPPing()", tt.args))
+			if uspErr == nil || uspErr.Code != model.ErrCodeInvalidCommandArgs {
+				t.Fatalf("IPPing(%v) error = %v, want code %d", tt.args, uspErr, model.ErrCodeInvalidCommandArgs)
+			}
+			if len(fake.echoes) != 0 {
+				t.Errorf("IPPing(%v) sent %d echoes", tt.args, len(fake.echoes))
+			}
+		})
+	}
+}
+
+func TestIPPingPingerError(t *testing.T) {
+	uc, _ := newTestUseCase(t, diagnosticsTestData)
+	uc.pingers = func(ipv6 bool, dscp int) (pinger, error) {
+		return nil, errors.New("operation not permitted")
+	}
+
+	outputArgs, uspErr := uc.ipPingCommand(context.Background(), diagRequest("IPPing()", map[string]string{"Host": "127.0.0.1"}))
+	if uspErr != nil {
+		t.Fatalf("IPPing() error: %v", uspErr)
+	}
+	if outputArgs["Status"] != model.DiagStatusInternal {
+		t.Errorf("Status = %s, want %s", outputArgs["Status"], model.DiagStatusInternal)
+	}
+}
+
+func TestIPPingCanceled(t *testing.T) {
+	uc, _ := newTestUseCase(t, diagnosticsTestData)
+	fake := useFakePinger(uc, map[int]time.Duration{0: time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	_, uspErr := uc.ipPingCommand(ctx, diagRequest("IPPing()", map[string]string{"Host": "127.0.0.1", "NumberOfRepetitions": "10"}))
+	if uspErr == nil || uspErr.Code != model.ErrCodeCommandCanceled {
+		t.Fatalf("IPPing() error = %v, want code %d", uspErr, model.ErrCodeCommandCanceled)
+	}
+	if len(fake.echoes) != 1 {
+		t.Errorf("sent %d echoes before cancel, want 1", len(fake.echoes))
+	}
+}
+
+func TestTraceRoute(t *testing.T) {
+	targetRTT := map[int]time.Duration{0: 40 * time.Millisecond}
+
+	tests := []struct {
+		name   string
+		host   string
+		args   map[string]string
+		rtts   map[int]time.Duration
+		status string
+		hosts  []string
+		times  []string
+	}{
+		{
+			name:   "simulated hops then target",
+			host:   "10.0.0.1",
+			rtts:   targetRTT,
+			status: model.DiagStatusComplete,
+			hosts:  []string{"192.168.1.1", "100.64.0.1", "203.0.113.1", "10.0.0.1"},
+			times:  []string{"10", "20", "30", "40"},
+		},
+		{
+			name:   "loopback target only",
+			host:   "127.0.0.1",
+			rtts:   targetRTT,
+			status: model.DiagStatusComplete,
+			hosts:  []string{"127.0.0.1"},
+			times:  []string{"40"},
+		},
+		{
+			name:   "max hop count reached before target",
+			host:   "10.0.0.1",
+			args:   map[string]string{"MaxHopCount": "2"},
+			rtts:   targetRTT,
+			status: model.DiagStatusMaxHopCountExceeded,
+			hosts:  []string{"192.168.1.1", "100.64.0.1"},
+			times:  []string{"10", "20"},
+		},
+		{
+			name:   "target does not reply",
+			host:   "10.0.0.1",
+			status: model.DiagStatusMaxHopCountExceeded,
+			hosts:  []string{"192.168.1.1", "100.64.0.1", "203.0.113.1"},
+			times:  []string{"", "", ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, _ := newTestUseCase(t, diagnosticsTestData)
+			useFakePinger(uc, tt.rtts)
+
+			args := map[string]string{"Host": tt.host, "NumberOfTries": "1"}
+			for key, value := range tt.args {
+				args[key] = value
+			}
+			outputArgs, uspErr := uc.traceRouteCommand(context.Background(), diagRequest("TraceRoute()", args))
+			if uspErr != nil {
+				t.Fatalf("TraceRoute() error: %v", uspErr)
+			}
+			if outputArgs["Status"] != tt.status {
+				t.Errorf("Status = %s, want %s", outputArgs["Status"], tt.status)
+			}
+
+			var hosts, times []string
+			for i := 1; outputArgs[routeHopKey(i, "Host")] != ""; i++ {
+				hosts = append(hosts, outputArgs[routeHopKey(i, "Host")])
+				times = append(times, outputArgs[routeHopKey(i, "RTTimes")])
+			}
+			if !reflect.DeepEqual(hosts, tt.hosts) || !reflect.DeepEqual(times, tt.times) {
+				t.Errorf("RouteHops = %v %v, want %v %v", hosts, times, tt.hosts, tt.times)
+			}
+		})
+	}
+}
+
+// routeHopKey 返回 TraceRoute() 输出参数 RouteHops.{i} 的参数名
+func routeHopKey(index int, param string) string {
+	return "RouteHops." + strconv.Itoa(index) + "." + param
+}
@@ -0,0 +1,334 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"tr369-wss-client/client/model"
+	logger "tr369-wss-client/log"
+	"tr369-wss-client/utils"
+)
+
+// diagTimeLayout 诊断结果中时间参数的格式（微秒精度的 UTC 时间）
+const diagTimeLayout = "2006-01-02T15:04:05.000000Z"
+
+// byteSample 某一时刻累计收发的字节数
+type byteSample struct {
+	at    time.Time
+	bytes int64
+}
+
+// byteCounter 记录连接累计收发字节数随时间的变化，用于统计满负载期间的字节数
+type byteCounter struct {
+	mu      sync.Mutex
+	samples []byteSample
+}
+
+func (c *byteCounter) add(n int) {
+	if n <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	total := int64(n)
+	if len(c.samples) > 0 {
+		total += c.samples[len(c.samples)-1].bytes
+	}
+	c.samples = append(c.samples, byteSample{at: time.Now(), bytes: total})
+}
+
+// total 返回累计字节数
+func (c *byteCounter) total() int64 {
+	return c.at(time.Now())
+}
+
+// at 返回 t 时刻的累计字节数
+func (c *byteCounter) at(t time.Time) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var bytes int64
+	for _, sample := range c.samples {
+		if sample.at.After(t) {
+			break
+		}
+		bytes = sample.bytes
+	}
+	return bytes
+}
+
+// between 返回 [start, end] 期间的字节数
+func (c *byteCounter) between(start time.Time, end time.Time) int64 {
+	return c.at(end) - c.at(start)
+}
+
+// countingConn 统计 TCP 连接收发字节数的 net.Conn
+type countingConn struct {
+	net.Conn
+	received *byteCounter
+	sent     *byteCounter
+}
+
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.received.add(n)
+	return n, err
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.sent.add(n)
+	return n, err
+}
+
+// downloadConnection DownloadDiagnostics() 一个连接的测试结果
+type downloadConnection struct {
+	tcpOpenRequest  time.Time
+	tcpOpenResponse time.Time
+	rom             time.Time // 发送 GET 请求的时间
+	bom             time.Time // 收到第一个响应字节的时间
+	eom             time.Time // 收到最后一个字节的时间
+	localAddr       string
+	testBytes       byteCounter // 收到的文件内容字节数
+	received        byteCounter // TCP 连接收到的字节数
+	sent            byteCounter // TCP 连接发送的字节数
+	err             error
+}
+
+// downloadDiagnosticsCommand Device.IP.Diagnostics.DownloadDiagnostics() HTTP 下载测速（异步命令）
+// 使用 NumberOfConnections 个连接同时下载 DownloadURL，TimeBasedTestDuration 大于 0 时到达测试时长后结束下载；
+// 模拟设备不区分 Interface，不支持 DSCP、EthernetPriority 和增量结果（TimeBasedTestMeasurementInterval）
+func (uc *ClientUseCase) downloadDiagnosticsCommand(ctx context.Context, req *commandRequest) (map[string]string, *model.USPError) {
+	args := req.InputArgs
+	u, err := url.Parse(args["DownloadURL"])
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, model.NewUSPError(model.ErrCodeInvalidCommandArgs, "DownloadURL expects an http or https URL, got %q", args["DownloadURL"])
+	}
+
+	uc.DataRepo.Lock()
+	maxConnections, _ := strconv.ParseUint(uc.getStringValue("Device.IP.Diagnostics.DownloadDiagnosticMaxConnections"), 10, 32)
+	uc.DataRepo.Unlock()
+	connections, uspErr := parseUintArg(args, "NumberOfConnections", 1, 1, max(maxConnections, 1))
+	if uspErr != nil {
+		return nil, uspErr
+	}
+	duration, uspErr := parseUintArg(args, "TimeBasedTestDuration", 0, 0, 999)
+	if uspErr != nil {
+		return nil, uspErr
+	}
+
+	network := "tcp"
+	switch version := args["ProtocolVersion"]; version {
+	case "", "Any":
+	case "IPv4":
+		network = "tcp4"
+	case "IPv6":
+		network = "tcp6"
+	default:
+		return nil, model.NewUSPError(model.ErrCodeInvalidCommandArgs, "unsupported ProtocolVersion %q", version)
+	}
+
+	testCtx := ctx
+	if duration > 0 {
+		var cancel context.CancelFunc
+		testCtx, cancel = context.WithTimeout(ctx, time.Duration(duration)*time.Second)
+		defer cancel()
+	}
+
+	results := make([]*downloadConnection, connections)
+	var wg sync.WaitGroup
+	for i := range results {
+		results[i] = &downloadConnection{}
+		wg.Add(1)
+		go func(conn *downloadConnection) {
+			defer wg.Done()
+			uc.runDownloadConnection(testCtx, u, network, conn, duration > 0)
+		}(results[i])
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return nil, commandCanceled(req)
+	}
+	for _, result := range results {
+		if result.err != nil {
+			status := downloadErrorStatus(result.err)
+			logger.Warnf("[USP] DownloadDiagnostics failed: url=%s, status=%s, err=%v", u.Redacted(), status, result.err)
+			return map[string]string{"Status": status}, nil
+		}
+	}
+
+	outputArgs := downloadResults(results, utils.IsTrue(args["EnablePerConnectionResults"]))
+	logger.Infof("[USP] DownloadDiagnostics completed: url=%s, connections=%d, bytes=%s", u.Redacted(), connections, outputArgs["TestBytesReceived"])
+	return outputArgs, nil
+}
+
+// runDownloadConnection 使用一个独立的 TCP 连接下载 u，记录各阶段时间和收发字节数
+// timeBased 为 true 时 ctx 到期表示测试时长结束，不视为错误
+func (uc *ClientUseCase) runDownloadConnection(ctx context.Context, u *url.URL, network string, result *downloadConnection, timeBased bool) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if base, ok := uc.getHTTPClient().Transport.(*http.Transport); ok {
+		transport = base.Clone()
+	}
+	transport.DisableKeepAlives = true
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	transport.DialContext = func(ctx context.Context, _ string, addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		result.localAddr = conn.LocalAddr().(*net.TCPAddr).IP.String()
+		return &countingConn{Conn: conn, received: &result.received, sent: &result.sent}, nil
+	}
+	defer transport.CloseIdleConnections()
+
+	trace := &httptrace.ClientTrace{
+		ConnectStart:         func(string, string) { result.tcpOpenRequest = time.Now() },
+		ConnectDone:          func(string, string, error) { result.tcpOpenResponse = time.Now() },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { result.rom = time.Now() },
+		GotFirstResponseByte: func() { result.bom = time.Now() },
+	}
+	httpReq, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), http.MethodGet, u.String(), nil)
+	if err != nil {
+		result.err = err
+		return
+	}
+
+	resp, err := (&http.Client{Transport: transport}).Do(httpReq)
+	if err != nil {
+		result.err = err
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		result.err = fmt.Errorf("unexpected HTTP status %s", resp.Status)
+		return
+	}
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := resp.Body.Read(buf)
+		result.testBytes.add(n)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if !timeBased || ctx.Err() != context.DeadlineExceeded {
+				result.err = err
+			}
+			break
+		}
+	}
+	result.eom = time.Now()
+}
+
+// downloadResults 汇总各连接的结果
+// 多连接时 ROMTime/BOMTime/TCPOpen*Time 取最早的连接，EOMTime 取最晚的连接；
+// 满负载期间为所有连接都在传输数据的时间段（最晚的 BOMTime 到最早的 EOMTime）
+func downloadResults(results []*downloadConnection, perConnection bool) map[string]string {
+	first := results[0]
+	rom, bom, eom := first.rom, first.bom, first.eom
+	tcpOpenRequest, tcpOpenResponse := first.tcpOpenRequest, first.tcpOpenResponse
+	fullStart, fullEnd := first.bom, first.eom
+	var testBytes, received, sent, testBytesFull, receivedFull, sentFull int64
+	for _, result := range results {
+		rom = earliest(rom, result.rom)
+		bom = earliest(bom, result.bom)
+		tcpOpenRequest = earliest(tcpOpenRequest, result.tcpOpenRequest)
+		tcpOpenResponse = earliest(tcpOpenResponse, result.tcpOpenResponse)
+		if result.eom.After(eom) {
+			eom = result.eom
+		}
+		if result.bom.After(fullStart) {
+			fullStart = result.bom
+		}
+		fullEnd = earliest(fullEnd, result.eom)
+		testBytes += result.testBytes.total()
+		received += result.received.total()
+		sent += result.sent.total()
+	}
+
+	var fullLoading time.Duration
+	if fullEnd.After(fullStart) {
+		fullLoading = fullEnd.Sub(fullStart)
+		for _, result := range results {
+			testBytesFull += result.testBytes.between(fullStart, fullEnd)
+			receivedFull += result.received.between(fullStart, fullEnd)
+			sentFull += result.sent.between(fullStart, fullEnd)
+		}
+	}
+
+	outputArgs := map[string]string{
+		"Status":                             model.DiagStatusComplete,
+		"IPAddressUsed":                      first.localAddr,
+		"ROMTime":                            formatDiagTime(rom),
+		"BOMTime":                            formatDiagTime(bom),
+		"EOMTime":                            formatDiagTime(eom),
+		"TCPOpenRequestTime":                 formatDiagTime(tcpOpenRequest),
+		"TCPOpenResponseTime":                formatDiagTime(tcpOpenResponse),
+		"TestBytesReceived":                  strconv.FormatInt(testBytes, 10),
+		"TotalBytesReceived":                 strconv.FormatInt(received, 10),
+		"TotalBytesSent":                     strconv.FormatInt(sent, 10),
+		"TestBytesReceivedUnderFullLoading":  strconv.FormatInt(testBytesFull, 10),
+		"TotalBytesReceivedUnderFullLoading": strconv.FormatInt(receivedFull, 10),
+		"TotalBytesSentUnderFullLoading":     strconv.FormatInt(sentFull, 10),
+		"PeriodOfFullLoading":                strconv.FormatInt(fullLoading.Microseconds(), 10),
+	}
+	if !perConnection {
+		return outputArgs
+	}
+
+	for i, result := range results {
+		prefix := "PerConnectionResult." + strconv.Itoa(i+1) + "."
+		outputArgs[prefix+"ROMTime"] = formatDiagTime(result.rom)
+		outputArgs[prefix+"BOMTime"] = formatDiagTime(result.bom)
+		outputArgs[prefix+"EOMTime"] = formatDiagTime(result.eom)
+		outputArgs[prefix+"TCPOpenRequestTime"] = formatDiagTime(result.tcpOpenRequest)
+		outputArgs[prefix+"TCPOpenResponseTime"] = formatDiagTime(result.tcpOpenResponse)
+		outputArgs[prefix+"TestBytesReceived"] = strconv.FormatInt(result.testBytes.total(), 10)
+		outputArgs[prefix+"TotalBytesReceived"] = strconv.FormatInt(result.received.total(), 10)
+		outputArgs[prefix+"TotalBytesSent"] = strconv.FormatInt(result.sent.total(), 10)
+	}
+	return outputArgs
+}
+
+// downloadErrorStatus 将下载错误转换为 DownloadDiagnostics() 的 Status
+func downloadErrorStatus(err error) string {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var opErr *net.OpError
+	switch {
+	case errors.As(err, &dnsErr):
+		return model.DiagStatusCannotResolveHostName
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return model.DiagStatusTimeout
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		return model.DiagStatusInitConnectionFailed
+	default:
+		return model.DiagStatusTransferFailed
+	}
+}
+
+// earliest 返回两个时间中较早的一个，忽略零值
+func earliest(a time.Time, b time.Time) time.Time {
+	if a.IsZero() || (!b.IsZero() && b.Before(a)) {
+		return b
+	}
+	return a
+}
+
+// formatDiagTime 格式化诊断结果中的时间，零值使用 TR-181 的未知时间
+func formatDiagTime(t time.Time) string {
+	if t.IsZero() {
+		return "0001-01-01T00:00:00Z"
+	}
+	return t.UTC().Format(diagTimeLayout)
+}
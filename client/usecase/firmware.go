@@ -0,0 +1,377 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"tr369-wss-client/client/model"
+	logger "tr369-wss-client/log"
+	"tr369-wss-client/trtree"
+	"tr369-wss-client/utils"
+)
+
+// checksumAlgorithms Download() 的 CheckSumAlgorithm 支持的校验算法
+var checksumAlgorithms = map[string]func() hash.Hash{
+	"SHA-1":   sha1.New,
+	"SHA-224": sha256.New224,
+	"SHA-256": sha256.New,
+	"SHA-384": sha512.New384,
+	"SHA-512": sha512.New,
+}
+
+// firmwareVersionRegex 从镜像文件名中提取版本号，如 XGB834v_1.2.0_build.bin -> 1.2.0
+var firmwareVersionRegex = regexp.MustCompile(`\d+(\.\d+)+`)
+
+// firmwareDownload Download() 的输入参数
+type firmwareDownload struct {
+	url        *url.URL
+	username   string
+	password   string
+	fileSize   int64 // 0 表示不校验文件大小
+	newHash    func() hash.Hash
+	checkSum   string
+	autoActive bool
+}
+
+// timeWindow Activate() 的 TimeWindow.{i} 输入参数，Start/End 为相对命令调用时刻的秒数
+type timeWindow struct {
+	index int
+	start time.Duration
+	end   time.Duration
+	mode  string
+}
+
+// firmwareDownloadCommand Device.DeviceInfo.FirmwareImage.{i}.Download() 下载固件镜像（异步命令）
+// 支持 http、https 和 file 协议，校验文件大小和校验和后将镜像置为 Available，并发送 TransferComplete! 事件；
+// AutoActivate 为 true 时下载完成后立即激活（重启），命令在重启完成后结束
+func (uc *ClientUseCase) firmwareDownloadCommand(ctx context.Context, req *commandRequest) (map[string]string, *model.USPError) {
+	dl, uspErr := parseFirmwareDownload(req.InputArgs)
+	if uspErr != nil {
+		return nil, uspErr
+	}
+
+	uc.DataRepo.Lock()
+	active := uc.isActiveFirmware(req.ObjPath)
+	uc.DataRepo.Unlock()
+	if active {
+		return nil, model.NewUSPError(model.ErrCodeCommandFailure, "cannot download into the active firmware image %s", req.ObjPath)
+	}
+
+	startTime := time.Now()
+	uc.updateParams(req.ObjPath, map[string]string{
+		"Status":    model.FirmwareStatusDownloading,
+		"Available": "false",
+	})
+
+	size, checkSum, err := uc.fetchFirmware(ctx, dl)
+	switch {
+	case ctx.Err() != nil:
+		uspErr = commandCanceled(req)
+		uc.updateParams(req.ObjPath, map[string]string{"Status": model.FirmwareStatusDownloadFailed})
+	case err != nil:
+		uspErr = model.NewUSPError(model.ErrCodeCommandFailure, "download %s failed: %v", dl.url.Redacted(), err)
+		uc.updateParams(req.ObjPath, map[string]string{"Status": model.FirmwareStatusDownloadFailed})
+	default:
+		uc.updateParams(req.ObjPath, map[string]string{"Status": model.FirmwareStatusValidating})
+		if uspErr = dl.verify(size, checkSum); uspErr != nil {
+			uc.updateParams(req.ObjPath, map[string]string{"Status": model.FirmwareStatusValidationFailed})
+		}
+	}
+
+	if uspErr == nil {
+		name := path.Base(dl.url.Path)
+		uc.updateParams(req.ObjPath, map[string]string{
+			"Status":    model.FirmwareStatusAvailable,
+			"Available": "true",
+			"Name":      name,
+			"Version":   firmwareVersion(name),
+		})
+		logger.Infof("[USP] firmware downloaded: image=%s, url=%s, size=%d", req.ObjPath, dl.url.Redacted(), size)
+	}
+	uc.sendTransferComplete(req, dl.url.Redacted(), startTime, uspErr)
+	if uspErr != nil {
+		return nil, uspErr
+	}
+
+	if dl.autoActive {
+		if err := uc.activateFirmware(req.ObjPath, req.CommandKey); err != nil {
+			return nil, model.NewUSPError(model.ErrCodeCommandFailure, "activate %s failed: %v", req.ObjPath, err)
+		}
+	}
+	return map[string]string{}, nil
+}
+
+// firmwareActivateCommand Device.DeviceInfo.FirmwareImage.{i}.Activate() 激活固件镜像（异步命令）
+// 未指定 TimeWindow 时立即激活，否则在第一个不需要用户确认的时间窗口开始时激活；
+// 激活后设备重启并以新的 SoftwareVersion 启动，命令在重启完成后结束
+func (uc *ClientUseCase) firmwareActivateCommand(ctx context.Context, req *commandRequest) (map[string]string, *model.USPError) {
+	windows, uspErr := parseTimeWindows(req.InputArgs)
+	if uspErr != nil {
+		return nil, uspErr
+	}
+	if uspErr := uc.checkFirmwareActivatable(req.ObjPath); uspErr != nil {
+		return nil, uspErr
+	}
+
+	var delay time.Duration
+	if len(windows) > 0 {
+		window, ok := firstAutomaticWindow(windows)
+		if !ok {
+			return nil, model.NewUSPError(model.ErrCodeCommandFailure, "time windows requiring user confirmation are not supported")
+		}
+		delay = window.start
+		logger.Infof("[USP] firmware activation scheduled: image=%s, window=%d, mode=%s, delay=%s", req.ObjPath, window.index, window.mode, delay)
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return nil, commandCanceled(req)
+	case <-timer.C:
+	}
+
+	// 等待期间镜像可能被重新下载，激活前再次检查
+	if uspErr := uc.checkFirmwareActivatable(req.ObjPath); uspErr != nil {
+		return nil, uspErr
+	}
+	if err := uc.activateFirmware(req.ObjPath, req.CommandKey); err != nil {
+		return nil, model.NewUSPError(model.ErrCodeCommandFailure, "activate %s failed: %v", req.ObjPath, err)
+	}
+	return map[string]string{}, nil
+}
+
+// parseFirmwareDownload 解析并校验 Download() 的输入参数
+func parseFirmwareDownload(args map[string]string) (*firmwareDownload, *model.USPError) {
+	u, uspErr := parseTransferURL(args["URL"])
+	if uspErr != nil {
+		return nil, uspErr
+	}
+
+	dl := &firmwareDownload{
+		url:        u,
+		username:   args["Username"],
+		password:   args["Password"],
+		checkSum:   strings.ToLower(args["CheckSum"]),
+		autoActive: utils.IsTrue(args["AutoActivate"]),
+	}
+
+	if value := args["FileSize"]; value != "" {
+		size, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return nil, model.NewUSPError(model.ErrCodeInvalidCommandArgs, "FileSize expects unsignedInt, got %q", value)
+		}
+		dl.fileSize = int64(size)
+	}
+
+	if algorithm := args["CheckSumAlgorithm"]; algorithm != "" || dl.checkSum != "" {
+		newHash, ok := checksumAlgorithms[algorithm]
+		if !ok {
+			return nil, model.NewUSPError(model.ErrCodeInvalidCommandArgs, "unsupported CheckSumAlgorithm %q", algorithm)
+		}
+		if _, err := hex.DecodeString(dl.checkSum); err != nil || dl.checkSum == "" {
+			return nil, model.NewUSPError(model.ErrCodeInvalidCommandArgs, "CheckSum expects hexBinary, got %q", args["CheckSum"])
+		}
+		dl.newHash = newHash
+	}
+	return dl, nil
+}
+
+// verify 校验下载文件的大小和校验和
+func (dl *firmwareDownload) verify(size int64, checkSum string) *model.USPError {
+	if dl.fileSize > 0 && size != dl.fileSize {
+		return model.NewUSPError(model.ErrCodeCommandFailure, "file size mismatch: expected %d, got %d", dl.fileSize, size)
+	}
+	if dl.newHash != nil && checkSum != dl.checkSum {
+		return model.NewUSPError(model.ErrCodeCommandFailure, "checksum mismatch: expected %s, got %s", dl.checkSum, checkSum)
+	}
+	return nil
+}
+
+// fetchFirmware 下载固件镜像，返回文件大小和校验和（未指定校验算法时为空）
+// 模拟设备不保存镜像内容，只在下载过程中计算大小和校验和
+func (uc *ClientUseCase) fetchFirmware(ctx context.Context, dl *firmwareDownload) (int64, string, error) {
+	body, err := uc.openTransferSource(ctx, dl.url, dl.username, dl.password)
+	if err != nil {
+		return 0, "", err
+	}
+	defer body.Close()
+
+	writer := io.Discard
+	var hasher hash.Hash
+	if dl.newHash != nil {
+		hasher = dl.newHash()
+		writer = hasher
+	}
+
+	size, err := io.Copy(writer, &contextReader{ctx: ctx, reader: body})
+	if err != nil {
+		return size, "", err
+	}
+	if hasher == nil {
+		return size, "", nil
+	}
+	return size, hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// firmwareVersion 根据镜像文件名推断版本号，文件名中没有版本号时使用去掉扩展名的文件名
+func firmwareVersion(name string) string {
+	if version := firmwareVersionRegex.FindString(name); version != "" {
+		return version
+	}
+	return strings.TrimSuffix(name, path.Ext(name))
+}
+
+// sendTransferComplete 发送 Device.LocalAgent.TransferComplete! 事件
+func (uc *ClientUseCase) sendTransferComplete(req *commandRequest, transferURL string, startTime time.Time, uspErr *model.USPError) {
+	params := map[string]string{
+		"Command":      req.Command(),
+		"CommandKey":   req.CommandKey,
+		"Requestor":    req.Originator,
+		"TransferType": "Download",
+		"Affected":     req.ObjPath,
+		"TransferURL":  transferURL,
+		"FaultCode":    "0",
+		"FaultString":  "",
+		"StartTime":    startTime.UTC().Format(time.RFC3339),
+		"CompleteTime": time.Now().UTC().Format(time.RFC3339),
+	}
+	if uspErr != nil {
+		params["FaultCode"] = strconv.FormatUint(uint64(uspErr.Code), 10)
+		params["FaultString"] = uspErr.Message
+	}
+	uc.notifyEvent(model.PathLocalAgent, model.TransferComplete, params)
+}
+
+// parseTimeWindows 解析 Activate() 的 TimeWindow.{i} 输入参数，按开始时间排序
+func parseTimeWindows(args map[string]string) ([]timeWindow, *model.USPError) {
+	windows := make(map[int]*timeWindow)
+	for arg, value := range args {
+		segments := strings.Split(arg, ".")
+		if len(segments) != 3 || segments[0] != "TimeWindow" || !trtree.IsInstanceKey(segments[1]) {
+			continue
+		}
+		index, _ := strconv.Atoi(segments[1])
+		window, ok := windows[index]
+		if !ok {
+			window = &timeWindow{index: index, start: -1, end: -1, mode: model.TimeWindowModeAnyTime}
+			windows[index] = window
+		}
+
+		switch segments[2] {
+		case "Start", "End":
+			seconds, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return nil, model.NewUSPError(model.ErrCodeInvalidCommandArgs, "%s expects unsignedInt, got %q", arg, value)
+			}
+			if segments[2] == "Start" {
+				window.start = time.Duration(seconds) * time.Second
+			} else {
+				window.end = time.Duration(seconds) * time.Second
+			}
+		case "Mode":
+			switch value {
+			case model.TimeWindowModeAnyTime, model.TimeWindowModeImmediately,
+				model.TimeWindowModeWhenIdle, model.TimeWindowModeConfirmationNeeded:
+				window.mode = value
+			default:
+				return nil, model.NewUSPError(model.ErrCodeInvalidCommandArgs, "invalid %s %q", arg, value)
+			}
+		}
+	}
+
+	result := make([]timeWindow, 0, len(windows))
+	for _, window := range windows {
+		if window.start < 0 || window.end < 0 {
+			return nil, model.NewUSPError(model.ErrCodeInvalidCommandArgs, "TimeWindow.%d requires Start and End", window.index)
+		}
+		if window.end < window.start {
+			return nil, model.NewUSPError(model.ErrCodeInvalidCommandArgs, "TimeWindow.%d End is before Start", window.index)
+		}
+		result = append(result, *window)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].start < result[j].start })
+	return result, nil
+}
+
+// firstAutomaticWindow 返回第一个不需要用户确认的时间窗口
+func firstAutomaticWindow(windows []timeWindow) (timeWindow, bool) {
+	for _, window := range windows {
+		if window.mode != model.TimeWindowModeConfirmationNeeded {
+			return window, true
+		}
+	}
+	return timeWindow{}, false
+}
+
+// checkFirmwareActivatable 检查固件镜像是否可以激活（调用方不能持有数据锁）
+func (uc *ClientUseCase) checkFirmwareActivatable(imagePath string) *model.USPError {
+	uc.DataRepo.Lock()
+	defer uc.DataRepo.Unlock()
+
+	if uc.isActiveFirmware(imagePath) {
+		return model.NewUSPError(model.ErrCodeCommandFailure, "firmware image %s is already active", imagePath)
+	}
+	if uc.getStringValue(imagePath+"Status") != model.FirmwareStatusAvailable || !utils.IsTrue(uc.getStringValue(imagePath+"Available")) {
+		return model.NewUSPError(model.ErrCodeCommandFailure, "firmware image %s is not available", imagePath)
+	}
+	return nil
+}
+
+// activateFirmware 切换到指定的固件镜像并重启，重启后 Boot! 的 FirmwareUpdated 为 true
+func (uc *ClientUseCase) activateFirmware(imagePath string, commandKey string) error {
+	logger.Infof("[USP] firmware activate: image=%s", imagePath)
+	return uc.restart(model.BootCauseRemoteReboot, commandKey, func() error {
+		return uc.switchFirmwareImage(imagePath)
+	}, true)
+}
+
+// switchFirmwareImage 将指定镜像设为运行和启动镜像，并更新 SoftwareVersion（调用方需持有数据锁）
+func (uc *ClientUseCase) switchFirmwareImage(imagePath string) error {
+	if uc.getStringValue(imagePath+"Status") != model.FirmwareStatusAvailable {
+		return fmt.Errorf("firmware image %s is not available", imagePath)
+	}
+
+	images, _ := trtree.ResolveObjectPath(uc.DataRepo.GetParameters(), model.PathFirmware+"*.")
+	for _, image := range images {
+		if uc.getStringValue(image+"Status") == model.FirmwareStatusActive {
+			uc.DataRepo.SetValue(image, "Status", model.FirmwareStatusAvailable)
+		}
+	}
+
+	ref := firmwareImageRef(imagePath)
+	uc.DataRepo.SetValue(imagePath, "Status", model.FirmwareStatusActive)
+	uc.DataRepo.SetValue(model.PathDeviceInfo, "ActiveFirmwareImage", ref)
+	uc.DataRepo.SetValue(model.PathDeviceInfo, "BootFirmwareImage", ref)
+	uc.DataRepo.SetValue(model.PathDeviceInfo, "SoftwareVersion", uc.getStringValue(imagePath+"Version"))
+	uc.DataRepo.SetValue(model.PathDeviceInfo, "X_PRPL-COM_LastUpgradeDate", time.Now().UTC().Format(time.RFC3339))
+	return nil
+}
+
+// isActiveFirmware 判断镜像是否为当前运行的镜像（调用方需持有数据锁）
+func (uc *ClientUseCase) isActiveFirmware(imagePath string) bool {
+	active := strings.TrimSuffix(uc.getStringValue(model.PathDeviceInfo+"ActiveFirmwareImage"), ".")
+	if active != "" {
+		return firmwareImageRef(imagePath) == strings.TrimPrefix(active, "Device.")
+	}
+	return uc.getStringValue(imagePath+"Status") == model.FirmwareStatusActive
+}
+
+// firmwareImageRef 返回 ActiveFirmwareImage/BootFirmwareImage 使用的镜像引用，与设备数据保持一致，
+// 如 Device.DeviceInfo.FirmwareImage.2. -> DeviceInfo.FirmwareImage.2
+func firmwareImageRef(imagePath string) string {
+	return strings.TrimPrefix(strings.TrimSuffix(imagePath, "."), "Device.")
+}
@@ -0,0 +1,353 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"tr369-wss-client/client/model"
+)
+
+// firmwareTestData 镜像 1 为当前运行的镜像，镜像 2 用于下载
+const firmwareTestData = `{
+	"Device": {
+		"DeviceInfo": {
+			"SoftwareVersion": "1.0.0",
+			"ActiveFirmwareImage": "DeviceInfo.FirmwareImage.1",
+			"BootFirmwareImage": "DeviceInfo.FirmwareImage.1",
+			"BootCount": "1",
+			"FirmwareImage": {
+				"1": {"Name": "fw_1.0.0.bin", "Version": "1.0.0", "Status": "Active", "Available": "true"},
+				"2": {"Name": "", "Version": "", "Status": "NoImage", "Available": "false"}
+			}
+		},
+		"LocalAgent": {
+			"EndpointID": "agent-1",
+			"Controller": {}
+		},
+		"X_TP_RebootCause": {"Cause": "LocalReboot", "CommandKey": "", "Reason": ""}
+	}
+}`
+
+const firmwareImage = "Device.DeviceInfo.FirmwareImage.2."
+
+// firmwareContent 测试服务器返回的固件镜像内容
+var firmwareContent = []byte("firmware image content")
+
+func firmwareChecksum() string {
+	sum := sha256.Sum256(firmwareContent)
+	return hex.EncodeToString(sum[:])
+}
+
+func newFirmwareServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/fw_2.1.0.bin", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(firmwareContent)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func firmwareRequest(name string, inputArgs map[string]string) *commandRequest {
+	return &commandRequest{
+		ObjPath:    firmwareImage,
+		Name:       name,
+		CommandKey: "key-1",
+		Originator: "ctrl-1",
+		InputArgs:  inputArgs,
+	}
+}
+
+func TestFirmwareDownload(t *testing.T) {
+	server := newFirmwareServer(t)
+
+	tests := []struct {
+		name        string
+		args        map[string]string
+		wantErr     bool
+		wantStatus  string
+		wantVersion string
+	}{
+		{
+			name: "download with size and checksum",
+			args: map[string]string{
+				"URL":               server.URL + "/fw_2.1.0.bin",
+				"FileSize":          strconv.Itoa(len(firmwareContent)),
+				"CheckSumAlgorithm": "SHA-256",
+				"CheckSum":          firmwareChecksum(),
+			},
+			wantStatus:  model.FirmwareStatusAvailable,
+			wantVersion: "2.1.0",
+		},
+		{
+			name: "file size mismatch",
+			args: map[string]string{
+				"URL":      server.URL + "/fw_2.1.0.bin",
+				"FileSize": strconv.Itoa(len(firmwareContent) + 1),
+			},
+			wantErr:    true,
+			wantStatus: model.FirmwareStatusValidationFailed,
+		},
+		{
+			name: "checksum mismatch",
+			args: map[string]string{
+				"URL":               server.URL + "/fw_2.1.0.bin",
+				"CheckSumAlgorithm": "SHA-256",
+				"CheckSum":          hex.EncodeToString(make([]byte, sha256.Size)),
+			},
+			wantErr:    true,
+			wantStatus: model.FirmwareStatusValidationFailed,
+		},
+		{
+			name: "http not found",
+			args: map[string]string{
+				"URL": server.URL + "/missing.bin",
+			},
+			wantErr:    true,
+			wantStatus: model.FirmwareStatusDownloadFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, lm := newTestUseCase(t, firmwareTestData)
+			uc.SetHTTPClient(server.Client())
+
+			_, uspErr := uc.firmwareDownloadCommand(context.Background(), firmwareRequest("Download()", tt.args))
+			if (uspErr != nil) != tt.wantErr {
+				t.Fatalf("Download() error = %v, wantErr %v", uspErr, tt.wantErr)
+			}
+			if uspErr != nil && uspErr.Code != model.ErrCodeCommandFailure {
+				t.Errorf("Download() error code = %d, want %d", uspErr.Code, model.ErrCodeCommandFailure)
+			}
+
+			if status := uc.getStringValue(firmwareImage + "Status"); status != tt.wantStatus {
+				t.Errorf("Status = %q, want %q", status, tt.wantStatus)
+			}
+			if tt.wantVersion != "" {
+				if version := uc.getStringValue(firmwareImage + "Version"); version != tt.wantVersion {
+					t.Errorf("Version = %q, want %q", version, tt.wantVersion)
+				}
+				if available := uc.getStringValue(firmwareImage + "Available"); available != "true" {
+					t.Errorf("Available = %q, want %q", available, "true")
+				}
+			}
+
+			events := lm.eventsNamed(model.TransferComplete)
+			if len(events) != 1 {
+				t.Fatalf("got %d TransferComplete! events, want 1", len(events))
+			}
+			params := events[0].Params
+			if params["Affected"] != firmwareImage || params["CommandKey"] != "key-1" || params["Requestor"] != "ctrl-1" {
+				t.Errorf("TransferComplete! params = %v", params)
+			}
+			wantFault := "0"
+			if tt.wantErr {
+				wantFault = strconv.FormatUint(uint64(model.ErrCodeCommandFailure), 10)
+			}
+			if params["FaultCode"] != wantFault {
+				t.Errorf("TransferComplete! FaultCode = %q, want %q", params["FaultCode"], wantFault)
+			}
+			if tt.wantErr && params["FaultString"] == "" {
+				t.Errorf("TransferComplete! FaultString is empty")
+			}
+		})
+	}
+}
+
+func TestFirmwareDownloadFileURL(t *testing.T) {
+	transferDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(transferDir, "fw_2.1.0.bin"), firmwareContent, 0644); err != nil {
+		t.Fatalf("write firmware image: %v", err)
+	}
+	outside := filepath.Join(t.TempDir(), "fw_2.2.0.bin")
+	if err := os.WriteFile(outside, firmwareContent, 0644); err != nil {
+		t.Fatalf("write firmware image: %v", err)
+	}
+	if err := os.Symlink(outside, filepath.Join(transferDir, "link.bin")); err != nil {
+		t.Fatalf("create symlink: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		transferDir string
+		path        string
+		wantStatus  string
+	}{
+		{"inside transfer dir", transferDir, filepath.Join(transferDir, "fw_2.1.0.bin"), model.FirmwareStatusAvailable},
+		{"file transfer disabled", "", filepath.Join(transferDir, "fw_2.1.0.bin"), model.FirmwareStatusDownloadFailed},
+		{"outside transfer dir", transferDir, outside, model.FirmwareStatusDownloadFailed},
+		{"dot-dot escape", transferDir, transferDir + "/../" + filepath.Base(filepath.Dir(outside)) + "/fw_2.2.0.bin", model.FirmwareStatusDownloadFailed},
+		{"symlink escape", transferDir, filepath.Join(transferDir, "link.bin"), model.FirmwareStatusDownloadFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, _ := newTestUseCase(t, firmwareTestData)
+			uc.Config.DataRefreshConfig.FileTransferDir = tt.transferDir
+
+			uc.firmwareDownloadCommand(context.Background(), firmwareRequest("Download()", map[string]string{"URL": "file://" + tt.path}))
+			if status := uc.getStringValue(firmwareImage + "Status"); status != tt.wantStatus {
+				t.Errorf("Status = %q, want %q", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestFirmwareDownloadInvalidArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args map[string]string
+	}{
+		{name: "missing URL", args: map[string]string{}},
+		{name: "unsupported scheme", args: map[string]string{"URL": "ftp://example.com/fw.bin"}},
+		{name: "invalid file size", args: map[string]string{"URL": "http://example.com/fw.bin", "FileSize": "-1"}},
+		{name: "unsupported algorithm", args: map[string]string{"URL": "http://example.com/fw.bin", "CheckSumAlgorithm": "MD5", "CheckSum": "00"}},
+		{name: "checksum without algorithm", args: map[string]string{"URL": "http://example.com/fw.bin", "CheckSum": "00"}},
+		{name: "checksum not hex", args: map[string]string{"URL": "http://example.com/fw.bin", "CheckSumAlgorithm": "SHA-1", "CheckSum": "xyz"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, uspErr := parseFirmwareDownload(tt.args); uspErr == nil || uspErr.Code != model.ErrCodeInvalidCommandArgs {
+				t.Errorf("parseFirmwareDownload(%v) error = %v, want code %d", tt.args, uspErr, model.ErrCodeInvalidCommandArgs)
+			}
+		})
+	}
+}
+
+// prepareAvailableImage 将镜像 2 下载为可激活的镜像
+func prepareAvailableImage(t *testing.T, uc *ClientUseCase) {
+	t.Helper()
+	server := newFirmwareServer(t)
+	uc.SetHTTPClient(server.Client())
+	if _, uspErr := uc.firmwareDownloadCommand(context.Background(), firmwareRequest("Download()", map[string]string{"URL": server.URL + "/fw_2.1.0.bin"})); uspErr != nil {
+		t.Fatalf("Download() error: %v", uspErr)
+	}
+}
+
+func TestFirmwareActivateTimeWindow(t *testing.T) {
+	uc, lm := newTestUseCase(t, firmwareTestData)
+	prepareAvailableImage(t, uc)
+
+	args := map[string]string{
+		"TimeWindow.1.Start": "1",
+		"TimeWindow.1.End":   "60",
+		"TimeWindow.1.Mode":  model.TimeWindowModeAnyTime,
+	}
+	start := time.Now()
+	if _, uspErr := uc.firmwareActivateCommand(context.Background(), firmwareRequest("Activate()", args)); uspErr != nil {
+		t.Fatalf("Activate() error: %v", uspErr)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("Activate() returned after %v, want activation at the window start (1s)", elapsed)
+	}
+
+	if status := uc.getStringValue(firmwareImage + "Status"); status != model.FirmwareStatusActive {
+		t.Errorf("Status = %q, want %q", status, model.FirmwareStatusActive)
+	}
+	if status := uc.getStringValue("Device.DeviceInfo.FirmwareImage.1.Status"); status != model.FirmwareStatusAvailable {
+		t.Errorf("previous image Status = %q, want %q", status, model.FirmwareStatusAvailable)
+	}
+	if version := uc.getStringValue("Device.DeviceInfo.SoftwareVersion"); version != "2.1.0" {
+		t.Errorf("SoftwareVersion = %q, want %q", version, "2.1.0")
+	}
+	if ref := uc.getStringValue("Device.DeviceInfo.ActiveFirmwareImage"); ref != "DeviceInfo.FirmwareImage.2" {
+		t.Errorf("ActiveFirmwareImage = %q, want %q", ref, "DeviceInfo.FirmwareImage.2")
+	}
+
+	boots := lm.eventsNamed(model.BOOT)
+	if len(boots) != 1 {
+		t.Fatalf("got %d Boot! events, want 1", len(boots))
+	}
+	if boots[0].Params["FirmwareUpdated"] != "true" || boots[0].Params["Cause"] != model.BootCauseRemoteReboot {
+		t.Errorf("Boot! params = %v", boots[0].Params)
+	}
+}
+
+func TestFirmwareActivateCanceledDuringWindow(t *testing.T) {
+	uc, _ := newTestUseCase(t, firmwareTestData)
+	prepareAvailableImage(t, uc)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	args := map[string]string{"TimeWindow.1.Start": "30", "TimeWindow.1.End": "60"}
+	if _, uspErr := uc.firmwareActivateCommand(ctx, firmwareRequest("Activate()", args)); uspErr == nil || uspErr.Code != model.ErrCodeCommandCanceled {
+		t.Fatalf("Activate() error = %v, want code %d", uspErr, model.ErrCodeCommandCanceled)
+	}
+	if status := uc.getStringValue(firmwareImage + "Status"); status != model.FirmwareStatusAvailable {
+		t.Errorf("Status = %q, want %q", status, model.FirmwareStatusAvailable)
+	}
+}
+
+func TestParseTimeWindows(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      map[string]string
+		wantStart []time.Duration
+		wantErr   bool
+	}{
+		{
+			name:      "no time window",
+			args:      map[string]string{},
+			wantStart: []time.Duration{},
+		},
+		{
+			name: "sorted by start",
+			args: map[string]string{
+				"TimeWindow.1.Start": "120", "TimeWindow.1.End": "180",
+				"TimeWindow.2.Start": "10", "TimeWindow.2.End": "20", "TimeWindow.2.Mode": model.TimeWindowModeWhenIdle,
+			},
+			wantStart: []time.Duration{10 * time.Second, 120 * time.Second},
+		},
+		{name: "missing end", args: map[string]string{"TimeWindow.1.Start": "10"}, wantErr: true},
+		{name: "end before start", args: map[string]string{"TimeWindow.1.Start": "20", "TimeWindow.1.End": "10"}, wantErr: true},
+		{name: "invalid mode", args: map[string]string{"TimeWindow.1.Start": "0", "TimeWindow.1.End": "10", "TimeWindow.1.Mode": "Later"}, wantErr: true},
+		{name: "invalid start", args: map[string]string{"TimeWindow.1.Start": "soon", "TimeWindow.1.End": "10"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			windows, uspErr := parseTimeWindows(tt.args)
+			if tt.wantErr {
+				if uspErr == nil || uspErr.Code != model.ErrCodeInvalidCommandArgs {
+					t.Fatalf("parseTimeWindows() error = %v, want code %d", uspErr, model.ErrCodeInvalidCommandArgs)
+				}
+				return
+			}
+			if uspErr != nil {
+				t.Fatalf("parseTimeWindows() error: %v", uspErr)
+			}
+			if len(windows) != len(tt.wantStart) {
+				t.Fatalf("got %d windows, want %d", len(windows), len(tt.wantStart))
+			}
+			for i, window := range windows {
+				if window.start != tt.wantStart[i] {
+					t.Errorf("window %d start = %v, want %v", i, window.start, tt.wantStart[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFirstAutomaticWindow(t *testing.T) {
+	windows := []timeWindow{
+		{index: 1, start: 0, end: time.Minute, mode: model.TimeWindowModeConfirmationNeeded},
+		{index: 2, start: time.Minute, end: 2 * time.Minute, mode: model.TimeWindowModeWhenIdle},
+	}
+	if window, ok := firstAutomaticWindow(windows); !ok || window.index != 2 {
+		t.Errorf("firstAutomaticWindow() = (%d, %v), want (2, true)", window.index, ok)
+	}
+	if _, ok := firstAutomaticWindow(windows[:1]); ok {
+		t.Errorf("firstAutomaticWindow() with only ConfirmationNeeded windows reported a window")
+	}
+}
@@ -1,7 +1,11 @@
 package usecase
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"tr369-wss-client/client/model"
+	logger "tr369-wss-client/log"
 	"tr369-wss-client/pkg/api"
 	"tr369-wss-client/trtree"
 )
@@ -17,15 +21,112 @@ func extractParamSettings[T model.ParamSetting](settings []T) map[string]string
 }
 
 // constructGetResp 构建 GET 响应（业务逻辑）
-func (uc *ClientUseCase) constructGetResp(paths []string) api.Response_GetResp {
+// 每个请求路径通过路径解析器匹配，每个对象实例返回一个 ResolvedPathResult
+// maxDepth 为 GET 请求的 max_depth，为 0 时返回所有层级的子对象
+func (uc *ClientUseCase) constructGetResp(paths []string, maxDepth uint32) api.Response_GetResp {
 	params := uc.DataRepo.GetParameters()
-	return trtree.ConstructGetResp(params, paths)
+	response := api.Response_GetResp{
+		GetResp: &api.GetResp{
+			ReqPathResults: []*api.GetResp_RequestedPathResult{},
+		},
+	}
+
+	for _, path := range paths {
+		requestedPathResult := &api.GetResp_RequestedPathResult{
+			RequestedPath:       path,
+			ResolvedPathResults: []*api.GetResp_ResolvedPathResult{},
+		}
+		results, err := trtree.ResolveGetPath(params, path, maxDepth)
+		if err != nil {
+			uspErr := pathResolveError(path, err)
+			requestedPathResult.ErrCode = uspErr.Code
+			requestedPathResult.ErrMsg = uspErr.Message
+		} else {
+			requestedPathResult.ResolvedPathResults = results
+		}
+		response.GetResp.ReqPathResults = append(response.GetResp.ReqPathResults, requestedPathResult)
+	}
+
+	return response
+}
+
+// pathSyntaxError 将路径语法错误转换为 USP 错误
+func pathSyntaxError(err error) *model.USPError {
+	detail := strings.TrimPrefix(err.Error(), trtree.ErrInvalidPathSyntax.Error()+": ")
+	return model.NewUSPError(model.ErrCodeInvalidPathSyntax, "%s", detail)
+}
+
+// pathResolveError 将路径解析错误转换为 USP 错误
+func pathResolveError(path string, err error) *model.USPError {
+	if errors.Is(err, trtree.ErrInvalidPathSyntax) {
+		return pathSyntaxError(err)
+	}
+	return model.NewUSPError(model.ErrCodeInvalidPath, "%s", path)
+}
+
+// defaultUniqueKeys 注册表中未声明唯一键时使用的默认唯一键（TR-181 中所有表均以 Alias 为唯一键）
+var defaultUniqueKeys = []string{"Alias"}
+
+// constructGetInstancesResp 构建 GET_INSTANCES 响应
+func (uc *ClientUseCase) constructGetInstancesResp(objPaths []string, firstLevelOnly bool) api.Response_GetInstancesResp {
+	params := uc.DataRepo.GetParameters()
+	response := api.Response_GetInstancesResp{
+		GetInstancesResp: &api.GetInstancesResp{
+			ReqPathResults: []*api.GetInstancesResp_RequestedPathResult{},
+		},
+	}
+
+	for _, objPath := range objPaths {
+		reqPathResult := &api.GetInstancesResp_RequestedPathResult{
+			RequestedPath: objPath,
+		}
+		instances, err := trtree.CollectInstances(params, objPath, firstLevelOnly, uc.uniqueKeyNames)
+		if errors.Is(err, trtree.ErrInvalidPathSyntax) {
+			reqPathResult.ErrCode = model.ErrCodeInvalidPathSyntax
+			reqPathResult.ErrMsg = pathSyntaxError(err).Message
+		} else if err != nil {
+			reqPathResult.ErrCode = model.ErrCodeObjectNotExist
+			reqPathResult.ErrMsg = fmt.Sprintf("object does not exist: %s", objPath)
+		} else {
+			reqPathResult.CurrInsts = instances
+		}
+		response.GetInstancesResp.ReqPathResults = append(response.GetInstancesResp.ReqPathResults, reqPathResult)
+	}
+
+	return response
 }
 
-// isExistPath 检查路径是否存在（包含路径表达式解析）
-func (uc *ClientUseCase) isExistPath(path string) (isSuccess bool, nodePath string) {
+// uniqueKeyNames 获取实例的唯一键参数名（优先使用支持的数据模型中声明的唯一键）
+func (uc *ClientUseCase) uniqueKeyNames(instPath string) []string {
+	if uc.SupportedDM != nil {
+		if obj, ok := uc.SupportedDM.GetObject(instPath); ok && len(obj.UniqueKeys) > 0 {
+			var names []string
+			for _, keySet := range obj.UniqueKeys {
+				names = append(names, keySet...)
+			}
+			return names
+		}
+	}
+	return defaultUniqueKeys
+}
+
+// instanceUniqueKeys 获取实例当前的唯一键取值
+func (uc *ClientUseCase) instanceUniqueKeys(instPath string) map[string]string {
+	uniqueKeys := make(map[string]string)
+	for _, name := range uc.uniqueKeyNames(instPath) {
+		if value, err := uc.DataRepo.GetValue(instPath + name); err == nil {
+			if strValue, ok := value.(string); ok {
+				uniqueKeys[name] = strValue
+			}
+		}
+	}
+	return uniqueKeys
+}
+
+// resolveObjectPaths 解析对象路径（支持通配符和搜索表达式），返回匹配的对象实例路径
+func (uc *ClientUseCase) resolveObjectPaths(objPath string) ([]string, error) {
 	params := uc.DataRepo.GetParameters()
-	return trtree.IsExistPath(params, path)
+	return trtree.ResolveObjectPath(params, objPath)
 }
 
 // getNewInstance 获取新实例路径（包含实例编号生成策略）
@@ -33,3 +134,44 @@ func (uc *ClientUseCase) getNewInstance(path string) string {
 	params := uc.DataRepo.GetParameters()
 	return trtree.GetNewInstance(params, path)
 }
+
+// getStringValue 获取参数值，参数不存在或不是字符串时返回空字符串
+func (uc *ClientUseCase) getStringValue(paramPath string) string {
+	value, err := uc.DataRepo.GetValue(paramPath)
+	if err != nil {
+		return ""
+	}
+	str, _ := value.(string)
+	return str
+}
+
+// updateParams 在后台任务中更新对象参数，并为发生变化的参数发送 ValueChange 通知（调用方不能持有数据锁）
+func (uc *ClientUseCase) updateParams(objPath string, values map[string]string) {
+	uc.DataRepo.Lock()
+	changed := uc.setParams(objPath, values)
+	uc.DataRepo.Unlock()
+	uc.notifyValueChanges(changed)
+}
+
+// setParams 更新对象参数，返回发生变化的参数路径和新值（调用方需持有数据锁）
+func (uc *ClientUseCase) setParams(objPath string, values map[string]string) map[string]string {
+	changed := make(map[string]string)
+	for key, value := range values {
+		ok, _, err := uc.DataRepo.SetValue(objPath, key, value)
+		if err != nil {
+			logger.Warnf("[USP] update param error: path=%s%s, err=%v", objPath, key, err)
+			continue
+		}
+		if ok {
+			changed[objPath+key] = value
+		}
+	}
+	return changed
+}
+
+// notifyValueChanges 为发生变化的参数发送 ValueChange 通知
+func (uc *ClientUseCase) notifyValueChanges(changed map[string]string) {
+	for paramPath, value := range changed {
+		uc.notifyValueChange(paramPath, value)
+	}
+}
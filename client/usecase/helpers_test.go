@@ -0,0 +1,256 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"tr369-wss-client/client/repository"
+	"tr369-wss-client/config"
+	logger "tr369-wss-client/log"
+	"tr369-wss-client/pkg/api"
+	tr181Model "tr369-wss-client/tr181/model"
+	"tr369-wss-client/utils"
+)
+
+func TestMain(m *testing.M) {
+	logger.InitLogger()
+	os.Exit(m.Run())
+}
+
+// newTestSupportedDM 使用仓库自带的节点文件和元数据文件创建支持的数据模型注册表
+func newTestSupportedDM(t *testing.T) *repository.SupportedDMRegistry {
+	t.Helper()
+	registry := repository.NewSupportedDMRegistry(&config.Config{
+		DataModelConfig: &config.DataModelConfig{
+			SupportedNodesPath: "../../data/default_tr181_nodes.json",
+			SupportedMetaPath:  "../../data/supported_dm_meta.json",
+		},
+	})
+	if err := registry.Load(); err != nil {
+		t.Fatalf("load supported dm: %v", err)
+	}
+	return registry
+}
+
+// recordingListenerManager 记录发出的通知，不注册监听器也不发送 Notify
+type recordingListenerManager struct {
+	mu            sync.Mutex
+	notifications []interface{}
+}
+
+func (lm *recordingListenerManager) AddListener(string, tr181Model.Listener) error { return nil }
+func (lm *recordingListenerManager) RemoveListener(string) error                   { return nil }
+func (lm *recordingListenerManager) RemoveSubscription(string) error               { return nil }
+func (lm *recordingListenerManager) ResetListener() error                          { return nil }
+func (lm *recordingListenerManager) RegisterHandler(string, tr181Model.Handler)    {}
+
+func (lm *recordingListenerManager) NotifyListeners(paramName string, value interface{}) {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+	lm.notifications = append(lm.notifications, value)
+}
+
+// valueChanges 返回指定参数的 ValueChange 通知中的值（按发送顺序）
+func (lm *recordingListenerManager) valueChanges(paramPath string) []string {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+
+	var values []string
+	for _, notification := range lm.notifications {
+		if change, ok := notification.(*api.Notify_ValueChange_); ok && change.ValueChange.ParamPath == paramPath {
+			values = append(values, change.ValueChange.ParamValue)
+		}
+	}
+	return values
+}
+
+// operCompletes 返回发出的 OperationComplete 通知
+func (lm *recordingListenerManager) operCompletes() []*api.Notify_OperationComplete {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+
+	var completes []*api.Notify_OperationComplete
+	for _, notification := range lm.notifications {
+		if complete, ok := notification.(*api.Notify_OperComplete); ok {
+			completes = append(completes, complete.OperComplete)
+		}
+	}
+	return completes
+}
+
+// objDeletions 返回 ObjectDeletion 通知中删除的对象路径（按发送顺序）
+func (lm *recordingListenerManager) objDeletions() []string {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+
+	var paths []string
+	for _, notification := range lm.notifications {
+		if deletion, ok := notification.(*api.Notify_ObjDeletion); ok {
+			paths = append(paths, deletion.ObjDeletion.ObjPath)
+		}
+	}
+	return paths
+}
+
+// eventsNamed 返回指定名称的事件（按发送顺序）
+func (lm *recordingListenerManager) eventsNamed(name string) []*api.Notify_Event {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+
+	var events []*api.Notify_Event
+	for _, notification := range lm.notifications {
+		if targeted, ok := notification.(*controllerEvent); ok {
+			notification = targeted.event
+		}
+		if event, ok := notification.(*api.Notify_Event_); ok && event.Event.EventName == name {
+			events = append(events, event.Event)
+		}
+	}
+	return events
+}
+
+// controllerEvents 返回发送给指定 controller 的指定名称的事件，测试中传给 HandleEvent 模拟订阅的投递
+func (lm *recordingListenerManager) controllerEvents(endpointId string, name string) []*controllerEvent {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+
+	var events []*controllerEvent
+	for _, notification := range lm.notifications {
+		if targeted, ok := notification.(*controllerEvent); ok && targeted.endpointId == endpointId && targeted.event.Event.EventName == name {
+			events = append(events, targeted)
+		}
+	}
+	return events
+}
+
+// newTestUseCase 使用 data 作为持久化数据创建 ClientUseCase，不连接 controller
+func newTestUseCase(t *testing.T, data string) (*ClientUseCase, *recordingListenerManager) {
+	t.Helper()
+
+	var tree map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &tree); err != nil {
+		t.Fatalf("unmarshal test data: %v", err)
+	}
+	dataPath := filepath.Join(t.TempDir(), "data.json")
+	content, _ := json.Marshal(tree)
+	if err := os.WriteFile(dataPath, content, 0644); err != nil {
+		t.Fatalf("write test data: %v", err)
+	}
+
+	cfg := &config.Config{
+		DataRefreshConfig: &config.DataRefreshConfig{
+			IntervalSeconds:     3600,
+			WriteCountThreshold: 1000,
+			TR181DataModelPath:  dataPath,
+		},
+		WebsocketConfig: &config.WebsocketConfig{
+			EndpointId:   "agent-1",
+			ControllerId: "ctrl-1",
+			ServerURL:    "ws://127.0.0.1:1/usp",
+		},
+		Tr369Config:     &config.TR369Config{Version: "1.0", SupportedVersions: "1.0"},
+		DataModelConfig: &config.DataModelConfig{},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	dataRepo, _ := repository.NewRepository(cfg, ctx, cancel)
+	dataRepo.Start()
+
+	listenerMgr := &recordingListenerManager{}
+	uc := NewClientUseCase(ctx, cfg, dataRepo, listenerMgr, newTestSupportedDM(t))
+	uc.SetWSClient(&recordingWSClient{uc: uc, payloads: make(chan []byte, 16)})
+	return uc, listenerMgr
+}
+
+// recordingWSClient 记录发送给各 controller 的 Record，不建立连接
+type recordingWSClient struct {
+	uc       *ClientUseCase
+	payloads chan []byte
+}
+
+func (c *recordingWSClient) Connect() error       { return nil }
+func (c *recordingWSClient) Disconnect()          {}
+func (c *recordingWSClient) StartMessageHandler() {}
+func (c *recordingWSClient) SyncControllers()     {}
+
+// Restart 执行 powerCycle 后按与每个 controller 重新建立连接处理
+func (c *recordingWSClient) Restart(powerCycle func()) error {
+	powerCycle()
+	for _, endpoint := range c.uc.ControllerEndpoints() {
+		c.uc.OnConnected(endpoint.EndpointID)
+	}
+	return nil
+}
+
+func (c *recordingWSClient) Send(toId string, payload []byte) error {
+	select {
+	case c.payloads <- payload:
+		return nil
+	default:
+		return fmt.Errorf("send queue of controller %s is full", toId)
+	}
+}
+
+// sentMessage 发送的 Record 中的目标 endpoint 和 USP 消息
+type sentMessage struct {
+	toId string
+	msg  *api.Msg
+}
+
+// drainSentMessages 取出已发送的所有 USP 消息，忽略 DisconnectRecord
+func drainSentMessages(t *testing.T, uc *ClientUseCase) []sentMessage {
+	t.Helper()
+	var sent []sentMessage
+	for {
+		select {
+		case payload := <-uc.wsClient.(*recordingWSClient).payloads:
+			record, err := utils.DecodeUSPRecord(payload)
+			if err != nil {
+				t.Fatalf("decode record: %v", err)
+			}
+			if record.GetNoSessionContext() == nil {
+				continue
+			}
+			msg, err := utils.DecodeUSPMessage(record.GetNoSessionContext().GetPayload())
+			if err != nil {
+				t.Fatalf("decode message: %v", err)
+			}
+			sent = append(sent, sentMessage{toId: record.GetToId(), msg: msg})
+		default:
+			return sent
+		}
+	}
+}
+
+func TestUniqueKeyNames(t *testing.T) {
+	registry := newTestSupportedDM(t)
+
+	tests := []struct {
+		name        string
+		supportedDM bool
+		instPath    string
+		want        []string
+	}{
+		{"declared in meta", true, "Device.LocalAgent.Controller.1.", []string{"EndpointID", "Alias"}},
+		{"registry without unique keys", true, "Device.LocalAgent.Request.1.", []string{"Alias"}},
+		{"no registry", false, "Device.LocalAgent.Controller.1.", []string{"Alias"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := &ClientUseCase{}
+			if tt.supportedDM {
+				uc.SupportedDM = registry
+			}
+			if got := uc.uniqueKeyNames(tt.instPath); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("uniqueKeyNames(%s) = %v, want %v", tt.instPath, got, tt.want)
+			}
+		})
+	}
+}
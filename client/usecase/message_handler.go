@@ -1,13 +1,19 @@
 package usecase
 
 import (
+	"errors"
+	"strings"
+	"time"
+
+	"tr369-wss-client/client/model"
 	logger "tr369-wss-client/log"
 	"tr369-wss-client/pkg/api"
+	"tr369-wss-client/trtree"
 	"tr369-wss-client/utils"
 )
 
 // HandleGetRequest handles incoming GET requests
-func (uc *ClientUseCase) HandleGetRequest(inComingMsg *api.Msg) {
+func (uc *ClientUseCase) HandleGetRequest(fromId string, inComingMsg *api.Msg) {
 	// 防御性检查
 	if inComingMsg == nil || inComingMsg.Header == nil {
 		logger.Warnf("[USP] HandleGetRequest received invalid message")
@@ -17,19 +23,69 @@ func (uc *ClientUseCase) HandleGetRequest(inComingMsg *api.Msg) {
 	msgId := inComingMsg.Header.MsgId
 	logger.Infof("[USP] receive GET request: %s", inComingMsg.String())
 
-	getNodePaths := inComingMsg.GetBody().GetRequest().GetGet().GetParamPaths()
-	resp := uc.constructGetResp(getNodePaths)
+	get := inComingMsg.GetBody().GetRequest().GetGet()
+	resp := uc.constructGetResp(get.GetParamPaths(), get.GetMaxDepth())
+	// 按 ControllerTrust 的角色权限过滤没有读权限的参数
+	uc.accessRules(fromId).filterGetResp(resp.GetResp)
 	msg := utils.CreateGetResponseMessage(msgId, resp)
 	logger.Infof("[USP] send GET response: %s", msg.String())
 
-	err := uc.HandleMTPMsgTransmit(msg)
+	err := uc.HandleMTPMsgTransmitTo(fromId, msg)
 	if err != nil {
 		logger.Warnf("[USP] GET error: msgId=%s, err=%v", msgId, err)
 	}
 }
 
+// HandleGetSupportedDMRequest handles incoming GET_SUPPORTED_DM requests
+func (uc *ClientUseCase) HandleGetSupportedDMRequest(fromId string, inComingMsg *api.Msg) {
+	// 防御性检查
+	if inComingMsg == nil || inComingMsg.Header == nil {
+		logger.Warnf("[USP] HandleGetSupportedDMRequest received invalid message")
+		return
+	}
+
+	msgId := inComingMsg.Header.MsgId
+	logger.Infof("[USP] receive GET_SUPPORTED_DM request: %s", inComingMsg.String())
+
+	resp := uc.constructGetSupportedDMResp(inComingMsg.GetBody().GetRequest().GetGetSupportedDm())
+	// 按 ControllerTrust 的角色权限过滤没有读权限的对象、参数、命令和事件
+	uc.accessRules(fromId).filterGetSupportedDMResp(resp.GetSupportedDmResp)
+	msg := utils.CreateGetSupportedDMResponseMessage(msgId, resp)
+	logger.Infof("[USP] send GET_SUPPORTED_DM response: %s", msg.String())
+
+	err := uc.HandleMTPMsgTransmitTo(fromId, msg)
+	if err != nil {
+		logger.Warnf("[USP] GET_SUPPORTED_DM error: msgId=%s, err=%v", msgId, err)
+	}
+}
+
+// HandleGetInstancesRequest handles incoming GET_INSTANCES requests
+func (uc *ClientUseCase) HandleGetInstancesRequest(fromId string, inComingMsg *api.Msg) {
+	// 防御性检查
+	if inComingMsg == nil || inComingMsg.Header == nil {
+		logger.Warnf("[USP] HandleGetInstancesRequest received invalid message")
+		return
+	}
+
+	msgId := inComingMsg.Header.MsgId
+	logger.Infof("[USP] receive GET_INSTANCES request: %s", inComingMsg.String())
+
+	getInstances := inComingMsg.GetBody().GetRequest().GetGetInstances()
+	resp := uc.constructGetInstancesResp(getInstances.GetObjPaths(), getInstances.GetFirstLevelOnly())
+	// 按 ControllerTrust 的角色权限过滤没有读权限的实例
+	uc.accessRules(fromId).filterGetInstancesResp(resp.GetInstancesResp)
+	msg := utils.CreateGetInstancesResponseMessage(msgId, resp)
+	logger.Infof("[USP] send GET_INSTANCES response: %s", msg.String())
+
+	err := uc.HandleMTPMsgTransmitTo(fromId, msg)
+	if err != nil {
+		logger.Warnf("[USP] GET_INSTANCES error: msgId=%s, err=%v", msgId, err)
+	}
+}
+
 // HandleSetRequest handles incoming SET requests
-func (uc *ClientUseCase) HandleSetRequest(inComingMsg *api.Msg) {
+// 所有修改在同一事务中执行：allow_partial=false 时任一对象失败都会回滚并返回 Error 消息
+func (uc *ClientUseCase) HandleSetRequest(fromId string, inComingMsg *api.Msg) {
 	// 防御性检查
 	if inComingMsg == nil || inComingMsg.Header == nil {
 		logger.Warnf("[USP] HandleSetRequest received invalid message")
@@ -39,45 +95,190 @@ func (uc *ClientUseCase) HandleSetRequest(inComingMsg *api.Msg) {
 	msgId := inComingMsg.Header.MsgId
 	logger.Infof("[USP] receive SET request: %s", inComingMsg.String())
 
-	getUpdateObjs := inComingMsg.GetBody().GetRequest().GetSet().GetUpdateObjs()
+	set := inComingMsg.GetBody().GetRequest().GetSet()
+
+	if err := uc.DataRepo.BeginTransaction(); err != nil {
+		logger.Warnf("[USP] SET begin transaction error: msgId=%s, err=%v", msgId, err)
+		uc.SendErrorMessage(uc.Config.WebsocketConfig.ControllerId, msgId, model.ErrCodeInternalError, err.Error())
+		return
+	}
+	var results []*api.SetResp_UpdatedObjectResult
+	var afterCommit []func()
+	for _, updateObj := range set.GetUpdateObjs() {
+		result, effects := uc.setObject(updateObj)
+		results = append(results, result)
+		afterCommit = append(afterCommit, effects...)
+	}
+
+	var msg *api.Msg
+	if uspErr, paramErrs := setFailures(results); uspErr != nil && !set.GetAllowPartial() {
+		uc.DataRepo.RollbackTransaction()
+		msg = utils.CreateErrorMessage(msgId, uspErr.Code, uspErr.Message, paramErrs)
+	} else {
+		uc.DataRepo.CommitTransaction()
+		uc.DataRepo.FlushDataChanges()
+		runEffects(afterCommit)
+		msg = utils.CreateSetResponseMessage(msgId, results)
+	}
+	logger.Infof("[USP] send SET response: %s", msg.String())
+
+	err := uc.HandleMTPMsgTransmitTo(fromId, msg)
+	if err != nil {
+		logger.Warnf("[USP] SET error: msgId=%s, err=%v", msgId, err)
+	}
+}
+
+// setObject 处理单个对象的 SET 操作，路径可通过通配符或搜索表达式匹配多个实例
+// 对象不存在或任一实例的必需参数校验失败时返回 OperFailure 且不做任何修改，其余参数错误记录在 ParamErrs 中
+// 返回的 effects 为事务提交后需要执行的通知
+func (uc *ClientUseCase) setObject(updateObj *api.Set_UpdateObject) (*api.SetResp_UpdatedObjectResult, []func()) {
+	path := updateObj.GetObjPath()
+	nodePaths, uspErr := uc.resolveExistingObjects(path)
+	if uspErr != nil {
+		logger.Warnf("[USP] SET object error: path=%s, err=%v", path, uspErr)
+		return utils.CreateSetFailureResult(path, uspErr.Code, uspErr.Message, nil), nil
+	}
 
-	var affectedPath []string
-	var requestPath []string
-	var updatedParams []map[string]string
+	// 先校验所有实例，任一实例的必需参数失败则整个对象失败
+	instResults := []*api.SetResp_UpdatedInstanceResult{}
+	var instFailures []*api.SetResp_UpdatedInstanceFailure
+	for _, nodePath := range nodePaths {
+		var paramErrs []*api.SetResp_ParameterError
+		requiredFailed := false
+		paramSettings := make(map[string]string)
+		for _, setting := range updateObj.GetParamSettings() {
+			uspErr := uc.validateParamSetting(nodePath, setting.GetParam(), setting.GetValue())
+			if uspErr == nil {
+				uspErr = uc.validateSubscriptionSetting(nodePath, setting.GetParam(), setting.GetValue())
+			}
+			if uspErr != nil {
+				paramErrs = append(paramErrs, &api.SetResp_ParameterError{
+					Param:   setting.GetParam(),
+					ErrCode: uspErr.Code,
+					ErrMsg:  uspErr.Message,
+				})
+				if setting.GetRequired() {
+					requiredFailed = true
+				}
+				continue
+			}
+			paramSettings[setting.GetParam()] = setting.GetValue()
+		}
 
-	for _, updateObj := range getUpdateObjs {
-		path := updateObj.GetObjPath()
-		isSuccess, nodePath := uc.isExistPath(path)
-		if !isSuccess {
+		if requiredFailed {
+			instFailures = append(instFailures, &api.SetResp_UpdatedInstanceFailure{AffectedPath: nodePath, ParamErrs: paramErrs})
 			continue
 		}
-		requestPath = append(requestPath, path)
-		affectedPath = append(affectedPath, nodePath)
+		instResults = append(instResults, &api.SetResp_UpdatedInstanceResult{
+			AffectedPath:  nodePath,
+			ParamErrs:     paramErrs,
+			UpdatedParams: paramSettings,
+		})
+	}
+
+	if len(instFailures) > 0 {
+		logger.Warnf("[USP] SET required parameter failed: path=%s", path)
+		return utils.CreateSetFailureResult(path, model.ErrCodeRequiredParamFailed, model.ErrMessage(model.ErrCodeRequiredParamFailed), instFailures), nil
+	}
+
+	required := make(map[string]bool)
+	for _, setting := range updateObj.GetParamSettings() {
+		required[setting.GetParam()] = required[setting.GetParam()] || setting.GetRequired()
+	}
 
-		// 使用辅助函数提取参数设置
-		paramSettings := extractParamSettings(updateObj.GetParamSettings())
-		// 将参数设置应用到 repository，并处理 value change 通知
-		for setKey, setValue := range paramSettings {
-			changed, _ := uc.DataRepo.SetValue(nodePath, setKey, setValue)
+	// 将参数设置应用到 repository，value change 通知在事务提交后发送
+	// 写入失败的参数记录在 ParamErrs 中；必需参数写入失败时撤销该对象的所有修改并返回 OperFailure
+	savepoint := uc.DataRepo.Savepoint()
+	var effects []func()
+	controllerChanged := false
+	for _, instResult := range instResults {
+		subscriptionChanged, ttlChanged := false, false
+		for setKey, setValue := range instResult.UpdatedParams {
+			changed, _, err := uc.DataRepo.SetValue(instResult.AffectedPath, setKey, setValue)
+			if err != nil {
+				logger.Warnf("[USP] SET parameter write failed: path=%s%s, err=%v", instResult.AffectedPath, setKey, err)
+				paramErr := &api.SetResp_ParameterError{
+					Param:   setKey,
+					ErrCode: model.ErrCodeParamActionFailed,
+					ErrMsg:  model.NewUSPError(model.ErrCodeParamActionFailed, "%v", err).Message,
+				}
+				if required[setKey] {
+					uc.DataRepo.RollbackToSavepoint(savepoint)
+					instFailures := []*api.SetResp_UpdatedInstanceFailure{{AffectedPath: instResult.AffectedPath, ParamErrs: []*api.SetResp_ParameterError{paramErr}}}
+					return utils.CreateSetFailureResult(path, model.ErrCodeRequiredParamFailed, model.ErrMessage(model.ErrCodeRequiredParamFailed), instFailures), nil
+				}
+				delete(instResult.UpdatedParams, setKey)
+				instResult.ParamErrs = append(instResult.ParamErrs, paramErr)
+				continue
+			}
 			if changed {
-				// 发送 value change 通知
-				uc.notifyValueChange(nodePath+setKey, setValue)
+				paramPath, value := instResult.AffectedPath+setKey, setValue
+				effects = append(effects, func() { uc.notifyValueChange(paramPath, value) })
+				controllerChanged = controllerChanged || isControllerParam(paramPath)
+				subscriptionChanged = subscriptionChanged || subscriptionListenerParams[setKey]
+				ttlChanged = ttlChanged || setKey == "TimeToLive"
 			}
 		}
-		updatedParams = append(updatedParams, paramSettings)
+		// 订阅的 Enable、ID、NotifType、ReferenceList 变化后重新注册监听器
+		if subscriptionChanged && uc.isSubscriptionInstancePath(instResult.AffectedPath) {
+			instancePath := instResult.AffectedPath
+			effects = append(effects, func() {
+				if err := uc.syncSubscription(instancePath); err != nil {
+					logger.Warnf("[USP] SET subscription update error: path=%s, err=%v", instancePath, err)
+				}
+			})
+		}
+		// 订阅的 TimeToLive 变化后按 CreationDate 重新计算过期时间
+		if ttlChanged && uc.isSubscriptionInstancePath(instResult.AffectedPath) {
+			instancePath := instResult.AffectedPath
+			effects = append(effects, func() { uc.scheduleSubscriptionExpiry(instancePath) })
+		}
+	}
+	// Controller 的连接或 Periodic! 参数变化后立即同步连接并重新调度
+	if controllerChanged {
+		effects = append(effects, uc.controllersChanged)
 	}
 
-	msg := utils.CreateSetResponseMessage(msgId, requestPath, affectedPath, updatedParams)
-	logger.Infof("[USP] send SET response: %s", msg.String())
+	return utils.CreateSetSuccessResult(path, instResults), effects
+}
 
-	err := uc.HandleMTPMsgTransmit(msg)
-	if err != nil {
-		logger.Warnf("[USP] SET error: msgId=%s, err=%v", msgId, err)
+// setFailures 汇总 SET 结果中的失败对象，用于 allow_partial=false 时构建 Error 消息
+// 返回第一个失败对象的错误，没有失败时返回 nil
+func setFailures(results []*api.SetResp_UpdatedObjectResult) (*model.USPError, []*api.Error_ParamError) {
+	var first *model.USPError
+	var paramErrs []*api.Error_ParamError
+	for _, result := range results {
+		failure := result.GetOperStatus().GetOperFailure()
+		if failure == nil {
+			continue
+		}
+		if first == nil {
+			first = &model.USPError{Code: failure.GetErrCode(), Message: failure.GetErrMsg()}
+		}
+		if len(failure.GetUpdatedInstFailures()) == 0 {
+			paramErrs = append(paramErrs, &api.Error_ParamError{
+				ParamPath: result.GetRequestedPath(),
+				ErrCode:   failure.GetErrCode(),
+				ErrMsg:    failure.GetErrMsg(),
+			})
+			continue
+		}
+		for _, instFailure := range failure.GetUpdatedInstFailures() {
+			for _, paramErr := range instFailure.GetParamErrs() {
+				paramErrs = append(paramErrs, &api.Error_ParamError{
+					ParamPath: instFailure.GetAffectedPath() + paramErr.GetParam(),
+					ErrCode:   paramErr.GetErrCode(),
+					ErrMsg:    paramErr.GetErrMsg(),
+				})
+			}
+		}
 	}
+	return first, paramErrs
 }
 
 // HandleAddRequest handles incoming ADD requests
-func (uc *ClientUseCase) HandleAddRequest(inComingMsg *api.Msg) {
+// 所有对象在同一事务中创建：allow_partial=false 时任一对象失败都会回滚并返回 Error 消息
+func (uc *ClientUseCase) HandleAddRequest(fromId string, inComingMsg *api.Msg) {
 	// 防御性检查
 	if inComingMsg == nil || inComingMsg.Header == nil {
 		logger.Warnf("[USP] HandleAddRequest received invalid message")
@@ -87,55 +288,170 @@ func (uc *ClientUseCase) HandleAddRequest(inComingMsg *api.Msg) {
 	msgId := inComingMsg.Header.MsgId
 	logger.Infof("[USP] receive ADD request: %s", inComingMsg.String())
 
-	getCreateObjs := inComingMsg.GetBody().GetRequest().GetAdd().GetCreateObjs()
+	add := inComingMsg.GetBody().GetRequest().GetAdd()
 
-	var affectedPath []string
-	var requestPath []string
-	var updatedParams []map[string]string
+	if err := uc.DataRepo.BeginTransaction(); err != nil {
+		logger.Warnf("[USP] ADD begin transaction error: msgId=%s, err=%v", msgId, err)
+		uc.SendErrorMessage(uc.Config.WebsocketConfig.ControllerId, msgId, model.ErrCodeInternalError, err.Error())
+		return
+	}
+	var results []*api.AddResp_CreatedObjectResult
+	var afterCommit []func()
+	for _, createObj := range add.GetCreateObjs() {
+		result, effects := uc.addObject(fromId, createObj)
+		results = append(results, result)
+		afterCommit = append(afterCommit, effects...)
+	}
+
+	var msg *api.Msg
+	if uspErr, paramErrs := addFailures(results); uspErr != nil && !add.GetAllowPartial() {
+		uc.DataRepo.RollbackTransaction()
+		msg = utils.CreateErrorMessage(msgId, uspErr.Code, uspErr.Message, paramErrs)
+	} else {
+		uc.DataRepo.CommitTransaction()
+		uc.DataRepo.FlushDataChanges()
+		runEffects(afterCommit)
+		msg = utils.CreateAddResponseMessage(msgId, results)
+	}
+	logger.Infof("[USP] send ADD response: %s", msg.String())
+
+	err := uc.HandleMTPMsgTransmitTo(fromId, msg)
+	if err != nil {
+		logger.Warnf("[USP] ADD error: msgId=%s, err=%v", msgId, err)
+	}
+}
 
-	for _, createObj := range getCreateObjs {
-		path := createObj.GetObjPath()
-		nodePath := uc.getNewInstance(path)
+// addObject 处理单个对象的 ADD 操作
+// 所有校验在写入前完成，失败的对象不会留下任何修改
+// 返回的 effects 为事务提交后需要执行的副作用（订阅注册、通知发送）
+func (uc *ClientUseCase) addObject(fromId string, createObj *api.Add_CreateObject) (*api.AddResp_CreatedObjectResult, []func()) {
+	path := createObj.GetObjPath()
+	tablePath, uspErr := uc.validateAddTarget(path)
+	if uspErr != nil {
+		logger.Warnf("[USP] ADD object error: path=%s, err=%v", path, uspErr)
+		return utils.CreateAddFailureResult(path, uspErr.Code, uspErr.Message), nil
+	}
 
-		requestPath = append(requestPath, path)
-		affectedPath = append(affectedPath, nodePath)
+	nodePath := uc.getNewInstance(tablePath)
 
-		// 使用辅助函数提取参数设置
-		paramSettings := extractParamSettings(createObj.GetParamSettings())
-		// 将参数设置应用到 repository
-		for setKey, setValue := range paramSettings {
-			uc.DataRepo.SetValue(nodePath, setKey, setValue)
+	var paramErrs []*api.AddResp_ParameterError
+	paramSettings := make(map[string]string)
+	for _, setting := range createObj.GetParamSettings() {
+		if uspErr := uc.validateParamSetting(nodePath, setting.GetParam(), setting.GetValue()); uspErr != nil {
+			if setting.GetRequired() {
+				logger.Warnf("[USP] ADD required parameter failed: path=%s, err=%v", path, uspErr)
+				return utils.CreateAddFailureResult(path, model.ErrCodeRequiredParamFailed, uspErr.Message), nil
+			}
+			paramErrs = append(paramErrs, &api.AddResp_ParameterError{
+				Param:   setting.GetParam(),
+				ErrCode: uspErr.Code,
+				ErrMsg:  uspErr.Message,
+			})
+			continue
 		}
-		updatedParams = append(updatedParams, paramSettings)
+		paramSettings[setting.GetParam()] = setting.GetValue()
+	}
 
-		// 处理对象创建后的副作用（订阅注册、通知发送）
-		uc.handleObjectCreationSideEffects(path, paramSettings)
+	// 订阅节点需要在创建前校验订阅参数，未指定 ID 时自动生成，ID 不能与已有订阅重复
+	if uc.isSubscriptionPath(tablePath) {
+		if paramSettings["ID"] == "" {
+			paramSettings["ID"] = uc.generateSubscriptionId()
+		}
+		err := uc.validateSubscriptionId(nodePath, paramSettings["ID"])
+		if err == nil {
+			_, err = uc.extractSubscriptionParams(paramSettings)
+		}
+		if err != nil {
+			logger.Warnf("[USP] ADD subscription validation error: path=%s, err=%v", path, err)
+			return utils.CreateAddFailureResult(path, model.ErrCodeObjectNotCreated, model.NewUSPError(model.ErrCodeObjectNotCreated, "%v", err).Message), nil
+		}
 	}
 
-	msg := utils.CreateAddResponseMessage(msgId, requestPath, affectedPath, updatedParams)
-	logger.Infof("[USP] send ADD response: %s", msg.String())
+	required := make(map[string]bool)
+	for _, setting := range createObj.GetParamSettings() {
+		required[setting.GetParam()] = required[setting.GetParam()] || setting.GetRequired()
+	}
 
-	err := uc.HandleMTPMsgTransmit(msg)
-	if err != nil {
-		logger.Warnf("[USP] ADD error: msgId=%s, err=%v", msgId, err)
+	// 将参数设置应用到 repository
+	// 写入失败的参数记录在 ParamErrs 中；必需参数写入失败时撤销实例创建并返回 OperFailure
+	savepoint := uc.DataRepo.Savepoint()
+	uc.DataRepo.CreateObject(nodePath)
+	for setKey, setValue := range paramSettings {
+		if _, _, err := uc.DataRepo.SetValue(nodePath, setKey, setValue); err != nil {
+			logger.Warnf("[USP] ADD parameter write failed: path=%s%s, err=%v", nodePath, setKey, err)
+			uspErr := model.NewUSPError(model.ErrCodeParamActionFailed, "%v", err)
+			if required[setKey] {
+				uc.DataRepo.RollbackToSavepoint(savepoint)
+				return utils.CreateAddFailureResult(path, model.ErrCodeRequiredParamFailed, uspErr.Message), nil
+			}
+			delete(paramSettings, setKey)
+			paramErrs = append(paramErrs, &api.AddResp_ParameterError{
+				Param:   setKey,
+				ErrCode: uspErr.Code,
+				ErrMsg:  uspErr.Message,
+			})
+		}
+	}
+	// 订阅记录创建时间，TimeToLive 从创建时间开始计算，重启后按持久化的时间恢复过期定时器；
+	// Recipient 为创建订阅的 controller，通知发送给该 controller，不在 Controller 表中时发送给配置文件中的 controller
+	if uc.isSubscriptionPath(tablePath) {
+		uc.DataRepo.SetValue(nodePath, "CreationDate", time.Now().UTC().Format(time.RFC3339))
+		uc.DataRepo.SetValue(nodePath, "Recipient", strings.TrimSuffix(uc.controllerInstance(fromId), "."))
+	}
+
+	effects := []func(){func() { uc.handleObjectCreationSideEffects(tablePath, nodePath, paramSettings) }}
+	return utils.CreateAddSuccessResult(path, nodePath, uc.instanceUniqueKeys(nodePath), paramErrs), effects
+}
+
+// addFailures 汇总 ADD 结果中的失败对象，用于 allow_partial=false 时构建 Error 消息
+// 返回第一个失败对象的错误，没有失败时返回 nil
+func addFailures(results []*api.AddResp_CreatedObjectResult) (*model.USPError, []*api.Error_ParamError) {
+	var first *model.USPError
+	var paramErrs []*api.Error_ParamError
+	for _, result := range results {
+		failure := result.GetOperStatus().GetOperFailure()
+		if failure == nil {
+			continue
+		}
+		if first == nil {
+			first = &model.USPError{Code: failure.GetErrCode(), Message: failure.GetErrMsg()}
+		}
+		paramErrs = append(paramErrs, &api.Error_ParamError{
+			ParamPath: result.GetRequestedPath(),
+			ErrCode:   failure.GetErrCode(),
+			ErrMsg:    failure.GetErrMsg(),
+		})
+	}
+	return first, paramErrs
+}
+
+// runEffects 依次执行事务提交后的副作用
+func runEffects(effects []func()) {
+	for _, effect := range effects {
+		effect()
 	}
 }
 
 // handleObjectCreationSideEffects 处理对象创建后的副作用
 // 包括：订阅注册（如果是订阅节点）、发送对象创建通知
-func (uc *ClientUseCase) handleObjectCreationSideEffects(path string, paramSettings map[string]string) {
+func (uc *ClientUseCase) handleObjectCreationSideEffects(path string, nodePath string, paramSettings map[string]string) {
 	// 尝试注册订阅（如果是订阅节点）
-	if err := uc.HandleAddLocalAgentSubscription(path, paramSettings); err != nil {
+	if err := uc.HandleAddLocalAgentSubscription(nodePath); err != nil {
 		logger.Warnf("[USP] ADD subscription registration error: path=%s, err=%v", path, err)
 		return
 	}
 
+	// 新增 Controller 或其 MTP 时建立连接并调度 Periodic!
+	if strings.HasPrefix(path, model.PathController) {
+		uc.controllersChanged()
+	}
+
 	// 发送对象创建通知
 	uc.notifyObjectCreation(path, paramSettings)
 }
 
 // HandleDeleteRequest handles incoming DELETE requests
-func (uc *ClientUseCase) HandleDeleteRequest(inComingMsg *api.Msg) {
+func (uc *ClientUseCase) HandleDeleteRequest(fromId string, inComingMsg *api.Msg) {
 	// 防御性检查
 	if inComingMsg == nil || inComingMsg.Header == nil {
 		logger.Warnf("[USP] HandleDeleteRequest received invalid message")
@@ -146,33 +462,59 @@ func (uc *ClientUseCase) HandleDeleteRequest(inComingMsg *api.Msg) {
 	logger.Infof("[USP] receive DELETE request: %s", inComingMsg.String())
 
 	objPaths := inComingMsg.GetBody().GetRequest().GetDelete().GetObjPaths()
-	var affectedPath []string
-	var requestPath []string
 
+	var results []*api.DeleteResp_DeletedObjectResult
 	for _, objPath := range objPaths {
+		results = append(results, uc.deleteObject(objPath))
+	}
+	// 删除通知按删除前解析的路径发送后，再重新解析订阅
+	uc.DataRepo.FlushDataChanges()
+
+	msg := utils.CreateDeleteResponseMessage(msgId, results)
+	logger.Infof("[USP] send DELETE response: %s", msg.String())
+
+	err := uc.HandleMTPMsgTransmitTo(fromId, msg)
+	if err != nil {
+		logger.Warnf("[USP] DELETE error: msgId=%s, err=%v", msgId, err)
+	}
+}
+
+// deleteObject 处理单个对象的 DELETE 操作，路径可通过通配符或搜索表达式匹配多个实例
+// 删除不存在的实例视为成功，affected_paths 为空
+func (uc *ClientUseCase) deleteObject(objPath string) *api.DeleteResp_DeletedObjectResult {
+	if uspErr := uc.validateDeleteTarget(objPath); uspErr != nil {
+		logger.Warnf("[USP] DELETE object error: path=%s, err=%v", objPath, uspErr)
+		return utils.CreateDeleteFailureResult(objPath, uspErr.Code, uspErr.Message)
+	}
+
+	nodePaths, err := uc.resolveObjectPaths(objPath)
+	if errors.Is(err, trtree.ErrInvalidPathSyntax) {
+		uspErr := pathSyntaxError(err)
+		return utils.CreateDeleteFailureResult(objPath, uspErr.Code, uspErr.Message)
+	}
+
+	affectedPaths := []string{}
+	for _, nodePath := range nodePaths {
 		// 处理对象删除前的副作用（取消订阅）
-		uc.handleObjectDeletionPreEffects(objPath)
+		uc.handleObjectDeletionPreEffects(nodePath)
 
 		// 执行删除操作
-		requestPath = append(requestPath, objPath)
-		nodePath, isFound := uc.DataRepo.DeleteNode(objPath)
-		if isFound {
-			affectedPath = append(affectedPath, nodePath)
-		} else {
-			affectedPath = append(affectedPath, objPath)
+		deletedPath, isFound := uc.DataRepo.DeleteNode(nodePath)
+		if !isFound {
+			continue
 		}
+		affectedPaths = append(affectedPaths, deletedPath)
 
 		// 处理对象删除后的副作用（发送通知）
-		uc.notifyObjectDeletion(objPath)
+		uc.notifyObjectDeletion(deletedPath)
 	}
 
-	msg := utils.CreateDeleteResponseMessage(msgId, requestPath, affectedPath)
-	logger.Infof("[USP] send DELETE response: %s", msg.String())
-
-	err := uc.HandleMTPMsgTransmit(msg)
-	if err != nil {
-		logger.Warnf("[USP] DELETE error: msgId=%s, err=%v", msgId, err)
+	// 删除 Controller 或其 MTP 时断开连接并停止 Periodic!
+	if len(affectedPaths) > 0 && strings.HasPrefix(objPath, model.PathController) {
+		uc.controllersChanged()
 	}
+
+	return utils.CreateDeleteSuccessResult(objPath, affectedPaths, nil)
 }
 
 // handleObjectDeletionPreEffects 处理对象删除前的副作用
@@ -185,7 +527,7 @@ func (uc *ClientUseCase) handleObjectDeletionPreEffects(objPath string) {
 }
 
 // HandleOperateRequest handles incoming OPERATE requests
-func (uc *ClientUseCase) HandleOperateRequest(inComingMsg *api.Msg) {
+func (uc *ClientUseCase) HandleOperateRequest(fromId string, inComingMsg *api.Msg) {
 	// 防御性检查
 	if inComingMsg == nil || inComingMsg.Header == nil {
 		logger.Warnf("[USP] HandleOperateRequest received invalid message")
@@ -194,7 +536,7 @@ func (uc *ClientUseCase) HandleOperateRequest(inComingMsg *api.Msg) {
 	logger.Infof("[USP] receive OPERATE request: %s", inComingMsg.String())
 
 	// 根据command名称决定调用operComplete还是event
-	uc.HandleCommand(inComingMsg.GetBody().GetRequest().GetOperate(), inComingMsg.Header.MsgId)
+	uc.HandleCommand(fromId, inComingMsg.GetBody().GetRequest().GetOperate(), inComingMsg.Header.MsgId)
 }
 
 // HandleNotifyResp handles incoming NOTIFY_RESP messages
@@ -206,4 +548,15 @@ func (uc *ClientUseCase) HandleNotifyResp(inComingMsg *api.Msg) {
 	}
 
 	logger.Infof("[USP] receive NOTIFY_RESP: %s", inComingMsg.String())
+
+	// 停止对应通知的重试
+	msgId := inComingMsg.Header.MsgId
+	pending := uc.completeNotification(msgId)
+	if pending == nil {
+		return
+	}
+	uc.completeBoot(pending.toId, msgId)
+	if subscriptionId := inComingMsg.GetBody().GetResponse().GetNotifyResp().GetSubscriptionId(); subscriptionId != pending.subscriptionId {
+		logger.Warnf("[USP] NOTIFY_RESP subscription mismatch: msgId=%s, expected=%s, got=%s", msgId, pending.subscriptionId, subscriptionId)
+	}
 }
@@ -1,24 +1,61 @@
 package usecase
 
 import (
+	"tr369-wss-client/client/model"
 	logger "tr369-wss-client/log"
 	"tr369-wss-client/pkg/api"
 	"tr369-wss-client/utils"
 )
 
+// controllerEvent 只发送给指定 controller 的事件，如每个 controller 各自调度的 Periodic!
+// 和 ParameterMap 按 controller 构建的 Boot!；sent 不为空时在 Notify 发送后调用，如等待 controller 确认的 Boot!
+type controllerEvent struct {
+	endpointId string
+	event      *api.Notify_Event_
+	sent       func(msgId string, awaitResp bool)
+}
+
 // sendNotification 通用通知发送函数
-// 接收 subscriptionId、notification 和 notifyType 参数，统一构建 Notify 消息并发送
-// notification 参数必须是实现了 isNotify_Notification 接口的类型
-func (uc *ClientUseCase) sendNotification(subscriptionId string, notify *api.Notify, notifyType string) {
+// 接收 subscriptionId、notification 和 notifyType 参数，统一构建 Notify 消息并发送给订阅的 Recipient；
+// endpointId 不为空时只发送给 Recipient 为该 controller 的订阅，接收方不存在或已禁用时不发送
+// 订阅的 NotifRetry 为 true 时要求 controller 回复 NOTIFY_RESP，未收到响应时按退避策略重试直到 NotifExpiration；
+// 返回发送的 Notify 的 msg_id 和是否要求响应，没有发送时 msg_id 为空
+func (uc *ClientUseCase) sendNotification(subscriptionId string, notify *api.Notify, notifyType string, endpointId string) (string, bool) {
+	// 监听器在独立的 goroutine 中执行，读取订阅参数需要加锁
+	uc.DataRepo.Lock()
+	subscription := uc.subscriptionInstance(subscriptionId)
+	toId := ""
+	if subscription != "" {
+		toId = uc.subscriptionRecipient(subscription)
+	}
+	policy := uc.notifRetryPolicy(subscription, toId)
+	uc.DataRepo.Unlock()
+
+	if endpointId != "" && endpointId != toId {
+		return "", false
+	}
+	if toId == "" {
+		logger.Debugf("[USP] %s notify skipped, no enabled recipient: subscriptionId=%s", notifyType, subscriptionId)
+		return "", false
+	}
+
+	notify.SendResp = policy.retry
 	msg := utils.CreateNotifyMessage(notify)
-
-	// 发送消息，通过 channel
-	if err := uc.HandleMTPMsgTransmit(msg); err != nil {
-		logger.Warnf("[USP] %s notify error: subscriptionId=%s, err=%v", notifyType, subscriptionId, err)
-		return
+	if policy.retry {
+		uc.trackNotification(toId, msg, subscriptionId, notifyType, policy)
 	}
 
-	logger.Infof("[USP] send %s notify: %s", notifyType, msg)
+	// 发送到接收方 controller 的发送队列
+	if err := uc.HandleMTPMsgTransmitTo(toId, msg); err != nil {
+		logger.Warnf("[USP] %s notify error: subscriptionId=%s, err=%v", notifyType, subscriptionId, err)
+		// 需要重试的通知已在等待 NOTIFY_RESP，之后按退避策略重发
+		if !policy.retry {
+			return "", false
+		}
+	} else {
+		logger.Infof("[USP] send %s notify: %s", notifyType, msg)
+	}
+	return msg.GetHeader().GetMsgId(), policy.retry
 }
 
 // HandleValueChange 处理 value change 事件
@@ -31,10 +68,9 @@ func (uc *ClientUseCase) HandleValueChange(subscriptionId string, change interfa
 
 	notify := &api.Notify{
 		SubscriptionId: subscriptionId,
-		SendResp:       true,
 		Notification:   valueChange,
 	}
-	uc.sendNotification(subscriptionId, notify, "VALUE_CHANGE")
+	uc.sendNotification(subscriptionId, notify, "VALUE_CHANGE", "")
 }
 
 // HandleObjectCreation 处理 object creation 事件
@@ -47,10 +83,9 @@ func (uc *ClientUseCase) HandleObjectCreation(subscriptionId string, change inte
 
 	notify := &api.Notify{
 		SubscriptionId: subscriptionId,
-		SendResp:       true,
 		Notification:   objCreation,
 	}
-	uc.sendNotification(subscriptionId, notify, "OBJ_CREATION")
+	uc.sendNotification(subscriptionId, notify, "OBJ_CREATION", "")
 }
 
 // HandleObjectDeletion 处理 object deletion 事件
@@ -63,10 +98,9 @@ func (uc *ClientUseCase) HandleObjectDeletion(subscriptionId string, change inte
 
 	notify := &api.Notify{
 		SubscriptionId: subscriptionId,
-		SendResp:       true,
 		Notification:   objDeletion,
 	}
-	uc.sendNotification(subscriptionId, notify, "OBJ_DELETION")
+	uc.sendNotification(subscriptionId, notify, "OBJ_DELETION", "")
 }
 
 // HandleOperateComplete 处理 operate complete 事件
@@ -79,14 +113,19 @@ func (uc *ClientUseCase) HandleOperateComplete(subscriptionId string, change int
 
 	notify := &api.Notify{
 		SubscriptionId: subscriptionId,
-		SendResp:       true,
 		Notification:   operComplete,
 	}
-	uc.sendNotification(subscriptionId, notify, "OPER_COMPLETE")
+	uc.sendNotification(subscriptionId, notify, "OPER_COMPLETE", "")
 }
 
 // HandleEvent 处理 event 事件
+// 指定 controller 的事件只发送给 Recipient 为该 controller 的订阅，需要发送结果的事件发送后回调 sent
 func (uc *ClientUseCase) HandleEvent(subscriptionId string, change interface{}) {
+	endpointId := ""
+	targeted, isTargeted := change.(*controllerEvent)
+	if isTargeted {
+		endpointId, change = targeted.endpointId, targeted.event
+	}
 	event, ok := change.(*api.Notify_Event_)
 	if !ok {
 		logger.Warnf("[USP] EVENT type assertion error: subscriptionId=%s, err=expected Notify_Event_, got %T", subscriptionId, change)
@@ -95,10 +134,12 @@ func (uc *ClientUseCase) HandleEvent(subscriptionId string, change interface{})
 
 	notify := &api.Notify{
 		SubscriptionId: subscriptionId,
-		SendResp:       true,
 		Notification:   event,
 	}
-	uc.sendNotification(subscriptionId, notify, "EVENT")
+	msgId, awaitResp := uc.sendNotification(subscriptionId, notify, "EVENT", endpointId)
+	if isTargeted && targeted.sent != nil && msgId != "" {
+		targeted.sent(msgId, awaitResp)
+	}
 }
 
 // notifyValueChange 发送值变化通知
@@ -133,27 +174,34 @@ func (uc *ClientUseCase) notifyObjectDeletion(objPath string) {
 	uc.ListenerMgr.NotifyListeners(objPath, notifyDeleteObj)
 }
 
-// notifyOperComplete 发送对象删除通知
-// 如果是失败的情况，需要别的结构体
-// 不同的的操作，返回的结果也是不同的
-func (uc *ClientUseCase) notifyOperComplete(objPath, commandKey, commandName string, outputArgs map[string]string) {
-	notifyOperComplete := &api.Notify_OperComplete{
-		OperComplete: &api.Notify_OperationComplete{
-			ObjPath:     objPath,
-			CommandKey:  commandKey,
-			CommandName: commandName,
-			OperationResp: &api.Notify_OperationComplete_ReqOutputArgs{
-				ReqOutputArgs: &api.Notify_OperationComplete_OutputArgs{
-					OutputArgs: outputArgs,
-				},
+// notifyOperComplete 发送命令执行完成通知
+// uspErr 不为空时发送失败结果，否则发送命令的输出参数
+// 监听器按命令的完整路径匹配，如 "Device.Reboot()"
+func (uc *ClientUseCase) notifyOperComplete(objPath, commandKey, commandName string, outputArgs map[string]string, uspErr *model.USPError) {
+	operComplete := &api.Notify_OperationComplete{
+		ObjPath:     objPath,
+		CommandKey:  commandKey,
+		CommandName: commandName,
+	}
+	if uspErr != nil {
+		operComplete.OperationResp = &api.Notify_OperationComplete_CmdFailure{
+			CmdFailure: &api.Notify_OperationComplete_CommandFailure{
+				ErrCode: uspErr.Code,
+				ErrMsg:  uspErr.Message,
 			},
-		},
+		}
+	} else {
+		operComplete.OperationResp = &api.Notify_OperationComplete_ReqOutputArgs{
+			ReqOutputArgs: &api.Notify_OperationComplete_OutputArgs{
+				OutputArgs: outputArgs,
+			},
+		}
 	}
-	uc.ListenerMgr.NotifyListeners(objPath, notifyOperComplete)
+	uc.ListenerMgr.NotifyListeners(objPath+commandName, &api.Notify_OperComplete{OperComplete: operComplete})
 }
 
-// notifyEvent 发送对象删除通知
-// 不同的event 返回的结果也是不同的
+// notifyEvent 发送事件通知
+// 不同的event 返回的结果也是不同的；监听器按事件的完整路径匹配，如 "Device.Boot!"
 func (uc *ClientUseCase) notifyEvent(objPath, eventName string, params map[string]string) {
 	event := &api.Notify_Event_{
 		Event: &api.Notify_Event{
@@ -162,5 +210,21 @@ func (uc *ClientUseCase) notifyEvent(objPath, eventName string, params map[strin
 			Params:    params,
 		},
 	}
-	uc.ListenerMgr.NotifyListeners(objPath, event)
+	uc.ListenerMgr.NotifyListeners(objPath+eventName, event)
+}
+
+// notifyControllerEvent 发送只通知指定 controller 的事件，sent 不为空时在 Notify 发送后调用
+func (uc *ClientUseCase) notifyControllerEvent(endpointId, objPath, eventName string, params map[string]string, sent func(msgId string, awaitResp bool)) {
+	event := &controllerEvent{
+		endpointId: endpointId,
+		sent:       sent,
+		event: &api.Notify_Event_{
+			Event: &api.Notify_Event{
+				ObjPath:   objPath,
+				EventName: eventName,
+				Params:    params,
+			},
+		},
+	}
+	uc.ListenerMgr.NotifyListeners(objPath+eventName, event)
 }
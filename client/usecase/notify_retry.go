@@ -0,0 +1,183 @@
+package usecase
+
+import (
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"time"
+
+	"tr369-wss-client/client/model"
+	logger "tr369-wss-client/log"
+	"tr369-wss-client/pkg/api"
+	"tr369-wss-client/trtree"
+	"tr369-wss-client/utils"
+)
+
+const (
+	// defaultNotifRetryMinWait USPNotifRetryMinimumWaitInterval 的默认值（秒）
+	defaultNotifRetryMinWait = 5
+	// defaultNotifRetryMultiplier USPNotifRetryIntervalMultiplier 的默认值（千分之一）
+	defaultNotifRetryMultiplier = 2000
+	// maxNotifRetryExponent 重试间隔指数的上限，超过后重试间隔不再增长
+	maxNotifRetryExponent = 10
+)
+
+// notifRetryPolicy 订阅的通知重试策略
+type notifRetryPolicy struct {
+	retry      bool          // Subscription.NotifRetry
+	expiration time.Duration // Subscription.NotifExpiration，0 表示不过期
+	minWait    time.Duration // Controller.USPNotifRetryMinimumWaitInterval
+	multiplier float64       // Controller.USPNotifRetryIntervalMultiplier / 1000
+}
+
+// pendingNotif 等待 NOTIFY_RESP 的通知
+type pendingNotif struct {
+	toId           string // 接收方 controller 的 EndpointID
+	msg            *api.Msg
+	subscriptionId string
+	notifyType     string
+	policy         *notifRetryPolicy
+	deadline       time.Time // 过期时间，零值表示不过期
+	retries        int       // 已重试次数
+	timer          *time.Timer
+}
+
+// notifRetryPolicy 读取订阅和接收方 controller 的重试参数（调用方需持有数据锁）
+// subscription 为订阅实例路径，toId 为接收方 controller 的 EndpointID；找不到订阅实例时不重试
+func (uc *ClientUseCase) notifRetryPolicy(subscription string, toId string) *notifRetryPolicy {
+	policy := &notifRetryPolicy{
+		minWait:    defaultNotifRetryMinWait * time.Second,
+		multiplier: defaultNotifRetryMultiplier / 1000.0,
+	}
+
+	if subscription == "" {
+		return policy
+	}
+	policy.retry = utils.IsTrue(uc.getStringValue(subscription + "NotifRetry"))
+	if expiration, err := strconv.ParseUint(uc.getStringValue(subscription+"NotifExpiration"), 10, 32); err == nil {
+		policy.expiration = time.Duration(expiration) * time.Second
+	}
+
+	controller := uc.controllerSettings(toId)
+	if controller == "" {
+		return policy
+	}
+	if minWait, err := strconv.ParseUint(uc.getStringValue(controller+"USPNotifRetryMinimumWaitInterval"), 10, 16); err == nil && minWait > 0 {
+		policy.minWait = time.Duration(minWait) * time.Second
+	}
+	if multiplier, err := strconv.ParseUint(uc.getStringValue(controller+"USPNotifRetryIntervalMultiplier"), 10, 16); err == nil && multiplier >= 1000 {
+		policy.multiplier = float64(multiplier) / 1000
+	}
+	return policy
+}
+
+// subscriptionInstance 根据 ID 查找订阅实例路径（调用方需持有数据锁）
+func (uc *ClientUseCase) subscriptionInstance(subscriptionId string) string {
+	subscriptions, _ := trtree.ResolveObjectPath(uc.DataRepo.GetParameters(), model.PathSubscription+"*.")
+	for _, subscription := range subscriptions {
+		if uc.getStringValue(subscription+"ID") == subscriptionId {
+			return subscription
+		}
+	}
+	return ""
+}
+
+// trackNotification 记录需要重试的通知，直到收到 NOTIFY_RESP、Error 或过期
+func (uc *ClientUseCase) trackNotification(toId string, msg *api.Msg, subscriptionId string, notifyType string, policy *notifRetryPolicy) {
+	pending := &pendingNotif{
+		toId:           toId,
+		msg:            msg,
+		subscriptionId: subscriptionId,
+		notifyType:     notifyType,
+		policy:         policy,
+	}
+	if policy.expiration > 0 {
+		pending.deadline = time.Now().Add(policy.expiration)
+	}
+
+	uc.notifMu.Lock()
+	defer uc.notifMu.Unlock()
+	uc.pendingNotifs[msg.GetHeader().GetMsgId()] = pending
+	uc.scheduleNotifRetry(pending)
+}
+
+// retryWaitRange 返回第 retry 次重试的等待时间范围 [m*k^(n-1), m*k^n]，m 为最小等待间隔，k 为间隔倍数，
+// n 超过 maxNotifRetryExponent 后不再增长
+func (p *notifRetryPolicy) retryWaitRange(retry int) (time.Duration, time.Duration) {
+	exponent := float64(min(retry, maxNotifRetryExponent))
+	minWait := float64(p.minWait) * math.Pow(p.multiplier, exponent-1)
+	maxWait := float64(p.minWait) * math.Pow(p.multiplier, exponent)
+	return time.Duration(minWait), time.Duration(maxWait)
+}
+
+// scheduleNotifRetry 按 TR-369 的指数退避调度下一次重试（调用方需持有 notifMu）
+// 等待时间在 retryWaitRange 的范围内随机选取
+func (uc *ClientUseCase) scheduleNotifRetry(pending *pendingNotif) {
+	minWait, maxWait := pending.policy.retryWaitRange(pending.retries + 1)
+	wait := minWait + time.Duration(rand.Int64N(int64(maxWait-minWait)+1))
+
+	// 通知在下次重试前过期时，在过期时刻丢弃
+	if !pending.deadline.IsZero() {
+		wait = min(wait, time.Until(pending.deadline))
+	}
+
+	msgId := pending.msg.GetHeader().GetMsgId()
+	pending.timer = time.AfterFunc(wait, func() { uc.retryNotification(msgId, pending) })
+}
+
+// retryNotification 重发未收到响应的通知，msg_id 保持不变，接收方 controller 被禁用后不再重试
+// 发送时不持有 notifMu，避免消息通道阻塞时影响 NOTIFY_RESP 的处理
+func (uc *ClientUseCase) retryNotification(msgId string, pending *pendingNotif) {
+	uc.DataRepo.Lock()
+	enabled := uc.controllerEnabled(pending.toId)
+	uc.DataRepo.Unlock()
+
+	uc.notifMu.Lock()
+	if uc.pendingNotifs[msgId] != pending {
+		uc.notifMu.Unlock()
+		return
+	}
+	if uc.ctx.Err() != nil || (!pending.deadline.IsZero() && !time.Now().Before(pending.deadline)) {
+		delete(uc.pendingNotifs, msgId)
+		uc.notifMu.Unlock()
+		logger.Warnf("[USP] %s notify expired: subscriptionId=%s, msgId=%s, retries=%d",
+			pending.notifyType, pending.subscriptionId, msgId, pending.retries)
+		return
+	}
+	if !enabled {
+		delete(uc.pendingNotifs, msgId)
+		uc.notifMu.Unlock()
+		logger.Warnf("[USP] %s notify dropped, controller disabled: subscriptionId=%s, msgId=%s, controller=%s",
+			pending.notifyType, pending.subscriptionId, msgId, pending.toId)
+		return
+	}
+	pending.retries++
+	uc.notifMu.Unlock()
+
+	logger.Infof("[USP] retry %s notify: subscriptionId=%s, msgId=%s, retry=%d",
+		pending.notifyType, pending.subscriptionId, msgId, pending.retries)
+	if err := uc.HandleMTPMsgTransmitTo(pending.toId, pending.msg); err != nil {
+		logger.Warnf("[USP] %s notify retry error: subscriptionId=%s, msgId=%s, err=%v",
+			pending.notifyType, pending.subscriptionId, msgId, err)
+	}
+
+	uc.notifMu.Lock()
+	defer uc.notifMu.Unlock()
+	if uc.pendingNotifs[msgId] == pending {
+		uc.scheduleNotifRetry(pending)
+	}
+}
+
+// completeNotification 收到 NOTIFY_RESP 或 Error 后停止重试，返回等待中的通知，不存在时返回 nil
+func (uc *ClientUseCase) completeNotification(msgId string) *pendingNotif {
+	uc.notifMu.Lock()
+	defer uc.notifMu.Unlock()
+
+	pending, ok := uc.pendingNotifs[msgId]
+	if !ok {
+		return nil
+	}
+	pending.timer.Stop()
+	delete(uc.pendingNotifs, msgId)
+	return pending
+}
@@ -0,0 +1,233 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"tr369-wss-client/client/model"
+	"tr369-wss-client/pkg/api"
+)
+
+// notifRetryTestData Subscription.1 要求重试，Subscription.2 不要求重试
+const notifRetryTestData = `{
+	"Device": {
+		"DeviceInfo": {"BootCount": "0"},
+		"X_TP_RebootCause": {"Cause": "", "CommandKey": "", "Reason": ""},
+		"LocalAgent": {
+			"Controller": {
+				"1": {"Enable": "true", "EndpointID": "ctrl-1", "USPNotifRetryMinimumWaitInterval": "1", "USPNotifRetryIntervalMultiplier": "1000"}
+			},
+			"Subscription": {
+				"1": {"Enable": "true", "ID": "retry", "NotifType": "Event", "ReferenceList": "Device.Boot!", "Recipient": "Device.LocalAgent.Controller.1", "Persistent": "true", "NotifRetry": "true", "NotifExpiration": "0"},
+				"2": {"Enable": "true", "ID": "no-retry", "NotifType": "Event", "ReferenceList": "Device.Boot!", "Recipient": "Device.LocalAgent.Controller.1", "Persistent": "true", "NotifRetry": "false", "NotifExpiration": "0"}
+			}
+		}
+	}
+}`
+
+// sendTestEvent 以订阅 subscriptionId 发送一个事件通知，返回发出的 Notify
+func sendTestEvent(t *testing.T, uc *ClientUseCase, subscriptionId string) *api.Msg {
+	t.Helper()
+	uc.HandleEvent(subscriptionId, &api.Notify_Event_{Event: &api.Notify_Event{ObjPath: model.PathDevice, EventName: model.BOOT}})
+	sent := drainSentMessages(t, uc)
+	if len(sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(sent))
+	}
+	return sent[0].msg
+}
+
+// pendingNotifCount 返回等待 NOTIFY_RESP 的通知数量
+func pendingNotifCount(uc *ClientUseCase) int {
+	uc.notifMu.Lock()
+	defer uc.notifMu.Unlock()
+	return len(uc.pendingNotifs)
+}
+
+func TestRetryWaitRange(t *testing.T) {
+	tests := []struct {
+		name       string
+		minWait    time.Duration
+		multiplier float64
+		retry      int
+		wantMin    time.Duration
+		wantMax    time.Duration
+	}{
+		{"first retry", 5 * time.Second, 2, 1, 5 * time.Second, 10 * time.Second},
+		{"second retry", 5 * time.Second, 2, 2, 10 * time.Second, 20 * time.Second},
+		{"fractional multiplier", time.Second, 1.5, 3, 2250 * time.Millisecond, 3375 * time.Millisecond},
+		{"multiplier one", 3 * time.Second, 1, 4, 3 * time.Second, 3 * time.Second},
+		{"exponent cap", 5 * time.Second, 2, maxNotifRetryExponent, 2560 * time.Second, 5120 * time.Second},
+		{"beyond exponent cap", 5 * time.Second, 2, maxNotifRetryExponent + 5, 2560 * time.Second, 5120 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := &notifRetryPolicy{minWait: tt.minWait, multiplier: tt.multiplier}
+			gotMin, gotMax := policy.retryWaitRange(tt.retry)
+			if gotMin != tt.wantMin || gotMax != tt.wantMax {
+				t.Errorf("retryWaitRange(%d) = [%s, %s], want [%s, %s]", tt.retry, gotMin, gotMax, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestNotifRetryPolicy(t *testing.T) {
+	tests := []struct {
+		name           string
+		subscriptionId string
+		minWait        string
+		multiplier     string
+		want           notifRetryPolicy
+	}{
+		{"controller settings", "retry", "30", "1500", notifRetryPolicy{retry: true, minWait: 30 * time.Second, multiplier: 1.5}},
+		{"invalid settings use defaults", "retry", "0", "999", notifRetryPolicy{retry: true, minWait: defaultNotifRetryMinWait * time.Second, multiplier: defaultNotifRetryMultiplier / 1000.0}},
+		{"NotifRetry false", "no-retry", "30", "1500", notifRetryPolicy{minWait: 30 * time.Second, multiplier: 1.5}},
+		{"unknown subscription", "missing", "30", "1500", notifRetryPolicy{minWait: defaultNotifRetryMinWait * time.Second, multiplier: defaultNotifRetryMultiplier / 1000.0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, _ := newTestUseCase(t, notifRetryTestData)
+
+			uc.DataRepo.Lock()
+			defer uc.DataRepo.Unlock()
+			uc.DataRepo.SetValue(model.PathController+"1.", "USPNotifRetryMinimumWaitInterval", tt.minWait)
+			uc.DataRepo.SetValue(model.PathController+"1.", "USPNotifRetryIntervalMultiplier", tt.multiplier)
+			if got := uc.notifRetryPolicy(uc.subscriptionInstance(tt.subscriptionId), "ctrl-1"); *got != tt.want {
+				t.Errorf("notifRetryPolicy(%s) = %+v, want %+v", tt.subscriptionId, *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotificationWithoutRetry(t *testing.T) {
+	uc, _ := newTestUseCase(t, notifRetryTestData)
+
+	msg := sendTestEvent(t, uc, "no-retry")
+	if msg.GetBody().GetRequest().GetNotify().GetSendResp() {
+		t.Error("send_resp = true for NotifRetry false")
+	}
+	if n := pendingNotifCount(uc); n != 0 {
+		t.Errorf("%d notifications awaiting NOTIFY_RESP, want 0", n)
+	}
+}
+
+func TestNotificationRetryUntilResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		response func(msgId string) *api.Msg
+	}{
+		{"NOTIFY_RESP", func(msgId string) *api.Msg {
+			return &api.Msg{
+				Header: &api.Header{MsgId: msgId, MsgType: api.Header_NOTIFY_RESP},
+				Body: &api.Body{MsgBody: &api.Body_Response{Response: &api.Response{RespType: &api.Response_NotifyResp{
+					NotifyResp: &api.NotifyResp{SubscriptionId: "retry"},
+				}}}},
+			}
+		}},
+		{"ERROR", func(msgId string) *api.Msg {
+			return &api.Msg{
+				Header: &api.Header{MsgId: msgId, MsgType: api.Header_ERROR},
+				Body:   &api.Body{MsgBody: &api.Body_Error{Error: &api.Error{ErrCode: model.ErrCodeRequestDenied}}},
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, _ := newTestUseCase(t, notifRetryTestData)
+
+			msg := sendTestEvent(t, uc, "retry")
+			msgId := msg.GetHeader().GetMsgId()
+			if !msg.GetBody().GetRequest().GetNotify().GetSendResp() {
+				t.Error("send_resp = false for NotifRetry true")
+			}
+
+			// 最小等待间隔 1s、倍数 1 时每秒重试一次，msg_id 不变
+			time.Sleep(1300 * time.Millisecond)
+			sent := drainSentMessages(t, uc)
+			if len(sent) != 1 || sent[0].msg.GetHeader().GetMsgId() != msgId {
+				t.Fatalf("after 1.3s sent %d messages, want 1 retry of %s", len(sent), msgId)
+			}
+
+			uc.HandleMessage("ctrl-1", tt.response(msgId))
+			if n := pendingNotifCount(uc); n != 0 {
+				t.Fatalf("%d notifications awaiting NOTIFY_RESP after %s, want 0", n, tt.name)
+			}
+			time.Sleep(1200 * time.Millisecond)
+			if sent := drainSentMessages(t, uc); len(sent) != 0 {
+				t.Errorf("sent %d messages after %s, want retries stopped", len(sent), tt.name)
+			}
+		})
+	}
+}
+
+func TestNotificationExpiration(t *testing.T) {
+	uc, _ := newTestUseCase(t, notifRetryTestData)
+
+	uc.DataRepo.Lock()
+	uc.DataRepo.SetValue(model.PathController+"1.", "USPNotifRetryMinimumWaitInterval", "3600")
+	uc.DataRepo.SetValue(model.PathSubscription+"1.", "NotifExpiration", "1")
+	uc.DataRepo.Unlock()
+
+	sendTestEvent(t, uc, "retry")
+	if n := pendingNotifCount(uc); n != 1 {
+		t.Fatalf("%d notifications awaiting NOTIFY_RESP, want 1", n)
+	}
+
+	// 下次重试晚于 NotifExpiration，在过期时刻丢弃且不重发
+	time.Sleep(1300 * time.Millisecond)
+	if n := pendingNotifCount(uc); n != 0 {
+		t.Errorf("%d notifications awaiting NOTIFY_RESP after expiration, want 0", n)
+	}
+	if sent := drainSentMessages(t, uc); len(sent) != 0 {
+		t.Errorf("sent %d messages after expiration, want 0", len(sent))
+	}
+}
+
+func TestBootRetainedUntilNotifyResp(t *testing.T) {
+	uc, lm := newTestUseCase(t, notifRetryTestData)
+	uc.DataRepo.Lock()
+	uc.DataRepo.SetValue(model.PathController+"1.", "USPNotifRetryMinimumWaitInterval", "3600")
+	uc.DataRepo.Unlock()
+
+	if err := uc.PowerOn(false); err != nil {
+		t.Fatalf("PowerOn: %v", err)
+	}
+	uc.OnConnected("ctrl-1")
+	deliverBoot(t, uc, lm, "ctrl-1", "retry")
+	first := drainSentMessages(t, uc)
+	if len(first) != 1 {
+		t.Fatalf("sent %d messages, want the Boot! notify", len(first))
+	}
+
+	// 重连时 Boot! 仍在重试，不重复发送
+	uc.OnConnected("ctrl-1")
+	if boots := lm.eventsNamed(model.BOOT); len(boots) != 1 {
+		t.Fatalf("got %d Boot! events while retrying, want 1", len(boots))
+	}
+
+	// 重试停止（如过期）但未收到 NOTIFY_RESP 时，重连后重新发送 Boot!
+	uc.completeNotification(first[0].msg.GetHeader().GetMsgId())
+	uc.OnConnected("ctrl-1")
+	if boots := lm.eventsNamed(model.BOOT); len(boots) != 2 {
+		t.Fatalf("got %d Boot! events after retry stopped, want 2", len(boots))
+	}
+	deliverBoot(t, uc, lm, "ctrl-1", "retry")
+	second := drainSentMessages(t, uc)
+	if len(second) != 1 {
+		t.Fatalf("sent %d messages, want the Boot! notify", len(second))
+	}
+
+	// 收到 NOTIFY_RESP 后清除 Boot!，重连不再发送
+	uc.HandleMessage("ctrl-1", &api.Msg{
+		Header: &api.Header{MsgId: second[0].msg.GetHeader().GetMsgId(), MsgType: api.Header_NOTIFY_RESP},
+		Body: &api.Body{MsgBody: &api.Body_Response{Response: &api.Response{RespType: &api.Response_NotifyResp{
+			NotifyResp: &api.NotifyResp{SubscriptionId: "retry"},
+		}}}},
+	})
+	uc.OnConnected("ctrl-1")
+	if boots := lm.eventsNamed(model.BOOT); len(boots) != 2 {
+		t.Errorf("got %d Boot! events after NOTIFY_RESP, want 2", len(boots))
+	}
+}
@@ -0,0 +1,319 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+
+	"tr369-wss-client/client/model"
+	logger "tr369-wss-client/log"
+	"tr369-wss-client/pkg/api"
+	"tr369-wss-client/trtree"
+	"tr369-wss-client/utils"
+)
+
+// commandRequest 一次命令调用
+type commandRequest struct {
+	ObjPath     string            // 命令所属对象的实际路径，如 "Device."
+	Name        string            // 命令名，如 "Reboot()"
+	CommandKey  string            // controller 指定的 CommandKey
+	Originator  string            // 发起命令的 controller 的 EndpointID
+	InputArgs   map[string]string // 输入参数
+	RequestPath string            // 异步命令对应的 Device.LocalAgent.Request.{i}. 路径，同步命令为空
+}
+
+// Command 返回命令的完整路径
+func (req *commandRequest) Command() string {
+	return req.ObjPath + req.Name
+}
+
+// commandHandler 命令执行函数，返回命令的输出参数
+// 同步命令在消息处理流程中执行（已持有数据锁）；异步命令在后台 goroutine 中执行，
+// 修改数据前需要调用 DataRepo.Lock()，ctx 在命令被取消时结束
+type commandHandler func(ctx context.Context, req *commandRequest) (map[string]string, *model.USPError)
+
+// commandDef 命令定义
+type commandDef struct {
+	async   bool
+	handler commandHandler
+
+	// validate 在分发前校验输入参数，可选；校验失败时直接回复 Error，不创建 Request 实例
+	validate func(inputArgs map[string]string) *model.USPError
+}
+
+// activeRequest 正在执行的异步命令
+type activeRequest struct {
+	req    *commandRequest
+	cancel context.CancelFunc
+}
+
+// registerCommands 注册 Agent 支持的命令，key 为支持的数据模型中的命令路径
+func (uc *ClientUseCase) registerCommands() {
+	uc.commands = map[string]*commandDef{
+		model.DeviceReboot:            {async: true, handler: uc.rebootCommand, validate: noInputArgs},
+		model.DeviceFactoryReset:      {async: true, handler: uc.factoryResetCommand, validate: noInputArgs},
+		model.LocalAgentRequestCancel: {async: false, handler: uc.cancelRequestCommand},
+		model.FirmwareImageDownload:   {async: true, handler: uc.firmwareDownloadCommand},
+		model.FirmwareImageActivate:   {async: true, handler: uc.firmwareActivateCommand},
+		model.InstallDU:               {async: true, handler: uc.installDUCommand},
+		model.DeploymentUnitUpdate:    {async: true, handler: uc.updateDUCommand},
+		model.DeploymentUnitUninstall: {async: true, handler: uc.uninstallDUCommand},
+		model.ExecutionUnitSetState:   {async: false, handler: uc.setRequestedStateCommand},
+		model.IPPing:                  {async: true, handler: uc.ipPingCommand},
+		model.TraceRoute:              {async: true, handler: uc.traceRouteCommand},
+		model.DownloadDiagnostics:     {async: true, handler: uc.downloadDiagnosticsCommand},
+	}
+}
+
+// HandleCommand 处理 OPERATE 请求中的命令
+// 命令路径支持通配符和搜索表达式，每个匹配的对象返回一个 OperationResult
+func (uc *ClientUseCase) HandleCommand(fromId string, operate *api.Operate, msgId string) {
+	def, reqs, uspErr := uc.resolveCommand(fromId, operate)
+	if uspErr != nil {
+		logger.Warnf("[USP] OPERATE error: msgId=%s, err=%v", msgId, uspErr)
+		msg := utils.CreateErrorMessage(msgId, uspErr.Code, uspErr.Message, nil)
+		if err := uc.HandleMTPMsgTransmitTo(fromId, msg); err != nil {
+			logger.Warnf("[USP] OPERATE error: msgId=%s, err=%v", msgId, err)
+		}
+		return
+	}
+
+	operationResults := []*api.OperateResp_OperationResult{}
+	for _, req := range reqs {
+		operationResults = append(operationResults, uc.executeCommand(def, req))
+	}
+
+	if !operate.GetSendResp() {
+		logger.Infof("[USP] OPERATE send_resp=false, skip response: msgId=%s", msgId)
+		return
+	}
+
+	msg := utils.CreateOperateResponseMessage(msgId, operationResults)
+	logger.Infof("[USP] send OPERATE response: %s", msg.String())
+
+	if err := uc.HandleMTPMsgTransmitTo(fromId, msg); err != nil {
+		logger.Warnf("[USP] OPERATE error: msgId=%s, err=%v", msgId, err)
+	}
+}
+
+// resolveCommand 解析命令路径，返回命令定义和每个匹配对象上的命令调用
+func (uc *ClientUseCase) resolveCommand(fromId string, operate *api.Operate) (*commandDef, []*commandRequest, *model.USPError) {
+	command := operate.GetCommand()
+	index := strings.LastIndex(command, ".")
+	if index < 0 || !strings.HasSuffix(command, "()") {
+		return nil, nil, model.NewUSPError(model.ErrCodeInvalidPathSyntax, "not a command path: %s", command)
+	}
+	objPath, name := command[:index+1], command[index+1:]
+
+	def, ok := uc.commands[trtree.ToSupportedPath(command)]
+	if !ok {
+		return nil, nil, model.NewUSPError(model.ErrCodeInvalidPath, "command not supported: %s", command)
+	}
+	if uspErr := uc.validateCommandArgs(objPath, name, operate.GetInputArgs()); uspErr != nil {
+		return nil, nil, uspErr
+	}
+	if def.validate != nil {
+		if uspErr := def.validate(operate.GetInputArgs()); uspErr != nil {
+			return nil, nil, uspErr
+		}
+	}
+
+	objPaths, uspErr := uc.resolveExistingObjects(objPath)
+	if uspErr != nil {
+		return nil, nil, uspErr
+	}
+
+	reqs := make([]*commandRequest, 0, len(objPaths))
+	for _, path := range objPaths {
+		reqs = append(reqs, &commandRequest{
+			ObjPath:    path,
+			Name:       name,
+			CommandKey: operate.GetCommandKey(),
+			Originator: fromId,
+			InputArgs:  operate.GetInputArgs(),
+		})
+	}
+	return def, reqs, nil
+}
+
+// validateCommandArgs 根据支持的数据模型校验命令输入参数
+// 多实例输入参数（如 TimeWindow.1.Start）按 {i} 形式（TimeWindow.{i}.Start）匹配
+func (uc *ClientUseCase) validateCommandArgs(objPath string, name string, inputArgs map[string]string) *model.USPError {
+	if uc.SupportedDM == nil {
+		return nil
+	}
+	obj, ok := uc.SupportedDM.GetObject(objPath)
+	if !ok {
+		return nil
+	}
+	supported, ok := obj.Commands[name]
+	if !ok {
+		return nil
+	}
+
+	for arg := range inputArgs {
+		found := false
+		for _, supportedArg := range supported.InputArgs {
+			if trtree.ToSupportedPath(arg) == supportedArg {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return model.NewUSPError(model.ErrCodeInvalidCommandArgs, "%s does not accept input argument %s", name, arg)
+		}
+	}
+	return nil
+}
+
+// executeCommand 执行命令
+// 同步命令直接返回输出参数；异步命令创建 Request 实例后在后台执行，返回 Request 路径
+func (uc *ClientUseCase) executeCommand(def *commandDef, req *commandRequest) *api.OperateResp_OperationResult {
+	result := &api.OperateResp_OperationResult{
+		ExecutedCommand: req.Command(),
+	}
+
+	if !def.async {
+		outputArgs, uspErr := def.handler(uc.ctx, req)
+		if uspErr != nil {
+			logger.Warnf("[USP] OPERATE command failed: command=%s, err=%v", req.Command(), uspErr)
+			result.OperationResp = &api.OperateResp_OperationResult_CmdFailure{
+				CmdFailure: &api.OperateResp_OperationResult_CommandFailure{
+					ErrCode: uspErr.Code,
+					ErrMsg:  uspErr.Message,
+				},
+			}
+			return result
+		}
+		result.OperationResp = &api.OperateResp_OperationResult_ReqOutputArgs{
+			ReqOutputArgs: &api.OperateResp_OperationResult_OutputArgs{
+				OutputArgs: outputArgs,
+			},
+		}
+		return result
+	}
+
+	requestPath, err := uc.createRequest(req)
+	if err != nil {
+		logger.Warnf("[USP] OPERATE create request failed: command=%s, err=%v", req.Command(), err)
+		uspErr := model.NewUSPError(model.ErrCodeCommandFailure, "%v", err)
+		result.OperationResp = &api.OperateResp_OperationResult_CmdFailure{
+			CmdFailure: &api.OperateResp_OperationResult_CommandFailure{
+				ErrCode: uspErr.Code,
+				ErrMsg:  uspErr.Message,
+			},
+		}
+		return result
+	}
+	req.RequestPath = requestPath
+	ctx, cancel := context.WithCancel(uc.ctx)
+	uc.requestMu.Lock()
+	uc.activeRequests[req.RequestPath] = &activeRequest{req: req, cancel: cancel}
+	uc.requestMu.Unlock()
+
+	uc.notifyObjectCreation(req.RequestPath, nil)
+	go uc.runAsyncCommand(ctx, def, req)
+
+	result.OperationResp = &api.OperateResp_OperationResult_ReqObjPath{
+		ReqObjPath: req.RequestPath,
+	}
+	return result
+}
+
+// createRequest 为异步命令创建 Device.LocalAgent.Request.{i} 实例
+// 参数写入失败时删除已创建的实例并返回错误
+func (uc *ClientUseCase) createRequest(req *commandRequest) (string, error) {
+	requestPath := uc.getNewInstance(model.PathRequest)
+	uc.DataRepo.CreateObject(requestPath)
+
+	params := []struct{ key, value string }{
+		{"Command", req.Command()},
+		{"CommandKey", req.CommandKey},
+		{"Originator", req.Originator},
+		{"Status", model.RequestStatusActive},
+	}
+	for _, param := range params {
+		if _, _, err := uc.DataRepo.SetValue(requestPath, param.key, param.value); err != nil {
+			uc.DataRepo.DeleteNode(requestPath)
+			return "", err
+		}
+	}
+	return requestPath, nil
+}
+
+// runAsyncCommand 在后台执行异步命令，完成后更新 Request 的最终状态（Success、Error 或 Canceled），
+// 发送 OperationComplete 通知并删除 Request 实例
+func (uc *ClientUseCase) runAsyncCommand(ctx context.Context, def *commandDef, req *commandRequest) {
+	logger.Infof("[USP] OPERATE command started: command=%s, request=%s", req.Command(), req.RequestPath)
+	outputArgs, uspErr := def.handler(ctx, req)
+
+	uc.requestMu.Lock()
+	if active, ok := uc.activeRequests[req.RequestPath]; ok {
+		active.cancel()
+		delete(uc.activeRequests, req.RequestPath)
+	}
+	uc.requestMu.Unlock()
+
+	status := model.RequestStatusSuccess
+	switch {
+	case uspErr != nil && uspErr.Code == model.ErrCodeCommandCanceled:
+		status = model.RequestStatusCanceled
+		logger.Infof("[USP] OPERATE command canceled: command=%s, request=%s", req.Command(), req.RequestPath)
+	case uspErr != nil:
+		status = model.RequestStatusError
+		logger.Warnf("[USP] OPERATE command failed: command=%s, request=%s, err=%v", req.Command(), req.RequestPath, uspErr)
+	default:
+		logger.Infof("[USP] OPERATE command completed: command=%s, request=%s", req.Command(), req.RequestPath)
+	}
+	uc.setRequestStatus(req.RequestPath, status)
+	uc.notifyOperComplete(req.ObjPath, req.CommandKey, req.Name, outputArgs, uspErr)
+
+	uc.DataRepo.Lock()
+	uc.DataRepo.DeleteNode(req.RequestPath)
+	uc.DataRepo.Unlock()
+	uc.notifyObjectDeletion(req.RequestPath)
+}
+
+// cancelRequestCommand Device.LocalAgent.Request.{i}.Cancel() 取消正在执行的异步命令
+func (uc *ClientUseCase) cancelRequestCommand(ctx context.Context, req *commandRequest) (map[string]string, *model.USPError) {
+	uc.requestMu.Lock()
+	active, ok := uc.activeRequests[req.ObjPath]
+	uc.requestMu.Unlock()
+	if !ok {
+		return nil, model.NewUSPError(model.ErrCodeCommandFailure, "request %s is not active", req.ObjPath)
+	}
+
+	if _, _, err := uc.DataRepo.SetValue(req.ObjPath, "Status", model.RequestStatusCanceling); err != nil {
+		return nil, model.NewUSPError(model.ErrCodeCommandFailure, "%v", err)
+	}
+	uc.notifyValueChange(req.ObjPath+"Status", model.RequestStatusCanceling)
+	active.cancel()
+	logger.Infof("[USP] OPERATE cancel requested: request=%s, command=%s", req.ObjPath, active.req.Command())
+	return nil, nil
+}
+
+// setRequestStatus 在后台任务中更新 Request 实例的状态并发送 ValueChange 通知
+func (uc *ClientUseCase) setRequestStatus(requestPath string, status string) {
+	uc.DataRepo.Lock()
+	changed, _, err := uc.DataRepo.SetValue(requestPath, "Status", status)
+	uc.DataRepo.Unlock()
+	if err != nil {
+		logger.Warnf("[USP] OPERATE update request status failed: request=%s, err=%v", requestPath, err)
+		return
+	}
+	if changed {
+		uc.notifyValueChange(requestPath+"Status", status)
+	}
+}
+
+// noInputArgs 校验不接受输入参数的命令
+func noInputArgs(inputArgs map[string]string) *model.USPError {
+	for arg := range inputArgs {
+		return model.NewUSPError(model.ErrCodeInvalidCommandArgs, "unexpected input argument %s", arg)
+	}
+	return nil
+}
+
+// commandCanceled 返回命令被取消的错误
+func commandCanceled(req *commandRequest) *model.USPError {
+	return model.NewUSPError(model.ErrCodeCommandCanceled, "%s", req.Command())
+}
@@ -0,0 +1,136 @@
+package usecase
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"tr369-wss-client/client/model"
+	"tr369-wss-client/pkg/api"
+)
+
+// operateTestData 异步命令测试使用的数据
+const operateTestData = `{
+	"Device": {
+		"DeviceInfo": {"SoftwareVersion": "1.0.0"},
+		"LocalAgent": {"EndpointID": "agent-1", "RequestNumberOfEntries": "0"}
+	}
+}`
+
+// operate 在数据锁内处理 OPERATE 请求（与消息处理流程一致），返回发送的消息
+func operate(t *testing.T, uc *ClientUseCase, command string, inputArgs map[string]string) []sentMessage {
+	t.Helper()
+	uc.DataRepo.Lock()
+	uc.HandleCommand("ctrl-1", &api.Operate{Command: command, CommandKey: "key-1", SendResp: true, InputArgs: inputArgs}, "op-"+command)
+	uc.DataRepo.Unlock()
+	return drainSentMessages(t, uc)
+}
+
+// waitRequestRemoved 等待异步命令结束后 Request 实例被删除
+func waitRequestRemoved(t *testing.T, uc *ClientUseCase, requestPath string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		uc.DataRepo.Lock()
+		_, err := uc.DataRepo.GetValue(requestPath + "Status")
+		uc.DataRepo.Unlock()
+		if err != nil {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("request %s not removed", requestPath)
+}
+
+func TestAsyncCommandValidate(t *testing.T) {
+	uc, _ := newTestUseCase(t, operateTestData)
+	// 不依赖支持的数据模型，由命令自身的 validate 校验输入参数
+	uc.SupportedDM = nil
+
+	sent := operate(t, uc, "Device.Reboot()", map[string]string{"Delay": "5"})
+	if len(sent) != 1 || sent[0].msg.GetBody().GetError().GetErrCode() != model.ErrCodeInvalidCommandArgs {
+		t.Fatalf("OPERATE Reboot() with input args sent %v, want Error %d", sent, model.ErrCodeInvalidCommandArgs)
+	}
+	if _, err := uc.DataRepo.GetValue(model.PathRequest + "1.Status"); err == nil {
+		t.Errorf("Request instance created for rejected command")
+	}
+}
+
+func TestAsyncCommandStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		handler  commandHandler
+		cancel   bool
+		statuses []string
+		errCode  uint32
+	}{
+		{
+			name: "success",
+			handler: func(ctx context.Context, req *commandRequest) (map[string]string, *model.USPError) {
+				return map[string]string{"Result": "ok"}, nil
+			},
+			statuses: []string{model.RequestStatusSuccess},
+		},
+		{
+			name: "error",
+			handler: func(ctx context.Context, req *commandRequest) (map[string]string, *model.USPError) {
+				return nil, model.NewUSPError(model.ErrCodeCommandFailure, "failed")
+			},
+			statuses: []string{model.RequestStatusError},
+			errCode:  model.ErrCodeCommandFailure,
+		},
+		{
+			name: "canceled",
+			handler: func(ctx context.Context, req *commandRequest) (map[string]string, *model.USPError) {
+				<-ctx.Done()
+				return nil, commandCanceled(req)
+			},
+			cancel:   true,
+			statuses: []string{model.RequestStatusCanceling, model.RequestStatusCanceled},
+			errCode:  model.ErrCodeCommandCanceled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, lm := newTestUseCase(t, operateTestData)
+			started := make(chan struct{})
+			handler := tt.handler
+			uc.commands["Device.X_Test()"] = &commandDef{async: true, handler: func(ctx context.Context, req *commandRequest) (map[string]string, *model.USPError) {
+				close(started)
+				return handler(ctx, req)
+			}}
+
+			sent := operate(t, uc, "Device.X_Test()", nil)
+			if len(sent) != 1 {
+				t.Fatalf("OPERATE sent %d messages, want 1", len(sent))
+			}
+			results := sent[0].msg.GetBody().GetResponse().GetOperateResp().GetOperationResults()
+			if len(results) != 1 || results[0].GetReqObjPath() == "" {
+				t.Fatalf("OPERATE results = %v, want a Request path", results)
+			}
+			requestPath := results[0].GetReqObjPath()
+
+			<-started
+			if tt.cancel {
+				sent := operate(t, uc, requestPath+"Cancel()", nil)
+				if len(sent) != 1 || sent[0].msg.GetBody().GetResponse().GetOperateResp().GetOperationResults()[0].GetCmdFailure() != nil {
+					t.Fatalf("Cancel() response = %v, want success", sent)
+				}
+			}
+			waitRequestRemoved(t, uc, requestPath)
+
+			if got := lm.valueChanges(requestPath + "Status"); !reflect.DeepEqual(got, tt.statuses) {
+				t.Errorf("Status changes = %v, want %v", got, tt.statuses)
+			}
+			completes := lm.operCompletes()
+			if len(completes) != 1 {
+				t.Fatalf("sent %d OperationComplete, want 1", len(completes))
+			}
+			if got := completes[0].GetCmdFailure().GetErrCode(); got != tt.errCode {
+				t.Errorf("OperationComplete error code = %d, want %d", got, tt.errCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package usecase
+
+import (
+	"strconv"
+	"time"
+
+	"tr369-wss-client/client/model"
+	logger "tr369-wss-client/log"
+)
+
+// periodicTimer 一个 controller 的 Periodic! 定时器
+type periodicTimer struct {
+	interval  time.Duration
+	reference time.Time // PeriodicNotifTime，未知时为启动时间
+	timer     *time.Timer
+}
+
+// reschedulePeriodicNotifs 根据 Device.LocalAgent.Controller.{i} 的 PeriodicNotifInterval 和 PeriodicNotifTime
+// 为每个连接的 controller 重新调度 Periodic! 事件（调用方需持有数据锁）；调度参数未变化的定时器保持不变，不会因无关的修改推迟触发
+func (uc *ClientUseCase) reschedulePeriodicNotifs() {
+	schedules := make(map[string]*periodicTimer)
+	for _, endpoint := range uc.controllerEndpoints() {
+		controller := uc.controllerSettings(endpoint.EndpointID)
+		if controller == "" {
+			continue
+		}
+		interval, _ := strconv.ParseUint(uc.getStringValue(controller+"PeriodicNotifInterval"), 10, 32)
+		if interval == 0 {
+			continue
+		}
+		reference, err := time.Parse(time.RFC3339, uc.getStringValue(controller+"PeriodicNotifTime"))
+		if err != nil || reference.Year() <= 1 {
+			reference = uc.bootTime
+		}
+		schedules[endpoint.EndpointID] = &periodicTimer{interval: time.Duration(interval) * time.Second, reference: reference}
+	}
+
+	uc.periodicMu.Lock()
+	defer uc.periodicMu.Unlock()
+
+	for endpointId, current := range uc.periodicTimers {
+		if next, ok := schedules[endpointId]; ok && next.interval == current.interval && next.reference.Equal(current.reference) {
+			schedules[endpointId] = current
+			continue
+		}
+		current.timer.Stop()
+		delete(uc.periodicTimers, endpointId)
+		logger.Infof("[USP] Periodic! stopped: controller=%s", endpointId)
+	}
+
+	for endpointId, schedule := range schedules {
+		if schedule.timer != nil {
+			continue
+		}
+		uc.startPeriodicTimer(endpointId, schedule)
+		uc.periodicTimers[endpointId] = schedule
+	}
+}
+
+// startPeriodicTimer 启动定时器，在下一个触发时刻向 controller 发送 Periodic! 并继续调度（调用方需持有 periodicMu）
+// Periodic! 只发送给 Recipient 为该 controller 的订阅
+func (uc *ClientUseCase) startPeriodicTimer(endpointId string, schedule *periodicTimer) {
+	next := nextPeriodicTime(time.Now(), schedule.reference, schedule.interval)
+	logger.Infof("[USP] Periodic! scheduled: controller=%s, interval=%s, next=%s", endpointId, schedule.interval, next.UTC().Format(time.RFC3339))
+
+	schedule.timer = time.AfterFunc(time.Until(next), func() {
+		uc.periodicMu.Lock()
+		current, ok := uc.periodicTimers[endpointId]
+		uc.periodicMu.Unlock()
+		// 定时器已被重新调度或停止
+		if !ok || current != schedule || uc.ctx.Err() != nil {
+			return
+		}
+
+		logger.Infof("[USP] send Periodic! event: controller=%s", endpointId)
+		uc.notifyControllerEvent(endpointId, model.PathLocalAgent, model.Periodic, map[string]string{}, nil)
+
+		uc.periodicMu.Lock()
+		defer uc.periodicMu.Unlock()
+		if uc.periodicTimers[endpointId] == schedule {
+			next := nextPeriodicTime(time.Now(), schedule.reference, schedule.interval)
+			schedule.timer.Reset(time.Until(next))
+		}
+	})
+}
+
+// nextPeriodicTime 计算 now 之后的下一个触发时刻：reference + k*interval
+// reference 可以早于或晚于当前时间（TR-181 PeriodicNotifTime 的语义）
+func nextPeriodicTime(now time.Time, reference time.Time, interval time.Duration) time.Time {
+	elapsed := now.Sub(reference)
+	periods := elapsed / interval
+	if elapsed < 0 {
+		periods-- // 向负无穷取整
+	}
+	next := reference.Add((periods + 1) * interval)
+	if !next.After(now) {
+		next = next.Add(interval)
+	}
+	return next
+}
@@ -0,0 +1,125 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"tr369-wss-client/client/model"
+)
+
+// periodicTestData Controller.1 为配置文件中的 controller，Controller.2 没有可以连接的 MTP
+const periodicTestData = `{
+	"Device": {
+		"LocalAgent": {
+			"Controller": {
+				"1": {"Enable": "true", "EndpointID": "ctrl-1", "PeriodicNotifInterval": "60", "PeriodicNotifTime": "0001-01-01T00:00:00Z"},
+				"2": {"Enable": "true", "EndpointID": "ctrl-2", "PeriodicNotifInterval": "30", "PeriodicNotifTime": "0001-01-01T00:00:00Z"}
+			}
+		}
+	}
+}`
+
+func TestNextPeriodicTime(t *testing.T) {
+	base := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		now       time.Time
+		reference time.Time
+		interval  time.Duration
+		want      time.Time
+	}{
+		{"reference in the past", base.Add(5 * time.Second), base, time.Minute, base.Add(time.Minute)},
+		{"now on a boundary", base.Add(2 * time.Minute), base, time.Minute, base.Add(3 * time.Minute)},
+		{"now equals reference", base, base, time.Minute, base.Add(time.Minute)},
+		{"reference far in the past", base, base.Add(-100*time.Hour - 90*time.Second), time.Hour, base.Add(58*time.Minute + 30*time.Second)},
+		{"reference in the future", base, base.Add(150 * time.Second), time.Minute, base.Add(30 * time.Second)},
+		{"reference one interval ahead", base, base.Add(time.Minute), time.Minute, base.Add(time.Minute)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := nextPeriodicTime(tt.now, tt.reference, tt.interval)
+			if !got.Equal(tt.want) {
+				t.Errorf("nextPeriodicTime(%s, %s, %s) = %s, want %s", tt.now, tt.reference, tt.interval, got, tt.want)
+			}
+			if !got.After(tt.now) || got.Sub(tt.now) > tt.interval {
+				t.Errorf("next %s not within one interval after %s", got, tt.now)
+			}
+			if tt.reference.Sub(got)%tt.interval != 0 {
+				t.Errorf("next %s not aligned to reference %s", got, tt.reference)
+			}
+		})
+	}
+}
+
+func TestIsControllerParam(t *testing.T) {
+	tests := map[string]bool{
+		model.PathController + "1.PeriodicNotifInterval":         true,
+		model.PathController + "1.PeriodicNotifTime":             true,
+		model.PathController + "1.Enable":                        true,
+		model.PathController + "1.EndpointID":                    true,
+		model.PathController + "1.Alias":                         false,
+		model.PathController + "1.BootParameter.1.Enable":        false,
+		model.PathLocalAgent + "Subscription.1.Enable":           false,
+		model.PathController + "1.MTP.1.WebSocket.KeepAliveTime": true,
+	}
+	for paramPath, want := range tests {
+		if got := isControllerParam(paramPath); got != want {
+			t.Errorf("isControllerParam(%s) = %v, want %v", paramPath, got, want)
+		}
+	}
+}
+
+func TestReschedulePeriodicNotifs(t *testing.T) {
+	uc, _ := newTestUseCase(t, periodicTestData)
+	controller := model.PathController + "1."
+
+	uc.DataRepo.Lock()
+	defer uc.DataRepo.Unlock()
+	schedule := func() *periodicTimer {
+		uc.reschedulePeriodicNotifs()
+		uc.periodicMu.Lock()
+		defer uc.periodicMu.Unlock()
+		if len(uc.periodicTimers) > 1 {
+			t.Fatalf("scheduled %d controllers, want only the connected controller", len(uc.periodicTimers))
+		}
+		return uc.periodicTimers["ctrl-1"]
+	}
+
+	first := schedule()
+	if first == nil || first.interval != time.Minute || !first.reference.Equal(uc.bootTime) {
+		t.Fatalf("initial schedule = %+v, want 1m from boot time", first)
+	}
+	if again := schedule(); again != first {
+		t.Error("unchanged parameters restarted the timer")
+	}
+
+	uc.DataRepo.SetValue(controller, "PeriodicNotifTime", "2026-01-01T00:00:30Z")
+	updated := schedule()
+	if updated == first || !updated.reference.Equal(time.Date(2026, 1, 1, 0, 0, 30, 0, time.UTC)) {
+		t.Errorf("PeriodicNotifTime change: schedule = %+v", updated)
+	}
+
+	uc.DataRepo.SetValue(controller, "PeriodicNotifInterval", "0")
+	if stopped := schedule(); stopped != nil {
+		t.Errorf("PeriodicNotifInterval 0: schedule = %+v, want stopped", stopped)
+	}
+}
+
+func TestPeriodicEvent(t *testing.T) {
+	uc, lm := newTestUseCase(t, periodicTestData)
+
+	uc.DataRepo.Lock()
+	uc.DataRepo.SetValue(model.PathController+"1.", "PeriodicNotifInterval", "1")
+	uc.reschedulePeriodicNotifs()
+	uc.DataRepo.Unlock()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for len(lm.eventsNamed(model.Periodic)) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d Periodic! events in 3s, want 2", len(lm.eventsNamed(model.Periodic)))
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,148 @@
+package usecase
+
+import (
+	"fmt"
+
+	"tr369-wss-client/config"
+	logger "tr369-wss-client/log"
+	"tr369-wss-client/pkg/api"
+	"tr369-wss-client/utils"
+)
+
+// HandleGetSupportedProtoRequest handles incoming GET_SUPPORTED_PROTO requests
+// 返回 Agent 支持的协议版本，并记录与该 controller 协商出的版本
+func (uc *ClientUseCase) HandleGetSupportedProtoRequest(fromId string, inComingMsg *api.Msg) {
+	// 防御性检查
+	if inComingMsg == nil || inComingMsg.Header == nil {
+		logger.Warnf("[USP] HandleGetSupportedProtoRequest received invalid message")
+		return
+	}
+
+	msgId := inComingMsg.Header.MsgId
+	logger.Infof("[USP] receive GET_SUPPORTED_PROTO request: %s", inComingMsg.String())
+
+	agentSupported := uc.Config.Tr369Config.SupportedVersions
+	controllerSupported := inComingMsg.GetBody().GetRequest().GetGetSupportedProtocol().GetControllerSupportedProtocolVersions()
+	if version, ok := utils.NegotiateVersion(agentSupported, controllerSupported); ok {
+		uc.setNegotiatedVersion(fromId, version)
+		logger.Infof("[USP] negotiated USP version %s with controller %s", version, fromId)
+	} else {
+		logger.Warnf("[USP] no common USP version with controller %s: agent=%s, controller=%s",
+			fromId, agentSupported, controllerSupported)
+	}
+
+	msg := utils.CreateGetSupportedProtocolResponseMessage(msgId, agentSupported)
+	logger.Infof("[USP] send GET_SUPPORTED_PROTO response: %s", msg.String())
+
+	err := uc.HandleMTPMsgTransmitTo(fromId, msg)
+	if err != nil {
+		logger.Warnf("[USP] GET_SUPPORTED_PROTO error: msgId=%s, err=%v", msgId, err)
+	}
+}
+
+// IsSupportedVersion 判断 Record 的协议版本是否被 Agent 支持
+func (uc *ClientUseCase) IsSupportedVersion(version string) bool {
+	return utils.IsVersionSupported(uc.Config.Tr369Config.SupportedVersions, version)
+}
+
+// AcceptRecord 校验 Record 的 to_id 和 from_id，返回是否继续处理
+// to_id 必须是 Agent 的 EndpointID，from_id 必须是配置文件中的 controller 或 Controller 表中的 controller；
+// 校验失败时按 RecordIdPolicy 丢弃 Record 或记录告警后继续处理（未知 controller 的请求受 UntrustedRole 限制）。
+// 缺少 from_id 的 Record 无法回复，总是丢弃
+func (uc *ClientUseCase) AcceptRecord(toId string, fromId string) bool {
+	if fromId == "" {
+		logger.Warnf("[USP] dropped record without from_id: to=%s", toId)
+		return false
+	}
+
+	var violation string
+	if agentId := uc.Config.WebsocketConfig.EndpointId; toId != agentId {
+		violation = fmt.Sprintf("to_id %q does not match agent EndpointID %q", toId, agentId)
+	} else if !uc.isKnownController(fromId) {
+		violation = fmt.Sprintf("from_id %q is not a known controller", fromId)
+	}
+	if violation == "" {
+		return true
+	}
+
+	if uc.Config.Tr369Config.RecordIdPolicy == config.RecordIdPolicyLog {
+		logger.Warnf("[USP] accepted record with invalid id: %s", violation)
+		return true
+	}
+	logger.Warnf("[USP] dropped record: %s", violation)
+	return false
+}
+
+// isKnownController 判断 endpoint 是否为配置文件中的 controller 或 Controller 表中的 controller
+func (uc *ClientUseCase) isKnownController(endpointId string) bool {
+	if endpointId == uc.Config.WebsocketConfig.ControllerId {
+		return true
+	}
+	uc.DataRepo.Lock()
+	defer uc.DataRepo.Unlock()
+	return uc.controllerInstance(endpointId) != ""
+}
+
+// SendErrorMessage 向指定 endpoint 发送 USP Error 消息
+func (uc *ClientUseCase) SendErrorMessage(toId string, msgId string, errCode uint32, errMsg string) {
+	msg := utils.CreateErrorMessage(msgId, errCode, errMsg, nil)
+	logger.Infof("[USP] send ERROR message: %s", msg.String())
+
+	if err := uc.HandleMTPMsgTransmitTo(toId, msg); err != nil {
+		logger.Warnf("[USP] ERROR message error: msgId=%s, err=%v", msgId, err)
+	}
+}
+
+// HandleErrorMessage handles incoming ERROR messages
+// controller 对 Agent 发出的请求（如 Notify）返回的错误，记录错误码和参数错误
+func (uc *ClientUseCase) HandleErrorMessage(fromId string, inComingMsg *api.Msg) {
+	// 防御性检查
+	if inComingMsg == nil || inComingMsg.Header == nil {
+		logger.Warnf("[USP] HandleErrorMessage received invalid message")
+		return
+	}
+
+	uspErr := inComingMsg.GetBody().GetError()
+	logger.Warnf("[USP] receive ERROR from %s: msgId=%s, errCode=%d, errMsg=%s",
+		fromId, inComingMsg.Header.MsgId, uspErr.GetErrCode(), uspErr.GetErrMsg())
+	for _, paramErr := range uspErr.GetParamErrs() {
+		logger.Warnf("[USP] ERROR param: msgId=%s, path=%s, errCode=%d, errMsg=%s",
+			inComingMsg.Header.MsgId, paramErr.GetParamPath(), paramErr.GetErrCode(), paramErr.GetErrMsg())
+	}
+
+	// controller 以 Error 拒绝通知时不再重试
+	if pending := uc.completeNotification(inComingMsg.Header.MsgId); pending != nil {
+		logger.Warnf("[USP] %s notify rejected: subscriptionId=%s, msgId=%s", pending.notifyType, pending.subscriptionId, inComingMsg.Header.MsgId)
+	}
+}
+
+// isResponseMsgType 判断消息类型是否为响应类消息（包括 Error）
+func isResponseMsgType(msgType api.Header_MsgType) bool {
+	switch msgType {
+	case api.Header_ERROR, api.Header_GET_RESP, api.Header_SET_RESP, api.Header_OPERATE_RESP,
+		api.Header_ADD_RESP, api.Header_DELETE_RESP, api.Header_GET_SUPPORTED_DM_RESP,
+		api.Header_GET_INSTANCES_RESP, api.Header_NOTIFY_RESP, api.Header_GET_SUPPORTED_PROTO_RESP,
+		api.Header_REGISTER_RESP, api.Header_DEREGISTER_RESP:
+		return true
+	}
+	return false
+}
+
+// negotiatedVersion 获取与 controller 协商后的协议版本，未协商时使用配置的默认版本
+func (uc *ClientUseCase) negotiatedVersion(controllerId string) string {
+	uc.versionMu.RLock()
+	defer uc.versionMu.RUnlock()
+
+	if version, ok := uc.negotiatedVersions[controllerId]; ok {
+		return version
+	}
+	return uc.Config.Tr369Config.Version
+}
+
+// setNegotiatedVersion 记录与 controller 协商后的协议版本
+func (uc *ClientUseCase) setNegotiatedVersion(controllerId string, version string) {
+	uc.versionMu.Lock()
+	defer uc.versionMu.Unlock()
+
+	uc.negotiatedVersions[controllerId] = version
+}
@@ -0,0 +1,108 @@
+package usecase
+
+import (
+	"strings"
+	"testing"
+
+	"tr369-wss-client/client/model"
+	"tr369-wss-client/config"
+	"tr369-wss-client/pkg/api"
+)
+
+func TestHandleMessageErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		msg     *api.Msg
+		errCode uint32 // 0 表示不回复
+	}{
+		{"missing header", &api.Msg{}, model.ErrCodeInvalidArguments},
+		{"unsupported request", &api.Msg{Header: &api.Header{MsgId: "m1", MsgType: api.Header_REGISTER}}, model.ErrCodeMessageNotSupported},
+		{"unsupported response", &api.Msg{Header: &api.Header{MsgId: "m2", MsgType: api.Header_GET_RESP}}, 0},
+		{"error from controller", &api.Msg{
+			Header: &api.Header{MsgId: "m3", MsgType: api.Header_ERROR},
+			Body:   &api.Body{MsgBody: &api.Body_Error{Error: &api.Error{ErrCode: model.ErrCodeInternalError}}},
+		}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, _ := newTestUseCase(t, validationTestData)
+			uc.HandleMessage("ctrl-1", tt.msg)
+
+			sent := drainSentMessages(t, uc)
+			if tt.errCode == 0 {
+				if len(sent) != 0 {
+					t.Fatalf("sent %d messages, want none", len(sent))
+				}
+				return
+			}
+			if len(sent) != 1 {
+				t.Fatalf("sent %d messages, want 1", len(sent))
+			}
+			if sent[0].toId != "ctrl-1" {
+				t.Errorf("Error sent to %q, want %q", sent[0].toId, "ctrl-1")
+			}
+			if got := sent[0].msg.GetBody().GetError().GetErrCode(); got != tt.errCode {
+				t.Errorf("Error code = %d, want %d", got, tt.errCode)
+			}
+			if got, want := sent[0].msg.GetHeader().GetMsgId(), tt.msg.GetHeader().GetMsgId(); got != want {
+				t.Errorf("Error msgId = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestHandleMTPMsgTransmitTooLarge(t *testing.T) {
+	uc, _ := newTestUseCase(t, validationTestData)
+	uc.Config.WebsocketConfig.MaxMessageSize = 512
+
+	large := &api.Msg{
+		Header: &api.Header{MsgId: "get-1", MsgType: api.Header_GET_RESP},
+		Body: &api.Body{MsgBody: &api.Body_Response{Response: &api.Response{RespType: &api.Response_GetResp{GetResp: &api.GetResp{
+			ReqPathResults: []*api.GetResp_RequestedPathResult{{RequestedPath: strings.Repeat("Device.", 200)}},
+		}}}}},
+	}
+	if err := uc.HandleMTPMsgTransmitTo("ctrl-1", large); err != nil {
+		t.Fatalf("HandleMTPMsgTransmitTo() error = %v", err)
+	}
+
+	sent := drainSentMessages(t, uc)
+	if len(sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(sent))
+	}
+	if got := sent[0].msg.GetBody().GetError().GetErrCode(); got != model.ErrCodeResourcesExceeded {
+		t.Errorf("Error code = %d, want %d", got, model.ErrCodeResourcesExceeded)
+	}
+	if got := sent[0].msg.GetHeader().GetMsgId(); got != "get-1" {
+		t.Errorf("Error msgId = %q, want %q", got, "get-1")
+	}
+}
+
+func TestAcceptRecord(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		toId   string
+		fromId string
+		want   bool
+	}{
+		{"configured controller", config.RecordIdPolicyReject, "agent-1", "ctrl-1", true},
+		{"controller in table", config.RecordIdPolicyReject, "agent-1", "ctrl-a", true},
+		{"default policy rejects unknown controller", "", "agent-1", "ctrl-x", false},
+		{"reject unknown controller", config.RecordIdPolicyReject, "agent-1", "ctrl-x", false},
+		{"reject wrong to_id", config.RecordIdPolicyReject, "agent-2", "ctrl-1", false},
+		{"log accepts unknown controller", config.RecordIdPolicyLog, "agent-1", "ctrl-x", true},
+		{"log accepts wrong to_id", config.RecordIdPolicyLog, "agent-2", "ctrl-1", true},
+		{"reject empty from_id", config.RecordIdPolicyReject, "agent-1", "", false},
+		{"log still drops empty from_id", config.RecordIdPolicyLog, "agent-1", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, _ := newTestUseCase(t, accessControlTestData)
+			uc.Config.Tr369Config.RecordIdPolicy = tt.policy
+			if got := uc.AcceptRecord(tt.toId, tt.fromId); got != tt.want {
+				t.Errorf("AcceptRecord(%q, %q) with policy %q = %v, want %v", tt.toId, tt.fromId, tt.policy, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,720 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"tr369-wss-client/client/model"
+	logger "tr369-wss-client/log"
+	"tr369-wss-client/trtree"
+	"tr369-wss-client/utils"
+)
+
+const (
+	// maxDUPackageSize 部署单元描述文件的最大长度
+	maxDUPackageSize = 1 << 20
+	// euTransitionDelay ExecutionUnit 启动/停止的模拟耗时（Starting、Stopping 状态的持续时间）
+	euTransitionDelay = 200 * time.Millisecond
+)
+
+// duUUIDRegex DeploymentUnit UUID 格式（RFC 4122）
+var duUUIDRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// duPackage 部署单元安装包，模拟设备只下载安装包的 JSON 描述文件
+type duPackage struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	Vendor         string      `json:"vendor"`
+	Description    string      `json:"description"`
+	ExecEnvType    string      `json:"exec_env_type"` // 要求的执行环境类型前缀（如 lxc），为空时不限制
+	DiskSpace      int64       `json:"disk_space"`    // 安装需要的磁盘空间（KiB）
+	ExecutionUnits []euPackage `json:"execution_units"`
+}
+
+// euPackage 安装包中的执行单元
+type euPackage struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	AutoStart   bool   `json:"auto_start"`
+}
+
+// duStateChange DUStateChange! 事件的内容
+type duStateChange struct {
+	UUID         string
+	DUPath       string
+	Version      string
+	CurrentState string
+	Resolved     bool
+	EUPaths      []string
+	StartTime    time.Time
+	Operation    string
+}
+
+// installDUCommand Device.SoftwareModules.InstallDU() 安装部署单元（异步命令）
+// 下载安装包描述文件后创建 DeploymentUnit 和 ExecutionUnit 实例，AutoStart 的执行单元安装后自动启动；
+// 无论成功与否都发送 DUStateChange! 事件，失败时 CurrentState 为 Failed
+func (uc *ClientUseCase) installDUCommand(ctx context.Context, req *commandRequest) (map[string]string, *model.USPError) {
+	u, uspErr := parseTransferURL(req.InputArgs["URL"])
+	if uspErr != nil {
+		return nil, uspErr
+	}
+
+	change := &duStateChange{
+		UUID:         req.InputArgs["UUID"],
+		CurrentState: model.DUStateFailed,
+		StartTime:    time.Now(),
+		Operation:    model.DUOperationInstall,
+	}
+	uspErr = uc.installDU(ctx, req, u, change)
+	uc.sendDUStateChange(change, uspErr)
+	if uspErr != nil {
+		return nil, uspErr
+	}
+	return map[string]string{}, nil
+}
+
+// installDU 执行部署单元安装，结果记录在 change 中
+func (uc *ClientUseCase) installDU(ctx context.Context, req *commandRequest, u *url.URL, change *duStateChange) *model.USPError {
+	if change.UUID != "" && !duUUIDRegex.MatchString(change.UUID) {
+		return model.NewUSPError(model.ErrCodeInvalidUUID, "%s", change.UUID)
+	}
+
+	uc.DataRepo.Lock()
+	eePath, uspErr := uc.lookupExecEnv(req.InputArgs["ExecutionEnvRef"])
+	if uspErr == nil && change.UUID != "" {
+		uspErr = uc.checkDuplicateDU(change.UUID, eePath, "")
+	}
+	var duPath string
+	if uspErr == nil {
+		duPath = uc.getNewInstance(model.PathDeploymentUnit)
+		uc.DataRepo.CreateObject(duPath)
+		uc.setParams(duPath, map[string]string{
+			"UUID":              change.UUID,
+			"DUID":              instanceNumber(duPath),
+			"Alias":             "cpe-du-" + instanceNumber(duPath),
+			"Name":              "",
+			"Status":            model.DUStatusInstalling,
+			"Resolved":          "false",
+			"URL":               u.Redacted(),
+			"Description":       "",
+			"Vendor":            "",
+			"Version":           "",
+			"ExecutionUnitList": "",
+			"ExecutionEnvRef":   objectRef(eePath),
+		})
+		uc.updateEntryCount(model.PathSoftwareModules, "DeploymentUnit")
+	}
+	uc.DataRepo.Unlock()
+	if uspErr != nil {
+		return uspErr
+	}
+	uc.notifyObjectCreation(duPath, nil)
+	logger.Infof("[USP] DU install started: du=%s, url=%s, execEnv=%s", duPath, u.Redacted(), eePath)
+
+	pkg, uspErr := uc.fetchDUPackage(ctx, u, req.InputArgs["Username"], req.InputArgs["Password"])
+	if ctx.Err() != nil {
+		uspErr = commandCanceled(req)
+	}
+
+	var changed map[string]string
+	var euPaths []string
+	if uspErr == nil {
+		if change.UUID == "" {
+			change.UUID = generateDUUUID(pkg)
+		}
+		uc.DataRepo.Lock()
+		uspErr = uc.checkDUInstallable(pkg, change.UUID, eePath, duPath)
+		if uspErr == nil {
+			changed, euPaths = uc.deployDU(duPath, eePath, pkg, change.UUID)
+		}
+		uc.DataRepo.Unlock()
+	}
+	if uspErr != nil {
+		uc.removeDU(duPath, nil)
+		return uspErr
+	}
+
+	for _, euPath := range euPaths {
+		uc.notifyObjectCreation(euPath, nil)
+	}
+	uc.notifyValueChanges(changed)
+
+	change.DUPath = duPath
+	change.Version = pkg.Version
+	change.CurrentState = model.DUStateInstalled
+	change.Resolved = true
+	change.EUPaths = euPaths
+	logger.Infof("[USP] DU installed: du=%s, name=%s, version=%s, eus=%v", duPath, pkg.Name, pkg.Version, euPaths)
+
+	uc.autoStartEUs(euPaths)
+	return nil
+}
+
+// updateDUCommand Device.SoftwareModules.DeploymentUnit.{i}.Update() 更新部署单元（异步命令）
+// URL 为空时使用安装时的 URL；只允许升级到更高的版本，同名的执行单元原地更新，
+// 新增的执行单元被创建，安装包中不再包含的执行单元被删除
+func (uc *ClientUseCase) updateDUCommand(ctx context.Context, req *commandRequest) (map[string]string, *model.USPError) {
+	duPath := req.ObjPath
+	change := &duStateChange{
+		DUPath:       duPath,
+		CurrentState: model.DUStateInstalled,
+		Resolved:     true,
+		StartTime:    time.Now(),
+		Operation:    model.DUOperationUpdate,
+	}
+
+	uc.DataRepo.Lock()
+	change.UUID = uc.getStringValue(duPath + "UUID")
+	change.Version = uc.getStringValue(duPath + "Version")
+	change.EUPaths = uc.duExecutionUnits(duPath)
+	status := uc.getStringValue(duPath + "Status")
+	var changed map[string]string
+	if status == model.DUStatusInstalled {
+		changed = uc.setParams(duPath, map[string]string{"Status": model.DUStatusUpdating})
+	}
+	rawURL := req.InputArgs["URL"]
+	if rawURL == "" {
+		rawURL = uc.getStringValue(duPath + "URL")
+	}
+	eePath := uc.getStringValue(duPath+"ExecutionEnvRef") + "."
+	uc.DataRepo.Unlock()
+
+	if status != model.DUStatusInstalled {
+		uspErr := duStatusError(duPath, status)
+		uc.sendDUStateChange(change, uspErr)
+		return nil, uspErr
+	}
+	uc.notifyValueChanges(changed)
+
+	uspErr := uc.updateDU(ctx, req, rawURL, eePath, change)
+	if uspErr != nil {
+		uc.updateParams(duPath, map[string]string{"Status": model.DUStatusInstalled})
+	}
+	uc.sendDUStateChange(change, uspErr)
+	if uspErr != nil {
+		return nil, uspErr
+	}
+	return map[string]string{}, nil
+}
+
+// updateDU 下载新的安装包并更新部署单元，结果记录在 change 中
+func (uc *ClientUseCase) updateDU(ctx context.Context, req *commandRequest, rawURL string, eePath string, change *duStateChange) *model.USPError {
+	u, uspErr := parseTransferURL(rawURL)
+	if uspErr != nil {
+		return uspErr
+	}
+	logger.Infof("[USP] DU update started: du=%s, url=%s", change.DUPath, u.Redacted())
+
+	pkg, uspErr := uc.fetchDUPackage(ctx, u, req.InputArgs["Username"], req.InputArgs["Password"])
+	if ctx.Err() != nil {
+		return commandCanceled(req)
+	}
+	if uspErr != nil {
+		return uspErr
+	}
+
+	switch cmp := compareVersions(pkg.Version, change.Version); {
+	case pkg.Version == "":
+		return model.NewUSPError(model.ErrCodeDUVersionNotSpecified, "%s", u.Redacted())
+	case cmp == 0:
+		return model.NewUSPError(model.ErrCodeDUVersionExists, "%s", pkg.Version)
+	case cmp < 0:
+		return model.NewUSPError(model.ErrCodeDUDowngradeNotPermitted, "%s -> %s", change.Version, pkg.Version)
+	}
+
+	uc.DataRepo.Lock()
+	uspErr = uc.checkDUInstallable(pkg, change.UUID, eePath, change.DUPath)
+	var changed map[string]string
+	var created, deleted []string
+	if uspErr == nil {
+		changed, created, deleted = uc.redeployDU(change.DUPath, eePath, pkg, u)
+		change.EUPaths = uc.duExecutionUnits(change.DUPath)
+	}
+	uc.DataRepo.Unlock()
+	if uspErr != nil {
+		return uspErr
+	}
+
+	for _, euPath := range deleted {
+		uc.notifyObjectDeletion(euPath)
+	}
+	for _, euPath := range created {
+		uc.notifyObjectCreation(euPath, nil)
+	}
+	uc.notifyValueChanges(changed)
+
+	change.Version = pkg.Version
+	logger.Infof("[USP] DU updated: du=%s, version=%s, eus=%v", change.DUPath, pkg.Version, change.EUPaths)
+
+	uc.autoStartEUs(created)
+	return nil
+}
+
+// uninstallDUCommand Device.SoftwareModules.DeploymentUnit.{i}.Uninstall() 卸载部署单元（异步命令）
+// 停止并删除部署单元的所有执行单元，然后删除部署单元实例
+func (uc *ClientUseCase) uninstallDUCommand(ctx context.Context, req *commandRequest) (map[string]string, *model.USPError) {
+	duPath := req.ObjPath
+	change := &duStateChange{
+		DUPath:       duPath,
+		CurrentState: model.DUStateInstalled,
+		Resolved:     true,
+		StartTime:    time.Now(),
+		Operation:    model.DUOperationUninstall,
+	}
+
+	uc.DataRepo.Lock()
+	change.UUID = uc.getStringValue(duPath + "UUID")
+	change.Version = uc.getStringValue(duPath + "Version")
+	change.EUPaths = uc.duExecutionUnits(duPath)
+	status := uc.getStringValue(duPath + "Status")
+	changed := make(map[string]string)
+	if status == model.DUStatusInstalled {
+		changed = uc.setParams(duPath, map[string]string{"Status": model.DUStatusUninstalling})
+		for _, euPath := range change.EUPaths {
+			if uc.getStringValue(euPath+"Status") != model.EUStatusIdle {
+				for paramPath, value := range uc.setParams(euPath, map[string]string{"Status": model.EUStatusStopping}) {
+					changed[paramPath] = value
+				}
+			}
+		}
+	}
+	uc.DataRepo.Unlock()
+
+	if status != model.DUStatusInstalled {
+		uspErr := duStatusError(duPath, status)
+		uc.sendDUStateChange(change, uspErr)
+		return nil, uspErr
+	}
+	uc.notifyValueChanges(changed)
+	logger.Infof("[USP] DU uninstall started: du=%s, eus=%v", duPath, change.EUPaths)
+
+	// 卸载不可取消，模拟执行单元停止的耗时后删除实例
+	time.Sleep(euTransitionDelay)
+	uc.removeDU(duPath, change.EUPaths)
+
+	change.CurrentState = model.DUStateUninstalled
+	change.Resolved = false
+	uc.sendDUStateChange(change, nil)
+	logger.Infof("[USP] DU uninstalled: du=%s, uuid=%s", duPath, change.UUID)
+	return map[string]string{}, nil
+}
+
+// setRequestedStateCommand Device.SoftwareModules.ExecutionUnit.{i}.SetRequestedState() 启动或停止执行单元（同步命令）
+// 命令只记录 RequestedState，状态迁移（Idle -> Starting -> Active，Active -> Stopping -> Idle）在后台完成
+func (uc *ClientUseCase) setRequestedStateCommand(ctx context.Context, req *commandRequest) (map[string]string, *model.USPError) {
+	euPath := req.ObjPath
+	state := req.InputArgs["RequestedState"]
+	if state != model.EUStatusIdle && state != model.EUStatusActive {
+		return nil, model.NewUSPError(model.ErrCodeInvalidCommandArgs, "RequestedState must be Idle or Active, got %q", state)
+	}
+	if duPath := uc.findDUForEU(euPath); duPath != "" {
+		if status := uc.getStringValue(duPath + "Status"); status != model.DUStatusInstalled {
+			return nil, duStatusError(duPath, status)
+		}
+	}
+
+	uc.notifyValueChanges(uc.setParams(euPath, map[string]string{"RequestedState": state}))
+	if uc.getStringValue(euPath+"Status") != state {
+		go uc.transitionEU(euPath, state)
+	}
+	return map[string]string{}, nil
+}
+
+// autoStartEUs 启动 AutoStart 为 true 的执行单元
+func (uc *ClientUseCase) autoStartEUs(euPaths []string) {
+	for _, euPath := range euPaths {
+		uc.DataRepo.Lock()
+		autoStart := utils.IsTrue(uc.getStringValue(euPath + "AutoStart"))
+		var changed map[string]string
+		if autoStart {
+			changed = uc.setParams(euPath, map[string]string{"RequestedState": model.EUStatusActive})
+		}
+		uc.DataRepo.Unlock()
+
+		if autoStart {
+			uc.notifyValueChanges(changed)
+			go uc.transitionEU(euPath, model.EUStatusActive)
+		}
+	}
+}
+
+// transitionEU 模拟执行单元的状态迁移
+// 迁移过程中 RequestedState 再次改变时放弃本次迁移；执行环境未启用时启动失败，ExecutionFaultCode 为 FailureOnStart
+func (uc *ClientUseCase) transitionEU(euPath string, requested string) {
+	transient := model.EUStatusStarting
+	if requested == model.EUStatusIdle {
+		transient = model.EUStatusStopping
+	}
+	uc.updateParams(euPath, map[string]string{"Status": transient})
+
+	select {
+	case <-uc.ctx.Done():
+		return
+	case <-time.After(euTransitionDelay):
+	}
+
+	uc.DataRepo.Lock()
+	if _, err := uc.DataRepo.GetValue(euPath); err != nil || uc.getStringValue(euPath+"RequestedState") != requested {
+		uc.DataRepo.Unlock()
+		return
+	}
+
+	values := map[string]string{
+		"Status":                requested,
+		"ExecutionFaultCode":    model.EUFaultNoFault,
+		"ExecutionFaultMessage": "",
+	}
+	eePath := uc.getStringValue(euPath+"ExecutionEnvRef") + "."
+	if requested == model.EUStatusActive && !uc.isExecEnvUp(eePath) {
+		values["Status"] = model.EUStatusIdle
+		values["ExecutionFaultCode"] = model.EUFaultFailureOnStart
+		values["ExecutionFaultMessage"] = "execution environment is not running"
+	}
+	changed := uc.setParams(euPath, values)
+	for paramPath, value := range uc.refreshActiveExecutionUnits(eePath) {
+		changed[paramPath] = value
+	}
+	uc.DataRepo.Unlock()
+
+	uc.notifyValueChanges(changed)
+	logger.Infof("[USP] EU state changed: eu=%s, status=%s, fault=%s", euPath, values["Status"], values["ExecutionFaultCode"])
+}
+
+// fetchDUPackage 下载并解析部署单元的安装包描述文件
+func (uc *ClientUseCase) fetchDUPackage(ctx context.Context, u *url.URL, username string, password string) (*duPackage, *model.USPError) {
+	body, err := uc.openTransferSource(ctx, u, username, password)
+	if err != nil {
+		return nil, model.NewUSPError(model.ErrCodeCommandFailure, "download %s failed: %v", u.Redacted(), err)
+	}
+	defer body.Close()
+
+	content, err := io.ReadAll(io.LimitReader(&contextReader{ctx: ctx, reader: body}, maxDUPackageSize))
+	if err != nil {
+		return nil, model.NewUSPError(model.ErrCodeCommandFailure, "download %s failed: %v", u.Redacted(), err)
+	}
+
+	pkg := &duPackage{}
+	if err := json.Unmarshal(content, pkg); err != nil {
+		return nil, model.NewUSPError(model.ErrCodeCommandFailure, "invalid package %s: %v", u.Redacted(), err)
+	}
+	if pkg.Name == "" {
+		return nil, model.NewUSPError(model.ErrCodeCommandFailure, "invalid package %s: name is missing", u.Redacted())
+	}
+	// 没有声明执行单元的安装包包含一个与部署单元同名的执行单元
+	if len(pkg.ExecutionUnits) == 0 {
+		pkg.ExecutionUnits = []euPackage{{Name: pkg.Name, Description: pkg.Description}}
+	}
+	return pkg, nil
+}
+
+// lookupExecEnv 查找安装使用的执行环境，ref 为空时使用第一个执行环境（调用方需持有数据锁）
+func (uc *ClientUseCase) lookupExecEnv(ref string) (string, *model.USPError) {
+	if ref == "" {
+		envs, _ := trtree.ResolveObjectPath(uc.DataRepo.GetParameters(), model.PathExecEnv+"*.")
+		if len(envs) == 0 {
+			return "", model.NewUSPError(model.ErrCodeUnknownExecEnv, "no execution environment")
+		}
+		ref = envs[0]
+	}
+
+	eePath := strings.TrimSuffix(ref, ".") + "."
+	if !strings.HasPrefix(eePath, model.PathDevice) {
+		eePath = model.PathDevice + eePath
+	}
+	envs, err := trtree.ResolveObjectPath(uc.DataRepo.GetParameters(), eePath)
+	if err != nil || len(envs) != 1 || !strings.HasPrefix(envs[0], model.PathExecEnv) {
+		return "", model.NewUSPError(model.ErrCodeUnknownExecEnv, "%s", ref)
+	}
+	if !uc.isExecEnvUp(envs[0]) {
+		return "", model.NewUSPError(model.ErrCodeDisabledExecEnv, "%s", ref)
+	}
+	return envs[0], nil
+}
+
+// isExecEnvUp 判断执行环境是否启用并处于运行状态（调用方需持有数据锁）
+func (uc *ClientUseCase) isExecEnvUp(eePath string) bool {
+	return utils.IsTrue(uc.getStringValue(eePath+"Enable")) && uc.getStringValue(eePath+"Status") == "Up"
+}
+
+// checkDuplicateDU 检查同一执行环境中是否已安装相同 UUID 的部署单元，exceptPath 为需要排除的部署单元（调用方需持有数据锁）
+func (uc *ClientUseCase) checkDuplicateDU(uuid string, eePath string, exceptPath string) *model.USPError {
+	dus, _ := trtree.ResolveObjectPath(uc.DataRepo.GetParameters(), model.PathDeploymentUnit+"*.")
+	for _, duPath := range dus {
+		if duPath == exceptPath {
+			continue
+		}
+		if strings.EqualFold(uc.getStringValue(duPath+"UUID"), uuid) &&
+			uc.getStringValue(duPath+"ExecutionEnvRef") == objectRef(eePath) {
+			return model.NewUSPError(model.ErrCodeDuplicateDU, "%s already installed as %s", uuid, duPath)
+		}
+	}
+	return nil
+}
+
+// checkDUInstallable 检查安装包能否安装到执行环境中（调用方需持有数据锁）
+func (uc *ClientUseCase) checkDUInstallable(pkg *duPackage, uuid string, eePath string, duPath string) *model.USPError {
+	if uspErr := uc.checkDuplicateDU(uuid, eePath, duPath); uspErr != nil {
+		return uspErr
+	}
+	if eeType := uc.getStringValue(eePath + "Type"); pkg.ExecEnvType != "" && !strings.HasPrefix(eeType, pkg.ExecEnvType) {
+		return model.NewUSPError(model.ErrCodeDUExecEnvMismatch, "%s requires %s, %s is %s", pkg.Name, pkg.ExecEnvType, eePath, eeType)
+	}
+	if available, err := strconv.ParseInt(uc.getStringValue(eePath+"AvailableDiskSpace"), 10, 64); err == nil && available >= 0 && pkg.DiskSpace > available {
+		return model.NewUSPError(model.ErrCodeSystemResourcesExceeded, "%s requires %d KiB disk space, %d KiB available", pkg.Name, pkg.DiskSpace, available)
+	}
+	return nil
+}
+
+// deployDU 写入部署单元参数并创建执行单元，返回发生变化的参数和创建的执行单元（调用方需持有数据锁）
+func (uc *ClientUseCase) deployDU(duPath string, eePath string, pkg *duPackage, uuid string) (map[string]string, []string) {
+	var euPaths []string
+	for _, eu := range pkg.ExecutionUnits {
+		euPaths = append(euPaths, uc.createEU(eePath, pkg, eu))
+	}
+	uc.updateEntryCount(model.PathSoftwareModules, "ExecutionUnit")
+
+	changed := uc.setParams(duPath, map[string]string{
+		"UUID":              uuid,
+		"Name":              pkg.Name,
+		"Status":            model.DUStatusInstalled,
+		"Resolved":          "true",
+		"Description":       pkg.Description,
+		"Vendor":            pkg.Vendor,
+		"Version":           pkg.Version,
+		"ExecutionUnitList": objectRefList(euPaths),
+	})
+	return changed, euPaths
+}
+
+// redeployDU 使用新的安装包更新部署单元，同名执行单元原地更新（调用方需持有数据锁）
+// 返回发生变化的参数、新建的执行单元和删除的执行单元
+func (uc *ClientUseCase) redeployDU(duPath string, eePath string, pkg *duPackage, u *url.URL) (map[string]string, []string, []string) {
+	existing := make(map[string]string)
+	for _, euPath := range uc.duExecutionUnits(duPath) {
+		existing[uc.getStringValue(euPath+"Name")] = euPath
+	}
+
+	changed := make(map[string]string)
+	var euPaths, created, deleted []string
+	for _, eu := range pkg.ExecutionUnits {
+		euPath, ok := existing[eu.Name]
+		if !ok {
+			euPath = uc.createEU(eePath, pkg, eu)
+			created = append(created, euPath)
+		} else {
+			delete(existing, eu.Name)
+			for paramPath, value := range uc.setParams(euPath, map[string]string{
+				"Vendor":      pkg.Vendor,
+				"Version":     pkg.Version,
+				"Description": eu.Description,
+				"AutoStart":   strconv.FormatBool(eu.AutoStart),
+			}) {
+				changed[paramPath] = value
+			}
+		}
+		euPaths = append(euPaths, euPath)
+	}
+	for _, euPath := range existing {
+		uc.DataRepo.DeleteNode(euPath)
+		deleted = append(deleted, euPath)
+	}
+	uc.updateEntryCount(model.PathSoftwareModules, "ExecutionUnit")
+	for paramPath, value := range uc.refreshActiveExecutionUnits(eePath) {
+		changed[paramPath] = value
+	}
+
+	for paramPath, value := range uc.setParams(duPath, map[string]string{
+		"Name":              pkg.Name,
+		"Status":            model.DUStatusInstalled,
+		"URL":               u.Redacted(),
+		"Description":       pkg.Description,
+		"Vendor":            pkg.Vendor,
+		"Version":           pkg.Version,
+		"ExecutionUnitList": objectRefList(euPaths),
+	}) {
+		changed[paramPath] = value
+	}
+	return changed, created, deleted
+}
+
+// createEU 创建执行单元实例（调用方需持有数据锁）
+func (uc *ClientUseCase) createEU(eePath string, pkg *duPackage, eu euPackage) string {
+	euPath := uc.getNewInstance(model.PathExecutionUnit)
+	uc.DataRepo.CreateObject(euPath)
+	uc.setParams(euPath, map[string]string{
+		"EUID":                  instanceNumber(euPath),
+		"Alias":                 "cpe-eu-" + instanceNumber(euPath),
+		"Name":                  eu.Name,
+		"ExecEnvLabel":          eu.Name,
+		"Status":                model.EUStatusIdle,
+		"RequestedState":        model.EUStatusIdle,
+		"ExecutionFaultCode":    model.EUFaultNoFault,
+		"ExecutionFaultMessage": "",
+		"AutoStart":             strconv.FormatBool(eu.AutoStart),
+		"RunLevel":              "0",
+		"Vendor":                pkg.Vendor,
+		"Version":               pkg.Version,
+		"Description":           eu.Description,
+		"ExecutionEnvRef":       objectRef(eePath),
+	})
+	return euPath
+}
+
+// removeDU 删除部署单元及其执行单元并发送 ObjectDeletion 通知（调用方不能持有数据锁）
+func (uc *ClientUseCase) removeDU(duPath string, euPaths []string) {
+	uc.DataRepo.Lock()
+	eePath := uc.getStringValue(duPath+"ExecutionEnvRef") + "."
+	for _, euPath := range euPaths {
+		uc.DataRepo.DeleteNode(euPath)
+	}
+	uc.DataRepo.DeleteNode(duPath)
+	uc.updateEntryCount(model.PathSoftwareModules, "DeploymentUnit")
+	uc.updateEntryCount(model.PathSoftwareModules, "ExecutionUnit")
+	changed := uc.refreshActiveExecutionUnits(eePath)
+	uc.DataRepo.Unlock()
+
+	for _, euPath := range euPaths {
+		uc.notifyObjectDeletion(euPath)
+	}
+	uc.notifyObjectDeletion(duPath)
+	uc.notifyValueChanges(changed)
+}
+
+// duExecutionUnits 返回部署单元的执行单元路径（调用方需持有数据锁）
+func (uc *ClientUseCase) duExecutionUnits(duPath string) []string {
+	var euPaths []string
+	for _, ref := range strings.Split(uc.getStringValue(duPath+"ExecutionUnitList"), ",") {
+		if ref = strings.TrimSpace(ref); ref != "" {
+			euPaths = append(euPaths, strings.TrimSuffix(ref, ".")+".")
+		}
+	}
+	return euPaths
+}
+
+// findDUForEU 查找执行单元所属的部署单元（调用方需持有数据锁）
+func (uc *ClientUseCase) findDUForEU(euPath string) string {
+	dus, _ := trtree.ResolveObjectPath(uc.DataRepo.GetParameters(), model.PathDeploymentUnit+"*.")
+	for _, duPath := range dus {
+		for _, path := range uc.duExecutionUnits(duPath) {
+			if path == euPath {
+				return duPath
+			}
+		}
+	}
+	return ""
+}
+
+// refreshActiveExecutionUnits 根据执行单元状态更新执行环境的 ActiveExecutionUnits（调用方需持有数据锁）
+func (uc *ClientUseCase) refreshActiveExecutionUnits(eePath string) map[string]string {
+	if _, err := uc.DataRepo.GetValue(eePath); err != nil {
+		return nil
+	}
+	var active []string
+	eus, _ := trtree.ResolveObjectPath(uc.DataRepo.GetParameters(), model.PathExecutionUnit+"*.")
+	for _, euPath := range eus {
+		if uc.getStringValue(euPath+"ExecutionEnvRef") == objectRef(eePath) &&
+			uc.getStringValue(euPath+"Status") == model.EUStatusActive {
+			active = append(active, euPath)
+		}
+	}
+	return uc.setParams(eePath, map[string]string{"ActiveExecutionUnits": objectRefList(active)})
+}
+
+// updateEntryCount 更新多实例表的 NumberOfEntries 参数（调用方需持有数据锁）
+func (uc *ClientUseCase) updateEntryCount(parentPath string, table string) {
+	instances, _ := trtree.ResolveObjectPath(uc.DataRepo.GetParameters(), parentPath+table+".*.")
+	uc.DataRepo.SetValue(parentPath, table+"NumberOfEntries", strconv.Itoa(len(instances)))
+}
+
+// sendDUStateChange 发送 Device.SoftwareModules.DUStateChange! 事件
+func (uc *ClientUseCase) sendDUStateChange(change *duStateChange, uspErr *model.USPError) {
+	params := map[string]string{
+		"UUID":                 change.UUID,
+		"DeploymentUnitRef":    objectRef(change.DUPath),
+		"Version":              change.Version,
+		"CurrentState":         change.CurrentState,
+		"Resolved":             strconv.FormatBool(change.Resolved),
+		"ExecutionUnitRefList": objectRefList(change.EUPaths),
+		"StartTime":            change.StartTime.UTC().Format(time.RFC3339),
+		"CompleteTime":         time.Now().UTC().Format(time.RFC3339),
+		"OperationPerformed":   change.Operation,
+		"Fault.FaultCode":      "0",
+		"Fault.FaultString":    "",
+	}
+	if uspErr != nil {
+		params["Fault.FaultCode"] = strconv.FormatUint(uint64(uspErr.Code), 10)
+		params["Fault.FaultString"] = uspErr.Message
+		logger.Warnf("[USP] DU %s failed: du=%s, err=%v", strings.ToLower(change.Operation), change.DUPath, uspErr)
+	}
+	uc.notifyEvent(model.PathSoftwareModules, model.DUStateChange, params)
+}
+
+// duStatusError 部署单元状态不允许当前操作时返回的错误，部署单元已被删除（如排队期间被卸载）时为 7228
+func duStatusError(duPath string, status string) *model.USPError {
+	if status == "" {
+		return model.NewUSPError(model.ErrCodeUnknownDU, "%s", duPath)
+	}
+	return model.NewUSPError(model.ErrCodeInvalidDUState, "%s is %s", duPath, status)
+}
+
+// generateDUUUID 根据 Vendor 和 Name 生成基于名称的 UUID（RFC 4122 版本 5）
+func generateDUUUID(pkg *duPackage) string {
+	sum := sha1.Sum([]byte(pkg.Vendor + ":" + pkg.Name))
+	sum[6] = (sum[6] & 0x0f) | 0x50
+	sum[8] = (sum[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// compareVersions 按分段比较版本号，如 1.10.0 > 1.9.2；数字分段按数值比较，其他分段按字符串比较
+func compareVersions(a string, b string) int {
+	partsA, partsB := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(partsA) || i < len(partsB); i++ {
+		var partA, partB string
+		if i < len(partsA) {
+			partA = partsA[i]
+		}
+		if i < len(partsB) {
+			partB = partsB[i]
+		}
+		numA, errA := strconv.Atoi(partA)
+		numB, errB := strconv.Atoi(partB)
+		switch {
+		case errA == nil && errB == nil && numA != numB:
+			if numA < numB {
+				return -1
+			}
+			return 1
+		case (errA != nil || errB != nil) && partA != partB:
+			return strings.Compare(partA, partB)
+		}
+	}
+	return 0
+}
+
+// instanceNumber 返回实例路径的实例编号，如 Device.SoftwareModules.DeploymentUnit.2. -> 2
+func instanceNumber(instPath string) string {
+	segments := strings.Split(strings.TrimSuffix(instPath, "."), ".")
+	return segments[len(segments)-1]
+}
+
+// objectRef 返回对象引用参数的值（不带结尾的 .）
+func objectRef(objPath string) string {
+	return strings.TrimSuffix(objPath, ".")
+}
+
+// objectRefList 返回对象引用列表参数的值（逗号分隔）
+func objectRefList(objPaths []string) string {
+	refs := make([]string, 0, len(objPaths))
+	for _, objPath := range objPaths {
+		refs = append(refs, objectRef(objPath))
+	}
+	return strings.Join(refs, ",")
+}
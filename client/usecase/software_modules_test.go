@@ -0,0 +1,349 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"tr369-wss-client/client/model"
+)
+
+// softwareModulesTestData ExecEnv.1 运行中，ExecEnv.2 未启用；DeploymentUnit.1 已安装（版本 1.0.0），
+// DeploymentUnit.2 正在安装；ExecutionUnit.1 属于 DeploymentUnit.1，ExecutionUnit.2 使用未启用的执行环境
+const softwareModulesTestData = `{
+	"Device": {
+		"LocalAgent": {"EndpointID": "agent-1", "Controller": {}},
+		"SoftwareModules": {
+			"ExecEnvNumberOfEntries": "2",
+			"ExecEnv": {
+				"1": {"Enable": "true", "Status": "Up", "Type": "lxc:5.0.3", "AvailableDiskSpace": "1000", "ActiveExecutionUnits": ""},
+				"2": {"Enable": "false", "Status": "Disabled", "Type": "lxc:5.0.3", "AvailableDiskSpace": "1000", "ActiveExecutionUnits": ""}
+			},
+			"DeploymentUnitNumberOfEntries": "2",
+			"DeploymentUnit": {
+				"1": {"UUID": "6f1c7a52-9d0e-4b7a-8c1d-2e3f4a5b6c7d", "Name": "app", "Status": "Installed", "Version": "1.0.0",
+					"URL": "", "ExecutionUnitList": "Device.SoftwareModules.ExecutionUnit.1", "ExecutionEnvRef": "Device.SoftwareModules.ExecEnv.1"},
+				"2": {"UUID": "", "Name": "", "Status": "Installing", "Version": "", "URL": "", "ExecutionUnitList": "",
+					"ExecutionEnvRef": "Device.SoftwareModules.ExecEnv.1"}
+			},
+			"ExecutionUnitNumberOfEntries": "2",
+			"ExecutionUnit": {
+				"1": {"Name": "app", "Status": "Idle", "RequestedState": "Idle", "ExecutionFaultCode": "NoFault", "ExecutionFaultMessage": "",
+					"ExecutionEnvRef": "Device.SoftwareModules.ExecEnv.1"},
+				"2": {"Name": "tool", "Status": "Idle", "RequestedState": "Idle", "ExecutionFaultCode": "NoFault", "ExecutionFaultMessage": "",
+					"ExecutionEnvRef": "Device.SoftwareModules.ExecEnv.2"}
+			}
+		}
+	}
+}`
+
+const (
+	testDUPath = "Device.SoftwareModules.DeploymentUnit.1."
+	testEUPath = "Device.SoftwareModules.ExecutionUnit.1."
+)
+
+// newDUPackageServer 提供部署单元安装包描述文件的测试服务器，路径为 /<名称>.json
+func newDUPackageServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	packages := map[string]duPackage{
+		"app_1.0":    {Name: "app", Version: "1.0.0", Vendor: "TP", ExecEnvType: "lxc", DiskSpace: 100},
+		"app_0.9":    {Name: "app", Version: "0.9.0", Vendor: "TP", ExecEnvType: "lxc", DiskSpace: 100},
+		"app_2.0":    {Name: "app", Version: "2.0.0", Vendor: "TP", ExecEnvType: "lxc", DiskSpace: 100},
+		"app_nover":  {Name: "app", Vendor: "TP", ExecEnvType: "lxc", DiskSpace: 100},
+		"app_docker": {Name: "app", Version: "1.0.0", Vendor: "TP", ExecEnvType: "docker", DiskSpace: 100},
+		"app_large":  {Name: "app", Version: "1.0.0", Vendor: "TP", ExecEnvType: "lxc", DiskSpace: 4096},
+		"web": {Name: "web", Version: "1.0.0", Vendor: "TP", ExecEnvType: "lxc", DiskSpace: 100,
+			ExecutionUnits: []euPackage{{Name: "web", AutoStart: true}, {Name: "worker"}}},
+	}
+	mux := http.NewServeMux()
+	for name, pkg := range packages {
+		content, _ := json.Marshal(pkg)
+		mux.HandleFunc("/"+name+".json", func(w http.ResponseWriter, r *http.Request) {
+			w.Write(content)
+		})
+	}
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// waitParamValue 等待参数变为指定的值（执行单元的状态迁移在后台完成）
+func waitParamValue(t *testing.T, uc *ClientUseCase, paramPath string, value string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		uc.DataRepo.Lock()
+		got := uc.getStringValue(paramPath)
+		uc.DataRepo.Unlock()
+		if got == value {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s = %q, want %q", paramPath, got, value)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSoftwareModulesErrorCodes(t *testing.T) {
+	server := newDUPackageServer(t)
+
+	tests := []struct {
+		name    string
+		command func(uc *ClientUseCase) (map[string]string, *model.USPError)
+		errCode uint32
+	}{
+		{"invalid UUID", runInstallDU(server.URL+"/app_1.0.json", map[string]string{"UUID": "not-a-uuid"}), model.ErrCodeInvalidUUID},
+		{"unknown exec env", runInstallDU(server.URL+"/app_1.0.json", map[string]string{"ExecutionEnvRef": "Device.SoftwareModules.ExecEnv.9"}), model.ErrCodeUnknownExecEnv},
+		{"disabled exec env", runInstallDU(server.URL+"/app_1.0.json", map[string]string{"ExecutionEnvRef": "Device.SoftwareModules.ExecEnv.2"}), model.ErrCodeDisabledExecEnv},
+		{"exec env mismatch", runInstallDU(server.URL+"/app_docker.json", nil), model.ErrCodeDUExecEnvMismatch},
+		{"duplicate DU", runInstallDU(server.URL+"/app_1.0.json", map[string]string{"UUID": "6F1C7A52-9D0E-4B7A-8C1D-2E3F4A5B6C7D"}), model.ErrCodeDuplicateDU},
+		{"disk space exceeded", runInstallDU(server.URL+"/app_large.json", nil), model.ErrCodeSystemResourcesExceeded},
+		{"unknown DU", runUpdateDU("Device.SoftwareModules.DeploymentUnit.9.", server.URL+"/app_2.0.json"), model.ErrCodeUnknownDU},
+		{"update while installing", runUpdateDU("Device.SoftwareModules.DeploymentUnit.2.", server.URL+"/app_2.0.json"), model.ErrCodeInvalidDUState},
+		{"downgrade", runUpdateDU(testDUPath, server.URL+"/app_0.9.json"), model.ErrCodeDUDowngradeNotPermitted},
+		{"version not specified", runUpdateDU(testDUPath, server.URL+"/app_nover.json"), model.ErrCodeDUVersionNotSpecified},
+		{"version exists", runUpdateDU(testDUPath, server.URL+"/app_1.0.json"), model.ErrCodeDUVersionExists},
+		{"uninstall while installing", runUninstallDU("Device.SoftwareModules.DeploymentUnit.2."), model.ErrCodeInvalidDUState},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, lm := newTestUseCase(t, softwareModulesTestData)
+			uc.SetHTTPClient(server.Client())
+
+			_, uspErr := tt.command(uc)
+			if uspErr == nil || uspErr.Code != tt.errCode {
+				t.Fatalf("command error = %v, want code %d", uspErr, tt.errCode)
+			}
+
+			changes := lm.eventsNamed(model.DUStateChange)
+			if len(changes) != 1 {
+				t.Fatalf("got %d DUStateChange! events, want 1", len(changes))
+			}
+			if fault := changes[0].Params["Fault.FaultCode"]; fault != strconv.FormatUint(uint64(tt.errCode), 10) {
+				t.Errorf("DUStateChange! Fault.FaultCode = %s, want %d", fault, tt.errCode)
+			}
+
+			uc.DataRepo.Lock()
+			defer uc.DataRepo.Unlock()
+			if count := uc.getStringValue(model.PathSoftwareModules + "DeploymentUnitNumberOfEntries"); count != "2" {
+				t.Errorf("DeploymentUnitNumberOfEntries = %s after failed command, want 2", count)
+			}
+		})
+	}
+}
+
+// runInstallDU 返回执行 InstallDU() 的函数，args 为 URL 以外的输入参数
+func runInstallDU(url string, args map[string]string) func(uc *ClientUseCase) (map[string]string, *model.USPError) {
+	return func(uc *ClientUseCase) (map[string]string, *model.USPError) {
+		inputArgs := map[string]string{"URL": url}
+		for key, value := range args {
+			inputArgs[key] = value
+		}
+		return uc.installDUCommand(context.Background(), &commandRequest{ObjPath: model.PathSoftwareModules, Name: "InstallDU()", InputArgs: inputArgs})
+	}
+}
+
+// runUpdateDU 返回执行 DeploymentUnit.{i}.Update() 的函数
+func runUpdateDU(duPath string, url string) func(uc *ClientUseCase) (map[string]string, *model.USPError) {
+	return func(uc *ClientUseCase) (map[string]string, *model.USPError) {
+		return uc.updateDUCommand(context.Background(), &commandRequest{ObjPath: duPath, Name: "Update()", InputArgs: map[string]string{"URL": url}})
+	}
+}
+
+// runUninstallDU 返回执行 DeploymentUnit.{i}.Uninstall() 的函数
+func runUninstallDU(duPath string) func(uc *ClientUseCase) (map[string]string, *model.USPError) {
+	return func(uc *ClientUseCase) (map[string]string, *model.USPError) {
+		return uc.uninstallDUCommand(context.Background(), &commandRequest{ObjPath: duPath, Name: "Uninstall()", InputArgs: map[string]string{}})
+	}
+}
+
+func TestDeploymentUnitStateTransitions(t *testing.T) {
+	server := newDUPackageServer(t)
+
+	tests := []struct {
+		name       string
+		command    func(uc *ClientUseCase) (map[string]string, *model.USPError)
+		duPath     string
+		operation  string
+		state      string
+		version    string
+		statusSeq  []string
+		wantExists bool
+	}{
+		{
+			name:       "install",
+			command:    runInstallDU(server.URL+"/web.json", nil),
+			duPath:     "Device.SoftwareModules.DeploymentUnit.3.",
+			operation:  model.DUOperationInstall,
+			state:      model.DUStateInstalled,
+			version:    "1.0.0",
+			statusSeq:  []string{model.DUStatusInstalled},
+			wantExists: true,
+		},
+		{
+			name:       "update",
+			command:    runUpdateDU(testDUPath, server.URL+"/app_2.0.json"),
+			duPath:     testDUPath,
+			operation:  model.DUOperationUpdate,
+			state:      model.DUStateInstalled,
+			version:    "2.0.0",
+			statusSeq:  []string{model.DUStatusUpdating, model.DUStatusInstalled},
+			wantExists: true,
+		},
+		{
+			name:      "uninstall",
+			command:   runUninstallDU(testDUPath),
+			duPath:    testDUPath,
+			operation: model.DUOperationUninstall,
+			state:     model.DUStateUninstalled,
+			version:   "1.0.0",
+			statusSeq: []string{model.DUStatusUninstalling},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, lm := newTestUseCase(t, softwareModulesTestData)
+			uc.SetHTTPClient(server.Client())
+
+			if _, uspErr := tt.command(uc); uspErr != nil {
+				t.Fatalf("command error: %v", uspErr)
+			}
+
+			changes := lm.eventsNamed(model.DUStateChange)
+			if len(changes) != 1 {
+				t.Fatalf("got %d DUStateChange! events, want 1", len(changes))
+			}
+			params := changes[0].Params
+			if params["OperationPerformed"] != tt.operation || params["CurrentState"] != tt.state ||
+				params["Version"] != tt.version || params["Fault.FaultCode"] != "0" {
+				t.Errorf("DUStateChange! params = %v", params)
+			}
+			if got := lm.valueChanges(tt.duPath + "Status"); !reflect.DeepEqual(got, tt.statusSeq) {
+				t.Errorf("%sStatus changes = %v, want %v", tt.duPath, got, tt.statusSeq)
+			}
+
+			uc.DataRepo.Lock()
+			_, err := uc.DataRepo.GetValue(tt.duPath)
+			uc.DataRepo.Unlock()
+			if (err == nil) != tt.wantExists {
+				t.Errorf("%s exists = %v, want %v", tt.duPath, err == nil, tt.wantExists)
+			}
+		})
+	}
+}
+
+func TestInstallDUAutoStart(t *testing.T) {
+	server := newDUPackageServer(t)
+	uc, _ := newTestUseCase(t, softwareModulesTestData)
+	uc.SetHTTPClient(server.Client())
+
+	if _, uspErr := runInstallDU(server.URL+"/web.json", nil)(uc); uspErr != nil {
+		t.Fatalf("InstallDU() error: %v", uspErr)
+	}
+
+	// web 自动启动，worker 保持 Idle
+	waitParamValue(t, uc, "Device.SoftwareModules.ExecutionUnit.3.Status", model.EUStatusActive)
+	waitParamValue(t, uc, "Device.SoftwareModules.ExecEnv.1.ActiveExecutionUnits", "Device.SoftwareModules.ExecutionUnit.3")
+	waitParamValue(t, uc, "Device.SoftwareModules.ExecutionUnit.4.Status", model.EUStatusIdle)
+}
+
+func TestExecutionUnitStateTransitions(t *testing.T) {
+	tests := []struct {
+		name      string
+		euPath    string
+		initial   string
+		requested string
+		status    string
+		fault     string
+		statusSeq []string
+	}{
+		{"start", testEUPath, model.EUStatusIdle, model.EUStatusActive, model.EUStatusActive, model.EUFaultNoFault,
+			[]string{model.EUStatusStarting, model.EUStatusActive}},
+		{"stop", testEUPath, model.EUStatusActive, model.EUStatusIdle, model.EUStatusIdle, model.EUFaultNoFault,
+			[]string{model.EUStatusStopping, model.EUStatusIdle}},
+		{"start in disabled exec env", "Device.SoftwareModules.ExecutionUnit.2.", model.EUStatusIdle, model.EUStatusActive,
+			model.EUStatusIdle, model.EUFaultFailureOnStart, []string{model.EUStatusStarting, model.EUStatusIdle}},
+		{"already in requested state", testEUPath, model.EUStatusIdle, model.EUStatusIdle, model.EUStatusIdle, model.EUFaultNoFault, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, lm := newTestUseCase(t, softwareModulesTestData)
+
+			uc.DataRepo.Lock()
+			uc.setParams(tt.euPath, map[string]string{"Status": tt.initial, "RequestedState": tt.initial})
+			_, uspErr := uc.setRequestedStateCommand(context.Background(), &commandRequest{
+				ObjPath:   tt.euPath,
+				Name:      "SetRequestedState()",
+				InputArgs: map[string]string{"RequestedState": tt.requested},
+			})
+			uc.DataRepo.Unlock()
+			if uspErr != nil {
+				t.Fatalf("SetRequestedState() error: %v", uspErr)
+			}
+
+			waitParamValue(t, uc, tt.euPath+"ExecutionFaultCode", tt.fault)
+			waitParamValue(t, uc, tt.euPath+"Status", tt.status)
+			// 等待迁移结束后的 ValueChange 通知发出
+			time.Sleep(2 * euTransitionDelay)
+			if got := lm.valueChanges(tt.euPath + "Status"); !reflect.DeepEqual(got, tt.statusSeq) {
+				t.Errorf("Status changes = %v, want %v", got, tt.statusSeq)
+			}
+		})
+	}
+}
+
+func TestSetRequestedStateInvalid(t *testing.T) {
+	tests := []struct {
+		name      string
+		duStatus  string
+		requested string
+		errCode   uint32
+	}{
+		{"invalid requested state", model.DUStatusInstalled, "Running", model.ErrCodeInvalidCommandArgs},
+		{"DU being updated", model.DUStatusUpdating, model.EUStatusActive, model.ErrCodeInvalidDUState},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, _ := newTestUseCase(t, softwareModulesTestData)
+
+			uc.DataRepo.Lock()
+			defer uc.DataRepo.Unlock()
+			uc.setParams(testDUPath, map[string]string{"Status": tt.duStatus})
+			_, uspErr := uc.setRequestedStateCommand(context.Background(), &commandRequest{
+				ObjPath:   testEUPath,
+				Name:      "SetRequestedState()",
+				InputArgs: map[string]string{"RequestedState": tt.requested},
+			})
+			if uspErr == nil || uspErr.Code != tt.errCode {
+				t.Fatalf("SetRequestedState() error = %v, want code %d", uspErr, tt.errCode)
+			}
+		})
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0.0", "1.0.0", 0},
+		{"1.10.0", "1.9.2", 1},
+		{"1.0", "1.0.1", -1},
+		{"2.0.0-rc1", "2.0.0-rc2", -1},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -4,8 +4,11 @@ import (
 	"fmt"
 	"regexp"
 	"tr369-wss-client/client/model"
+	"tr369-wss-client/common"
 	logger "tr369-wss-client/log"
 	tr181Model "tr369-wss-client/tr181/model"
+	"tr369-wss-client/trtree"
+	"tr369-wss-client/utils"
 )
 
 // 预编译正则表达式，避免每次调用都重新编译
@@ -13,30 +16,156 @@ import (
 var subscriptionInstanceRegex = regexp.MustCompile(`^` + regexp.QuoteMeta(model.PathSubscription) + `([1-9]\d*)\.`)
 
 // HandleAddLocalAgentSubscription 处理添加 LocalAgent 订阅
-// 仅当路径为订阅节点时才处理，否则静默返回
-func (uc *ClientUseCase) HandleAddLocalAgentSubscription(requestPath string, paramSettings map[string]string) error {
-	// 非订阅节点路径，静默返回
-	if !uc.isSubscriptionPath(requestPath) {
+// 仅当路径为订阅实例时才处理，否则静默返回；注册监听器并按 TimeToLive 调度过期删除
+func (uc *ClientUseCase) HandleAddLocalAgentSubscription(instancePath string) error {
+	// 非订阅实例路径，静默返回
+	if !uc.isSubscriptionInstancePath(instancePath) {
+		return nil
+	}
+	uc.scheduleSubscriptionExpiry(instancePath)
+	return uc.syncSubscription(instancePath)
+}
+
+// syncSubscription 按订阅实例当前的参数重新注册监听器（调用方需持有数据锁）
+// 先移除该实例已注册的监听器，实例已删除或 Enable 为 false（暂停）时不再注册
+func (uc *ClientUseCase) syncSubscription(instancePath string) error {
+	params, paths, err := uc.registerSubscription(instancePath)
+	if err != nil {
+		return err
+	}
+	if params == nil {
+		logger.Infof("[USP] SUBSCRIPTION suspended: path=%s", instancePath)
 		return nil
 	}
 
+	logger.Infof("[USP] SUBSCRIPTION registered: path=%s, id=%s, type=%s, ref=%s, paths=%v",
+		instancePath, params.Id, params.NotifType, params.ReferenceList, paths)
+	return nil
+}
+
+// registerSubscription 移除订阅实例已注册的监听器并按当前参数重新注册（调用方需持有数据锁）
+// 返回订阅参数和注册的路径，实例已删除或暂停时返回的参数为 nil
+func (uc *ClientUseCase) registerSubscription(instancePath string) (*model.SubscriptionParams, []string, error) {
+	if err := uc.ListenerMgr.RemoveSubscription(instancePath); err != nil {
+		return nil, nil, err
+	}
+	if _, err := uc.DataRepo.GetValue(instancePath); err != nil {
+		return nil, nil, nil
+	}
+	if enable := uc.getStringValue(instancePath + "Enable"); enable != "" && !utils.IsTrue(enable) {
+		return nil, nil, nil
+	}
+
 	// 提取并验证订阅参数
-	params, err := uc.extractSubscriptionParams(paramSettings)
+	params, err := uc.extractSubscriptionParams(uc.subscriptionSettings(instancePath))
+	if err != nil {
+		return nil, nil, fmt.Errorf("register subscription %s failed: %w", instancePath, err)
+	}
+
+	// 注册订阅监听器（搜索表达式和引用跟随按当前数据解析为实际路径，通配符路径由监听器匹配）
+	paths, err := uc.resolveSubscriptionPaths(params.ReferenceList)
 	if err != nil {
-		return fmt.Errorf("add subscription failed: %w", err)
+		return nil, nil, fmt.Errorf("register subscription %s failed: %w", instancePath, err)
+	}
+	for _, path := range paths {
+		if err := uc.HandleSubscription(path, instancePath, params.Id, params.NotifType); err != nil {
+			uc.ListenerMgr.RemoveSubscription(instancePath)
+			return nil, nil, fmt.Errorf("register subscription listener failed (id=%s, type=%s): %w",
+				params.Id, params.NotifType, err)
+		}
 	}
+	return params, paths, nil
+}
 
-	// 注册订阅监听器
-	if err := uc.HandleSubscription(params.ReferenceList, params.Id, params.NotifType); err != nil {
-		return fmt.Errorf("register subscription listener failed (id=%s, type=%s): %w",
-			params.Id, params.NotifType, err)
+// refreshDynamicSubscriptions 重新解析 ReferenceList 中包含搜索表达式或引用跟随的订阅（调用方需持有数据锁）
+// 作为数据仓库的数据变化处理函数，在请求、异步命令、订阅过期等修改数据后调用，使匹配的实例随数据变化
+func (uc *ClientUseCase) refreshDynamicSubscriptions() {
+	subscriptions, _ := trtree.ResolveObjectPath(uc.DataRepo.GetParameters(), model.PathSubscription+"*.")
+	for _, subscription := range subscriptions {
+		if !hasDynamicPath(uc.getStringValue(subscription + "ReferenceList")) {
+			continue
+		}
+		if _, paths, err := uc.registerSubscription(subscription); err != nil {
+			logger.Warnf("[USP] SUBSCRIPTION refresh error: path=%s, err=%v", subscription, err)
+		} else {
+			logger.Debugf("[USP] SUBSCRIPTION refreshed: path=%s, paths=%v", subscription, paths)
+		}
 	}
+}
 
-	logger.Infof("[USP] ADD_SUBSCRIPTION: success id=%s, type=%s, ref=%s",
-		params.Id, params.NotifType, params.ReferenceList)
+// hasDynamicPath 判断 ReferenceList 中是否包含需要按数据解析的路径
+func hasDynamicPath(refList string) bool {
+	for _, path := range trtree.SplitPathList(refList) {
+		if trtree.IsDynamicPath(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// subscriptionSettings 读取订阅实例中与监听器注册相关的参数（调用方需持有数据锁）
+func (uc *ClientUseCase) subscriptionSettings(instancePath string) map[string]string {
+	settings := make(map[string]string, len(subscriptionListenerParams))
+	for param := range subscriptionListenerParams {
+		settings[param] = uc.getStringValue(instancePath + param)
+	}
+	return settings
+}
+
+// subscriptionListenerParams 影响监听器注册的订阅参数，修改后需要重新注册
+var subscriptionListenerParams = map[string]bool{
+	"Enable":        true,
+	"ID":            true,
+	"NotifType":     true,
+	"ReferenceList": true,
+}
+
+// validateSubscriptionSetting 校验对订阅实例参数的修改（调用方需持有数据锁），非订阅实例或其他参数返回 nil
+func (uc *ClientUseCase) validateSubscriptionSetting(instancePath string, param string, value string) *model.USPError {
+	if !uc.isSubscriptionInstancePath(instancePath) {
+		return nil
+	}
+
+	var err error
+	switch param {
+	case "ID":
+		err = uc.validateSubscriptionId(instancePath, value)
+	case "NotifType":
+		if !isSupportedNotifType(value) {
+			err = fmt.Errorf("unsupported NotifType '%s'", value)
+		}
+	case "ReferenceList":
+		if err = uc.validateReferenceList(value); err == nil {
+			_, err = uc.resolveSubscriptionPaths(value)
+		}
+	}
+	if err != nil {
+		return model.NewUSPError(model.ErrCodeInvalidValue, "%s: %v", param, err)
+	}
+	return nil
+}
+
+// validateSubscriptionId 校验订阅 ID 非空且不与其他订阅实例重复（调用方需持有数据锁）
+func (uc *ClientUseCase) validateSubscriptionId(instancePath string, id string) error {
+	if id == "" {
+		return fmt.Errorf("missing required parameter 'ID'")
+	}
+	if other := uc.subscriptionInstance(id); other != "" && other != instancePath {
+		return fmt.Errorf("duplicate subscription ID '%s' (used by %s)", id, other)
+	}
 	return nil
 }
 
+// generateSubscriptionId 为未指定 ID 的订阅生成唯一 ID（调用方需持有数据锁）
+func (uc *ClientUseCase) generateSubscriptionId() string {
+	for {
+		id := "sub-" + common.RandStr(10)
+		if uc.subscriptionInstance(id) == "" {
+			return id
+		}
+	}
+}
+
 // HandleDeleteLocalAgentSubscription 处理删除 LocalAgent 订阅
 // 支持三种删除场景：父节点删除（批量）、订阅实例删除（单个）、非订阅路径（忽略）
 func (uc *ClientUseCase) HandleDeleteLocalAgentSubscription(requestPath string) error {
@@ -58,60 +187,36 @@ func (uc *ClientUseCase) HandleDeleteLocalAgentSubscription(requestPath string)
 	return nil
 }
 
-// HandleSubscription 处理订阅消息
-func (uc *ClientUseCase) HandleSubscription(path string, subscriptionId string, subscriptionType string) error {
-	switch subscriptionType {
-	case tr181Model.ValueChange:
-		// 处理 value change 订阅
-		err := uc.ListenerMgr.AddListener(path, tr181Model.Listener{
-			SubscriptionId: subscriptionId,
-			Listener:       uc.HandleValueChange,
-		})
-		if err != nil {
-			return err
-		}
-
-	case tr181Model.ObjectCreation:
-		// 处理对象创建订阅
-		err := uc.ListenerMgr.AddListener(path, tr181Model.Listener{
-			SubscriptionId: subscriptionId,
-			Listener:       uc.HandleObjectCreation,
-		})
-		if err != nil {
-			return err
-		}
-	case tr181Model.ObjectDeletion:
-		// 处理对象删除订阅
-		err := uc.ListenerMgr.AddListener(path, tr181Model.Listener{
-			SubscriptionId: subscriptionId,
-			Listener:       uc.HandleObjectDeletion,
-		})
-		if err != nil {
-			return err
-		}
-	case tr181Model.OperationComplete:
-		// 处理操作完成订阅
-		err := uc.ListenerMgr.AddListener(path, tr181Model.Listener{
-			SubscriptionId: subscriptionId,
-			Listener:       uc.HandleOperateComplete,
-		})
-		if err != nil {
-			return err
-		}
-	case tr181Model.Event:
-		// 处理事件订阅
-		err := uc.ListenerMgr.AddListener(path, tr181Model.Listener{
-			SubscriptionId: subscriptionId,
-			Listener:       uc.HandleEvent,
-		})
-		if err != nil {
-			return err
-		}
-	default:
+// HandleSubscription 为订阅实例在指定路径上注册监听器
+func (uc *ClientUseCase) HandleSubscription(path string, instancePath string, subscriptionId string, subscriptionType string) error {
+	handler, ok := uc.notifHandlers()[subscriptionType]
+	if !ok {
 		return fmt.Errorf("unknown subscription type %s", subscriptionType)
 	}
 
-	return nil
+	return uc.ListenerMgr.AddListener(path, tr181Model.Listener{
+		SubscriptionId: subscriptionId,
+		Instance:       instancePath,
+		Listener:       handler,
+	})
+}
+
+// notifHandlers 各通知类型对应的监听器处理函数
+func (uc *ClientUseCase) notifHandlers() map[string]tr181Model.Handler {
+	return map[string]tr181Model.Handler{
+		tr181Model.ValueChange:       uc.HandleValueChange,
+		tr181Model.ObjectCreation:    uc.HandleObjectCreation,
+		tr181Model.ObjectDeletion:    uc.HandleObjectDeletion,
+		tr181Model.OperationComplete: uc.HandleOperateComplete,
+		tr181Model.Event:             uc.HandleEvent,
+	}
+}
+
+// registerNotifHandlers 向监听器管理器注册通知处理函数，用于启动时恢复持久化的订阅
+func (uc *ClientUseCase) registerNotifHandlers() {
+	for notifType, handler := range uc.notifHandlers() {
+		uc.ListenerMgr.RegisterHandler(notifType, handler)
+	}
 }
 
 // isSubscriptionPath 判断路径是否为订阅节点
@@ -157,20 +262,53 @@ func (uc *ClientUseCase) extractSubscriptionParams(paramSettings map[string]stri
 	if params.NotifType == "" {
 		return nil, fmt.Errorf("missing required parameter 'NotifType'")
 	}
+	if !isSupportedNotifType(params.NotifType) {
+		return nil, fmt.Errorf("unsupported NotifType '%s'", params.NotifType)
+	}
 
 	// 校验 ReferenceList 是否符合 TR181 Path Name 规范
 	if err := uc.validateReferenceList(params.ReferenceList); err != nil {
 		return nil, fmt.Errorf("invalid ReferenceList: %w", err)
 	}
+	if _, err := uc.resolveSubscriptionPaths(params.ReferenceList); err != nil {
+		return nil, fmt.Errorf("invalid ReferenceList: %w", err)
+	}
 
 	return params, nil
 }
 
-// validateReferenceList 校验 ReferenceList 是否符合 TR181 Path Name 规范
+// isSupportedNotifType 判断订阅的通知类型是否支持
+func isSupportedNotifType(notifType string) bool {
+	switch notifType {
+	case tr181Model.ValueChange, tr181Model.ObjectCreation, tr181Model.ObjectDeletion,
+		tr181Model.OperationComplete, tr181Model.Event:
+		return true
+	}
+	return false
+}
+
+// resolveSubscriptionPaths 解析订阅路径
+// ReferenceList 为逗号分隔的路径列表，包含搜索表达式（如 [Enable==true]）或引用跟随（如 LowerLayers#1+.Name）的路径
+// 按当前数据解析为实际路径（可以暂时没有匹配），其他路径（包括通配符 * 和 {i}）原样返回
+func (uc *ClientUseCase) resolveSubscriptionPaths(refList string) ([]string, error) {
+	return trtree.ResolvePathList(uc.DataRepo.GetParameters(), refList)
+}
+
+// validateReferenceList 校验 ReferenceList 中的每个路径是否符合 TR181 Path Name 规范
 func (uc *ClientUseCase) validateReferenceList(refList string) error {
+	paths := trtree.SplitPathList(refList)
+	if len(paths) == 0 {
+		return model.NewPathValidationError(refList, -1, model.ErrReasonEmpty)
+	}
+
 	// 使用 PathValidator 进行校验
 	validator := uc.getPathValidator()
-	return validator.ValidatePath(refList)
+	for _, path := range paths {
+		if err := validator.ValidatePath(path); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // pathValidator 缓存的路径校验器
@@ -236,41 +374,18 @@ func (v *simplePathValidator) ValidatePath(path string) error {
 // deleteAllSubscriptions 删除所有订阅
 func (uc *ClientUseCase) deleteAllSubscriptions(parentPath string) error {
 	logger.Infof("[USP] DELETE_SUBSCRIPTION: deleting all subscriptions for parent path=%s", parentPath)
+	uc.stopAllSubscriptionExpiries()
 	return uc.ListenerMgr.ResetListener()
 }
 
-// deleteSingleSubscription 删除单个订阅实例
+// deleteSingleSubscription 删除单个订阅实例，只移除该实例注册的监听器
 func (uc *ClientUseCase) deleteSingleSubscription(instancePath string) error {
-	// 获取订阅的 ReferenceList
-	refList, err := uc.getSubscriptionReferenceList(instancePath)
-	if err != nil {
-		return err
-	}
-
-	// 移除监听器
-	if err := uc.ListenerMgr.RemoveListener(refList); err != nil {
+	uc.stopSubscriptionExpiry(instancePath)
+	if err := uc.ListenerMgr.RemoveSubscription(instancePath); err != nil {
 		logger.Warnf("[USP] DELETE_SUBSCRIPTION remove listener error: path=%s, err=%v", instancePath, err)
 		return err
 	}
 
-	logger.Infof("[USP] DELETE_SUBSCRIPTION: success path=%s, ReferenceList=%s", instancePath, refList)
+	logger.Infof("[USP] DELETE_SUBSCRIPTION: success path=%s", instancePath)
 	return nil
 }
-
-// getSubscriptionReferenceList 获取订阅实例的 ReferenceList
-func (uc *ClientUseCase) getSubscriptionReferenceList(instancePath string) (string, error) {
-	pathName, err := uc.DataRepo.GetValue(instancePath + "ReferenceList")
-	if err != nil {
-		logger.Warnf("[USP] DELETE_SUBSCRIPTION get ReferenceList error: path=%s, err=%v", instancePath, err)
-		return "", err
-	}
-
-	refList, ok := pathName.(string)
-	if !ok {
-		err := fmt.Errorf("ReferenceList is not a string, got %T", pathName)
-		logger.Warnf("[USP] DELETE_SUBSCRIPTION ReferenceList type error: path=%s, err=%v", instancePath, err)
-		return "", err
-	}
-
-	return refList, nil
-}
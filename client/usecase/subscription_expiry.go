@@ -0,0 +1,100 @@
+package usecase
+
+import (
+	"strconv"
+	"time"
+
+	"tr369-wss-client/client/model"
+	logger "tr369-wss-client/log"
+	"tr369-wss-client/trtree"
+)
+
+// subscriptionExpiry 订阅的过期定时器
+type subscriptionExpiry struct {
+	deadline time.Time
+	timer    *time.Timer
+}
+
+// scheduleSubscriptionExpiries 为所有订阅调度 TimeToLive 过期定时器（调用方需持有数据锁）
+// 启动和模拟重启后调用，按持久化的 CreationDate 计算过期时间，重启期间已过期的订阅立即删除
+func (uc *ClientUseCase) scheduleSubscriptionExpiries() {
+	uc.stopAllSubscriptionExpiries()
+
+	subscriptions, _ := trtree.ResolveObjectPath(uc.DataRepo.GetParameters(), model.PathSubscription+"*.")
+	for _, subscription := range subscriptions {
+		uc.scheduleSubscriptionExpiry(subscription)
+	}
+}
+
+// scheduleSubscriptionExpiry 根据 CreationDate 和 TimeToLive 调度订阅的过期定时器（调用方需持有数据锁）
+// TimeToLive 为 0 时不过期；CreationDate 未知时以启动时间为准
+func (uc *ClientUseCase) scheduleSubscriptionExpiry(instancePath string) {
+	uc.stopSubscriptionExpiry(instancePath)
+
+	ttl, _ := strconv.ParseUint(uc.getStringValue(instancePath+"TimeToLive"), 10, 32)
+	if ttl == 0 {
+		return
+	}
+	creationDate, err := time.Parse(time.RFC3339, uc.getStringValue(instancePath+"CreationDate"))
+	if err != nil || creationDate.Year() <= 1 {
+		creationDate = uc.bootTime
+	}
+
+	expiry := &subscriptionExpiry{deadline: creationDate.Add(time.Duration(ttl) * time.Second)}
+	expiry.timer = time.AfterFunc(time.Until(expiry.deadline), func() { uc.expireSubscription(instancePath, expiry) })
+
+	uc.expiryMu.Lock()
+	uc.subscriptionExpiries[instancePath] = expiry
+	uc.expiryMu.Unlock()
+	logger.Infof("[USP] SUBSCRIPTION expiry scheduled: path=%s, ttl=%ds, expires=%s", instancePath, ttl, expiry.deadline.UTC().Format(time.RFC3339))
+}
+
+// stopSubscriptionExpiry 停止订阅的过期定时器
+func (uc *ClientUseCase) stopSubscriptionExpiry(instancePath string) {
+	uc.expiryMu.Lock()
+	defer uc.expiryMu.Unlock()
+
+	if expiry, ok := uc.subscriptionExpiries[instancePath]; ok {
+		expiry.timer.Stop()
+		delete(uc.subscriptionExpiries, instancePath)
+	}
+}
+
+// stopAllSubscriptionExpiries 停止所有订阅的过期定时器
+func (uc *ClientUseCase) stopAllSubscriptionExpiries() {
+	uc.expiryMu.Lock()
+	defer uc.expiryMu.Unlock()
+
+	for instancePath, expiry := range uc.subscriptionExpiries {
+		expiry.timer.Stop()
+		delete(uc.subscriptionExpiries, instancePath)
+	}
+}
+
+// expireSubscription 删除过期的订阅：移除监听器、从数据模型删除实例并发送 ObjectDeletion 通知
+func (uc *ClientUseCase) expireSubscription(instancePath string, expiry *subscriptionExpiry) {
+	uc.DataRepo.Lock()
+	defer uc.DataRepo.Unlock()
+
+	// 定时器已被重新调度或停止
+	uc.expiryMu.Lock()
+	current := uc.subscriptionExpiries[instancePath]
+	if current == expiry {
+		delete(uc.subscriptionExpiries, instancePath)
+	}
+	uc.expiryMu.Unlock()
+	if current != expiry || uc.ctx.Err() != nil {
+		return
+	}
+
+	id := uc.getStringValue(instancePath + "ID")
+	if err := uc.ListenerMgr.RemoveSubscription(instancePath); err != nil {
+		logger.Warnf("[USP] SUBSCRIPTION expiry remove listener error: path=%s, err=%v", instancePath, err)
+	}
+	deletedPath, isFound := uc.DataRepo.DeleteNode(instancePath)
+	if !isFound {
+		return
+	}
+	logger.Infof("[USP] SUBSCRIPTION expired: path=%s, id=%s", deletedPath, id)
+	uc.notifyObjectDeletion(deletedPath)
+}
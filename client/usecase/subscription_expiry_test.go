@@ -0,0 +1,148 @@
+package usecase
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"tr369-wss-client/client/model"
+)
+
+// expiryTestData Subscription.1 的 TimeToLive 由测试设置，Subscription.2 不过期
+const expiryTestData = `{
+	"Device": {
+		"DeviceInfo": {"BootCount": "0"},
+		"X_TP_RebootCause": {"Cause": "", "CommandKey": "", "Reason": ""},
+		"LocalAgent": {
+			"Subscription": {
+				"1": {"Enable": "true", "ID": "sub-1", "NotifType": "ValueChange", "ReferenceList": "Device.DeviceInfo.BootCount", "Persistent": "true", "TimeToLive": "0", "CreationDate": "0001-01-01T00:00:00Z"},
+				"2": {"Enable": "true", "ID": "sub-2", "NotifType": "ValueChange", "ReferenceList": "Device.DeviceInfo.BootCount", "Persistent": "true", "TimeToLive": "0", "CreationDate": "0001-01-01T00:00:00Z"}
+			}
+		}
+	}
+}`
+
+// subscriptionExpiryOf 返回订阅实例当前的过期定时器，未调度时返回 nil
+func subscriptionExpiryOf(uc *ClientUseCase, instancePath string) *subscriptionExpiry {
+	uc.expiryMu.Lock()
+	defer uc.expiryMu.Unlock()
+	return uc.subscriptionExpiries[instancePath]
+}
+
+func TestScheduleSubscriptionExpiry(t *testing.T) {
+	created := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+
+	tests := []struct {
+		name         string
+		ttl          string
+		creationDate string
+		want         func(uc *ClientUseCase) time.Time // 为空表示不调度
+	}{
+		{"TimeToLive 0", "0", created.Format(time.RFC3339), nil},
+		{"TimeToLive empty", "", created.Format(time.RFC3339), nil},
+		{"from CreationDate", "3600", created.Format(time.RFC3339), func(*ClientUseCase) time.Time { return created.Add(time.Hour) }},
+		{"unknown CreationDate", "3600", "0001-01-01T00:00:00Z", func(uc *ClientUseCase) time.Time { return uc.bootTime.Add(time.Hour) }},
+		{"invalid CreationDate", "3600", "yesterday", func(uc *ClientUseCase) time.Time { return uc.bootTime.Add(time.Hour) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, _ := newTestUseCase(t, expiryTestData)
+			instance := model.PathSubscription + "1."
+
+			uc.DataRepo.Lock()
+			uc.DataRepo.SetValue(instance, "TimeToLive", tt.ttl)
+			uc.DataRepo.SetValue(instance, "CreationDate", tt.creationDate)
+			uc.scheduleSubscriptionExpiry(instance)
+			uc.DataRepo.Unlock()
+
+			expiry := subscriptionExpiryOf(uc, instance)
+			if tt.want == nil {
+				if expiry != nil {
+					t.Fatalf("expiry scheduled at %s, want none", expiry.deadline)
+				}
+				return
+			}
+			if expiry == nil {
+				t.Fatal("no expiry scheduled")
+			}
+			if want := tt.want(uc); !expiry.deadline.Equal(want) {
+				t.Errorf("deadline = %s, want %s", expiry.deadline, want)
+			}
+		})
+	}
+}
+
+func TestSubscriptionExpiredDuringReboot(t *testing.T) {
+	uc, lm := newTestUseCase(t, expiryTestData)
+	instance := model.PathSubscription + "1."
+
+	uc.DataRepo.Lock()
+	uc.DataRepo.SetValue(instance, "TimeToLive", "5")
+	uc.DataRepo.SetValue(instance, "CreationDate", time.Now().Add(-10*time.Second).UTC().Format(time.RFC3339))
+	uc.DataRepo.Flush()
+	uc.DataRepo.Unlock()
+
+	if err := uc.PowerOn(false); err != nil {
+		t.Fatalf("PowerOn: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(lm.objDeletions()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expired subscription not deleted within 1s")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := lm.objDeletions(); !reflect.DeepEqual(got, []string{instance}) {
+		t.Errorf("ObjectDeletion = %v, want [%s]", got, instance)
+	}
+
+	uc.DataRepo.Lock()
+	defer uc.DataRepo.Unlock()
+	if _, err := uc.DataRepo.GetValue(instance); err == nil {
+		t.Errorf("%s still exists after expiry", instance)
+	}
+	if _, err := uc.DataRepo.GetValue(model.PathSubscription + "2."); err != nil {
+		t.Errorf("Subscription.2 without TimeToLive deleted: %v", err)
+	}
+	if expiry := subscriptionExpiryOf(uc, instance); expiry != nil {
+		t.Error("expiry timer left after the subscription expired")
+	}
+}
+
+func TestExpireSubscriptionRescheduled(t *testing.T) {
+	uc, lm := newTestUseCase(t, expiryTestData)
+	instance := model.PathSubscription + "1."
+
+	uc.DataRepo.Lock()
+	uc.DataRepo.SetValue(instance, "TimeToLive", "3600")
+	uc.scheduleSubscriptionExpiry(instance)
+	uc.DataRepo.Unlock()
+	stale := subscriptionExpiryOf(uc, instance)
+
+	// TimeToLive 修改后重新调度，旧定时器触发时不删除订阅
+	uc.DataRepo.Lock()
+	uc.DataRepo.SetValue(instance, "TimeToLive", "7200")
+	uc.scheduleSubscriptionExpiry(instance)
+	uc.DataRepo.Unlock()
+	current := subscriptionExpiryOf(uc, instance)
+	if current == nil || current == stale || !current.deadline.Equal(stale.deadline.Add(time.Hour)) {
+		t.Fatalf("rescheduled expiry = %+v, want deadline one hour later than %s", current, stale.deadline)
+	}
+
+	uc.expireSubscription(instance, stale)
+	if len(lm.objDeletions()) != 0 {
+		t.Fatal("stale expiry timer deleted the subscription")
+	}
+
+	// 删除订阅时停止定时器
+	uc.DataRepo.Lock()
+	defer uc.DataRepo.Unlock()
+	if err := uc.HandleDeleteLocalAgentSubscription(instance); err != nil {
+		t.Fatalf("HandleDeleteLocalAgentSubscription: %v", err)
+	}
+	if expiry := subscriptionExpiryOf(uc, instance); expiry != nil {
+		t.Error("expiry timer left after the subscription was deleted")
+	}
+}
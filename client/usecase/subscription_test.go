@@ -0,0 +1,223 @@
+package usecase
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"tr369-wss-client/client/model"
+	"tr369-wss-client/client/repository"
+	"tr369-wss-client/pkg/api"
+	tr181Model "tr369-wss-client/tr181/model"
+)
+
+// subscriptionTestData Subscription.1 和 Subscription.2 监听同一个参数
+const subscriptionTestData = `{
+	"Device": {
+		"DeviceInfo": {"SoftwareVersion": "1.0", "HardwareVersion": "A"},
+		"LocalAgent": {
+			"Subscription": {
+				"1": {"Enable": "true", "ID": "sub-1", "NotifType": "ValueChange", "ReferenceList": "Device.DeviceInfo.SoftwareVersion", "Persistent": "true"},
+				"2": {"Enable": "true", "ID": "sub-2", "NotifType": "ValueChange", "ReferenceList": "Device.DeviceInfo.SoftwareVersion", "Persistent": "true"}
+			}
+		}
+	}
+}`
+
+// useRepositoryListeners 让 uc 使用数据仓库的监听器管理，返回监听器表所在的仓库
+func useRepositoryListeners(uc *ClientUseCase) *repository.BaseRepository {
+	base := uc.DataRepo.(*repository.DataRepository).BaseRepository
+	uc.ListenerMgr = repository.NewListenerManager(base)
+	return base
+}
+
+// subscriptionListeners 返回订阅实例注册的监听器路径（已排序）
+func subscriptionListeners(base *repository.BaseRepository, instance string) []string {
+	var paths []string
+	for path, listeners := range base.TR181DataModel.Listeners {
+		for _, listener := range listeners {
+			if listener.Instance == instance {
+				paths = append(paths, path)
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestSyncSubscription(t *testing.T) {
+	tests := []struct {
+		name    string
+		update  map[string]string // Subscription.1 的修改，值为 "-" 时删除实例
+		wantErr bool
+		want    []string
+	}{
+		{"register", nil, false, []string{"Device.DeviceInfo.SoftwareVersion"}},
+		{"ReferenceList changed", map[string]string{"ReferenceList": "Device.DeviceInfo.HardwareVersion"}, false, []string{"Device.DeviceInfo.HardwareVersion"}},
+		{"NotifType changed", map[string]string{"NotifType": "ObjectCreation", "ReferenceList": "Device.LocalAgent.Controller."}, false,
+			[]string{"Device.LocalAgent.Controller."}},
+		{"suspended", map[string]string{"Enable": "false"}, false, nil},
+		{"Enable empty counts as enabled", map[string]string{"Enable": ""}, false, []string{"Device.DeviceInfo.SoftwareVersion"}},
+		{"unsupported NotifType", map[string]string{"NotifType": "Periodic"}, true, nil},
+		{"deleted", map[string]string{"Enable": "-"}, false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, _ := newTestUseCase(t, subscriptionTestData)
+			base := useRepositoryListeners(uc)
+			first, second := model.PathSubscription+"1.", model.PathSubscription+"2."
+
+			uc.DataRepo.Lock()
+			defer uc.DataRepo.Unlock()
+			for _, instance := range []string{first, second} {
+				if err := uc.syncSubscription(instance); err != nil {
+					t.Fatalf("syncSubscription(%s): %v", instance, err)
+				}
+			}
+
+			for param, value := range tt.update {
+				if value == "-" {
+					uc.DataRepo.DeleteNode(first)
+					continue
+				}
+				uc.DataRepo.SetValue(first, param, value)
+			}
+			err := uc.syncSubscription(first)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("syncSubscription() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got := subscriptionListeners(base, first); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Subscription.1 listeners = %v, want %v", got, tt.want)
+			}
+			// 同一路径上其他订阅的监听器不受影响
+			if got := subscriptionListeners(base, second); !reflect.DeepEqual(got, []string{"Device.DeviceInfo.SoftwareVersion"}) {
+				t.Errorf("Subscription.2 listeners = %v", got)
+			}
+		})
+	}
+}
+
+func TestValidateSubscriptionSetting(t *testing.T) {
+	tests := []struct {
+		name     string
+		instance string
+		param    string
+		value    string
+		wantErr  bool
+	}{
+		{"not a subscription", model.PathDeviceInfo, "ID", "", false},
+		{"unchecked parameter", model.PathSubscription + "1.", "Persistent", "maybe", false},
+		{"ID unchanged", model.PathSubscription + "1.", "ID", "sub-1", false},
+		{"ID new", model.PathSubscription + "1.", "ID", "sub-3", false},
+		{"ID empty", model.PathSubscription + "1.", "ID", "", true},
+		{"ID used by another instance", model.PathSubscription + "1.", "ID", "sub-2", true},
+		{"NotifType supported", model.PathSubscription + "1.", "NotifType", tr181Model.Event, false},
+		{"NotifType unsupported", model.PathSubscription + "1.", "NotifType", "Periodic", true},
+		{"ReferenceList valid", model.PathSubscription + "1.", "ReferenceList", "Device.DeviceInfo.", false},
+		{"ReferenceList empty", model.PathSubscription + "1.", "ReferenceList", "", true},
+		{"ReferenceList invalid path", model.PathSubscription + "1.", "ReferenceList", "Device..DeviceInfo", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, _ := newTestUseCase(t, subscriptionTestData)
+
+			uc.DataRepo.Lock()
+			defer uc.DataRepo.Unlock()
+			uspErr := uc.validateSubscriptionSetting(tt.instance, tt.param, tt.value)
+			if (uspErr != nil) != tt.wantErr {
+				t.Fatalf("validateSubscriptionSetting(%s, %s, %q) = %v, wantErr %v", tt.instance, tt.param, tt.value, uspErr, tt.wantErr)
+			}
+			if uspErr != nil && uspErr.Code != model.ErrCodeInvalidValue {
+				t.Errorf("error code = %d, want %d", uspErr.Code, model.ErrCodeInvalidValue)
+			}
+		})
+	}
+}
+
+// dynamicSubscriptionTestData Subscription.1 的 ReferenceList 包含搜索表达式，Subscription.2 由测试设置过期；
+// 配置文件中的 controller 不在 Controller 表中，通过 UntrustedRole 获得完全访问权限
+const dynamicSubscriptionTestData = `{
+	"Device": {
+		"DeviceInfo": {"SoftwareVersion": "1.0"},
+		"IP": {
+			"Interface": {
+				"1": {"Enable": "true", "Name": "wan"},
+				"2": {"Enable": "false", "Name": "lan"}
+			}
+		},
+		"LocalAgent": {
+			"ControllerTrust": {
+				"UntrustedRole": "Device.LocalAgent.ControllerTrust.Role.1",
+				"Role": {"1": {"Enable": "true", "Name": "Full Access",
+					"Permission": {"1": {"Enable": "true", "Order": "1", "Targets": "Device.", "Param": "rw-n", "Obj": "rw-n", "InstantiatedObj": "rw-n", "CommandEvent": "r-xn"}}}}
+			},
+			"Subscription": {
+				"1": {"Enable": "true", "ID": "sub-1", "NotifType": "ValueChange", "Persistent": "true",
+					"ReferenceList": "Device.IP.Interface.[Enable==true].Name,Device.LocalAgent.Subscription.[ID==sub-2].ID"},
+				"2": {"Enable": "true", "ID": "sub-2", "NotifType": "ValueChange", "Persistent": "true",
+					"ReferenceList": "Device.DeviceInfo.SoftwareVersion", "TimeToLive": "3600", "CreationDate": "0001-01-01T00:00:00Z"}
+			}
+		}
+	}
+}`
+
+func TestDynamicSubscriptionRefresh(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(uc *ClientUseCase)
+		want   []string
+	}{
+		{"background update", func(uc *ClientUseCase) {
+			uc.updateParams("Device.IP.Interface.2.", map[string]string{"Enable": "true"})
+		}, []string{"Device.IP.Interface.1.Name", "Device.IP.Interface.2.Name", "Device.LocalAgent.Subscription.2.ID"}},
+		{"background delete", func(uc *ClientUseCase) {
+			uc.DataRepo.Lock()
+			uc.DataRepo.DeleteNode("Device.IP.Interface.1.")
+			uc.DataRepo.Unlock()
+		}, []string{"Device.LocalAgent.Subscription.2.ID"}},
+		{"SET request", func(uc *ClientUseCase) {
+			uc.HandleMessage("ctrl-1", &api.Msg{
+				Header: &api.Header{MsgId: "set-1", MsgType: api.Header_SET},
+				Body: &api.Body{MsgBody: &api.Body_Request{Request: &api.Request{ReqType: &api.Request_Set{Set: &api.Set{
+					UpdateObjs: []*api.Set_UpdateObject{{
+						ObjPath:       "Device.IP.Interface.1.",
+						ParamSettings: []*api.Set_UpdateParamSetting{{Param: "Enable", Value: "false"}},
+					}},
+				}}}}},
+			})
+		}, []string{"Device.LocalAgent.Subscription.2.ID"}},
+		{"subscription expired", func(uc *ClientUseCase) {
+			instance := model.PathSubscription + "2."
+			uc.DataRepo.Lock()
+			uc.scheduleSubscriptionExpiry(instance)
+			uc.DataRepo.Unlock()
+			uc.expireSubscription(instance, subscriptionExpiryOf(uc, instance))
+		}, []string{"Device.IP.Interface.1.Name"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, _ := newTestUseCase(t, dynamicSubscriptionTestData)
+			base := useRepositoryListeners(uc)
+			instance := model.PathSubscription + "1."
+
+			uc.DataRepo.Lock()
+			err := uc.syncSubscription(instance)
+			uc.DataRepo.Unlock()
+			if err != nil {
+				t.Fatalf("syncSubscription: %v", err)
+			}
+			if got, want := subscriptionListeners(base, instance), []string{"Device.IP.Interface.1.Name", "Device.LocalAgent.Subscription.2.ID"}; !reflect.DeepEqual(got, want) {
+				t.Fatalf("initial listeners = %v, want %v", got, want)
+			}
+
+			tt.change(uc)
+			if got := subscriptionListeners(base, instance); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("listeners after change = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
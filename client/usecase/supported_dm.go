@@ -0,0 +1,129 @@
+package usecase
+
+import (
+	"tr369-wss-client/client/model"
+	"tr369-wss-client/pkg/api"
+	"tr369-wss-client/utils"
+)
+
+// objAccessTypes 对象访问类型到 protobuf 枚举的映射
+var objAccessTypes = map[string]api.GetSupportedDMResp_ObjAccessType{
+	model.ObjAccessReadOnly:   api.GetSupportedDMResp_OBJ_READ_ONLY,
+	model.ObjAccessAddDelete:  api.GetSupportedDMResp_OBJ_ADD_DELETE,
+	model.ObjAccessAddOnly:    api.GetSupportedDMResp_OBJ_ADD_ONLY,
+	model.ObjAccessDeleteOnly: api.GetSupportedDMResp_OBJ_DELETE_ONLY,
+}
+
+// paramAccessTypes 参数访问类型到 protobuf 枚举的映射
+var paramAccessTypes = map[string]api.GetSupportedDMResp_ParamAccessType{
+	model.ParamAccessReadOnly:  api.GetSupportedDMResp_PARAM_READ_ONLY,
+	model.ParamAccessReadWrite: api.GetSupportedDMResp_PARAM_READ_WRITE,
+	model.ParamAccessWriteOnly: api.GetSupportedDMResp_PARAM_WRITE_ONLY,
+}
+
+// paramValueTypes 参数值类型到 protobuf 枚举的映射
+var paramValueTypes = map[string]api.GetSupportedDMResp_ParamValueType{
+	model.ParamTypeString:       api.GetSupportedDMResp_PARAM_STRING,
+	model.ParamTypeBoolean:      api.GetSupportedDMResp_PARAM_BOOLEAN,
+	model.ParamTypeInt:          api.GetSupportedDMResp_PARAM_INT,
+	model.ParamTypeLong:         api.GetSupportedDMResp_PARAM_LONG,
+	model.ParamTypeUnsignedInt:  api.GetSupportedDMResp_PARAM_UNSIGNED_INT,
+	model.ParamTypeUnsignedLong: api.GetSupportedDMResp_PARAM_UNSIGNED_LONG,
+	model.ParamTypeDateTime:     api.GetSupportedDMResp_PARAM_DATE_TIME,
+	model.ParamTypeBase64:       api.GetSupportedDMResp_PARAM_BASE_64,
+	model.ParamTypeHexBinary:    api.GetSupportedDMResp_PARAM_HEX_BINARY,
+	model.ParamTypeDecimal:      api.GetSupportedDMResp_PARAM_DECIMAL,
+}
+
+// valueChangeTypes 值变化类型到 protobuf 枚举的映射
+var valueChangeTypes = map[string]api.GetSupportedDMResp_ValueChangeType{
+	model.ValueChangeAllowed:    api.GetSupportedDMResp_VALUE_CHANGE_ALLOWED,
+	model.ValueChangeWillIgnore: api.GetSupportedDMResp_VALUE_CHANGE_WILL_IGNORE,
+}
+
+// commandTypes 命令类型到 protobuf 枚举的映射
+var commandTypes = map[string]api.GetSupportedDMResp_CmdType{
+	model.CommandTypeSync:  api.GetSupportedDMResp_CMD_SYNC,
+	model.CommandTypeAsync: api.GetSupportedDMResp_CMD_ASYNC,
+}
+
+// constructGetSupportedDMResp 构建 GET_SUPPORTED_DM 响应
+func (uc *ClientUseCase) constructGetSupportedDMResp(req *api.GetSupportedDM) api.Response_GetSupportedDmResp {
+	response := api.Response_GetSupportedDmResp{
+		GetSupportedDmResp: &api.GetSupportedDMResp{
+			ReqObjResults: []*api.GetSupportedDMResp_RequestedObjectResult{},
+		},
+	}
+
+	for _, objPath := range req.GetObjPaths() {
+		reqObjResult := &api.GetSupportedDMResp_RequestedObjectResult{
+			ReqObjPath:       objPath,
+			DataModelInstUri: uc.SupportedDM.DataModelInstURI(),
+		}
+
+		objects, err := uc.SupportedDM.GetObjects(objPath, req.GetFirstLevelOnly())
+		if err != nil {
+			reqObjResult.ErrCode = model.ErrCodeInvalidPath
+			reqObjResult.ErrMsg = err.Error()
+		} else {
+			for _, obj := range objects {
+				reqObjResult.SupportedObjs = append(reqObjResult.SupportedObjs, constructSupportedObjResult(obj, req))
+			}
+		}
+		response.GetSupportedDmResp.ReqObjResults = append(response.GetSupportedDmResp.ReqObjResults, reqObjResult)
+	}
+
+	return response
+}
+
+// constructSupportedObjResult 构建单个对象的描述结果
+func constructSupportedObjResult(obj *model.SupportedObject, req *api.GetSupportedDM) *api.GetSupportedDMResp_SupportedObjectResult {
+	result := &api.GetSupportedDMResp_SupportedObjectResult{
+		SupportedObjPath: obj.Path,
+		Access:           objAccessTypes[obj.Access],
+		IsMultiInstance:  obj.IsMultiInstance,
+	}
+
+	if req.GetReturnParams() {
+		for _, name := range utils.SortedKeys(obj.Params) {
+			param := obj.Params[name]
+			result.SupportedParams = append(result.SupportedParams, &api.GetSupportedDMResp_SupportedParamResult{
+				ParamName:   name,
+				Access:      paramAccessTypes[param.Access],
+				ValueType:   paramValueTypes[param.Type],
+				ValueChange: valueChangeTypes[param.ValueChange],
+			})
+		}
+	}
+
+	if req.GetReturnCommands() {
+		for _, name := range utils.SortedKeys(obj.Commands) {
+			command := obj.Commands[name]
+			result.SupportedCommands = append(result.SupportedCommands, &api.GetSupportedDMResp_SupportedCommandResult{
+				CommandName:    name,
+				InputArgNames:  command.InputArgs,
+				OutputArgNames: command.OutputArgs,
+				CommandType:    commandTypes[command.Type],
+			})
+		}
+	}
+
+	if req.GetReturnEvents() {
+		for _, name := range utils.SortedKeys(obj.Events) {
+			result.SupportedEvents = append(result.SupportedEvents, &api.GetSupportedDMResp_SupportedEventResult{
+				EventName: name,
+				ArgNames:  obj.Events[name].Args,
+			})
+		}
+	}
+
+	if req.GetReturnUniqueKeySets() && obj.IsMultiInstance {
+		for _, keys := range obj.UniqueKeys {
+			result.UniqueKeySets = append(result.UniqueKeySets, &api.GetSupportedDMResp_SupportedUniqueKeySet{
+				KeyNames: keys,
+			})
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,110 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"tr369-wss-client/client/model"
+)
+
+// contextReader 在 ctx 结束后中断读取，用于取消正在进行的下载
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.reader.Read(p)
+}
+
+// SetHTTPClient 设置文件传输使用的 HTTP 客户端（如 httptest 服务器的客户端），未设置时使用 http.DefaultClient
+func (uc *ClientUseCase) SetHTTPClient(client *http.Client) {
+	uc.httpClient = client
+}
+
+// getHTTPClient 获取文件传输使用的 HTTP 客户端
+func (uc *ClientUseCase) getHTTPClient() *http.Client {
+	if uc.httpClient == nil {
+		return http.DefaultClient
+	}
+	return uc.httpClient
+}
+
+// parseTransferURL 解析文件传输的 URL，支持 http、https 和 file 协议
+func parseTransferURL(rawURL string) (*url.URL, *model.USPError) {
+	if rawURL == "" {
+		return nil, model.NewUSPError(model.ErrCodeInvalidCommandArgs, "URL is required")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, model.NewUSPError(model.ErrCodeInvalidCommandArgs, "invalid URL %q: %v", rawURL, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "file":
+	default:
+		return nil, model.NewUSPError(model.ErrCodeInvalidCommandArgs, "unsupported URL scheme %q", u.Scheme)
+	}
+	return u, nil
+}
+
+// openTransferSource 打开下载源，http/https 使用 username/password 进行 Basic 认证，
+// file 只能读取 FileTransferDir 目录下的文件
+func (uc *ClientUseCase) openTransferSource(ctx context.Context, u *url.URL, username string, password string) (io.ReadCloser, error) {
+	if u.Scheme == "file" {
+		filePath, err := uc.localTransferPath(u.Path)
+		if err != nil {
+			return nil, err
+		}
+		return os.Open(filePath)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if username != "" || password != "" {
+		httpReq.SetBasicAuth(username, password)
+	}
+	resp, err := uc.getHTTPClient().Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected HTTP status %s", resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// localTransferPath 将 file:// URL 的路径解析为 FileTransferDir 目录下的文件路径
+// 符号链接解析后仍需位于该目录内，防止通过 ".." 或链接读取任意文件
+func (uc *ClientUseCase) localTransferPath(urlPath string) (string, error) {
+	dir := uc.Config.DataRefreshConfig.FileTransferDir
+	if dir == "" {
+		return "", fmt.Errorf("file transfer is not enabled")
+	}
+	baseDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", fmt.Errorf("invalid file transfer dir %s: %v", dir, err)
+	}
+	baseDir, _ = filepath.Abs(baseDir)
+	filePath, err := filepath.EvalSymlinks(filepath.Clean(urlPath))
+	if err != nil {
+		return "", err
+	}
+	filePath, _ = filepath.Abs(filePath)
+	rel, err := filepath.Rel(baseDir, filePath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside the file transfer dir", urlPath)
+	}
+	return filePath, nil
+}
@@ -0,0 +1,172 @@
+package usecase
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"tr369-wss-client/client/model"
+	"tr369-wss-client/trtree"
+)
+
+// lookupSupportedObject 在支持的数据模型中查找对象
+// 注册表未配置或路径包含引用跟随（需解析后才能确定对象）时不做校验，返回 nil 对象
+func (uc *ClientUseCase) lookupSupportedObject(objPath string) (*model.SupportedObject, *model.USPError) {
+	if uc.SupportedDM == nil || trtree.HasReference(objPath) {
+		return nil, nil
+	}
+	obj, ok := uc.SupportedDM.GetObject(objPath)
+	if !ok {
+		return nil, model.NewUSPError(model.ErrCodeInvalidPath, "%s", objPath)
+	}
+	return obj, nil
+}
+
+// resolveExistingObjects 解析对象路径并确认对象在数据中存在
+// 支持通配符和搜索表达式，返回所有匹配的实例路径；通配符或搜索表达式匹配不到实例时返回空列表
+func (uc *ClientUseCase) resolveExistingObjects(objPath string) ([]string, *model.USPError) {
+	if !strings.HasSuffix(objPath, ".") {
+		return nil, model.NewUSPError(model.ErrCodeInvalidPathSyntax, "object path must end with '.': %s", objPath)
+	}
+	if _, uspErr := uc.lookupSupportedObject(objPath); uspErr != nil {
+		return nil, uspErr
+	}
+
+	nodePaths, err := uc.resolveObjectPaths(objPath)
+	if errors.Is(err, trtree.ErrInvalidPathSyntax) {
+		return nil, pathSyntaxError(err)
+	}
+	if err != nil {
+		return nil, model.NewUSPError(model.ErrCodeObjectNotExist, "%s", objPath)
+	}
+
+	// 引用跟随的路径在解析后再校验是否为支持的对象
+	if trtree.HasReference(objPath) {
+		for _, nodePath := range nodePaths {
+			if _, uspErr := uc.lookupSupportedObject(nodePath); uspErr != nil {
+				return nil, uspErr
+			}
+		}
+	}
+	return nodePaths, nil
+}
+
+// validateParamSetting 校验对象上的参数设置（参数是否支持、是否可写、值类型是否合法）
+func (uc *ClientUseCase) validateParamSetting(objPath string, param string, value string) *model.USPError {
+	if uc.SupportedDM == nil {
+		return nil
+	}
+
+	supported, ok := uc.SupportedDM.GetParam(objPath + param)
+	if !ok {
+		return model.NewUSPError(model.ErrCodeUnsupportedParam, "%s%s", objPath, param)
+	}
+	if supported.Access == model.ParamAccessReadOnly {
+		return model.NewUSPError(model.ErrCodeParamReadOnly, "%s%s", objPath, param)
+	}
+	if !isValidParamValue(supported.Type, value) {
+		return model.NewUSPError(model.ErrCodeInvalidValue, "%s%s expects %s, got %q", objPath, param, supported.Type, value)
+	}
+	return nil
+}
+
+// validateAddTarget 校验 ADD 请求的目标路径是否为可创建实例的多实例表
+// 父对象路径支持搜索表达式，但必须唯一匹配；返回解析后的多实例表路径
+func (uc *ClientUseCase) validateAddTarget(objPath string) (string, *model.USPError) {
+	if !strings.HasSuffix(objPath, ".") {
+		return "", model.NewUSPError(model.ErrCodeInvalidPathSyntax, "object path must end with '.': %s", objPath)
+	}
+
+	obj, uspErr := uc.lookupSupportedObject(objPath)
+	if uspErr != nil {
+		return "", uspErr
+	}
+
+	segments, err := trtree.SplitPath(strings.TrimSuffix(objPath, "."))
+	if err != nil {
+		return "", pathSyntaxError(err)
+	}
+	tableName := segments[len(segments)-1]
+	if trtree.IsInstanceKey(tableName) || trtree.IsSearchSegment(tableName) || tableName == trtree.WildcardSegment {
+		return "", model.NewUSPError(model.ErrCodeNotATable, "%s", objPath)
+	}
+
+	if obj != nil {
+		if !obj.IsMultiInstance {
+			return "", model.NewUSPError(model.ErrCodeNotATable, "%s", objPath)
+		}
+		if obj.Access != model.ObjAccessAddDelete && obj.Access != model.ObjAccessAddOnly {
+			return "", model.NewUSPError(model.ErrCodeObjectNotCreatable, "%s", objPath)
+		}
+	}
+
+	// 父对象必须存在且唯一
+	parentPath := strings.Join(segments[:len(segments)-1], ".") + "."
+	parentPaths, uspErr := uc.resolveExistingObjects(parentPath)
+	if uspErr != nil || len(parentPaths) == 0 {
+		return "", model.NewUSPError(model.ErrCodeObjectNotExist, "parent of %s", objPath)
+	}
+	if len(parentPaths) > 1 {
+		return "", model.NewUSPError(model.ErrCodeObjectNotCreated, "parent of %s matches %d objects", objPath, len(parentPaths))
+	}
+	return parentPaths[0] + tableName + ".", nil
+}
+
+// validateDeleteTarget 校验 DELETE 请求的目标路径是否为可删除的对象实例
+func (uc *ClientUseCase) validateDeleteTarget(objPath string) *model.USPError {
+	if !strings.HasSuffix(objPath, ".") {
+		return model.NewUSPError(model.ErrCodeInvalidPathSyntax, "object path must end with '.': %s", objPath)
+	}
+
+	obj, uspErr := uc.lookupSupportedObject(objPath)
+	if uspErr != nil {
+		return uspErr
+	}
+
+	segments, err := trtree.SplitPath(strings.TrimSuffix(objPath, "."))
+	if err != nil {
+		return pathSyntaxError(err)
+	}
+	lastSegment := segments[len(segments)-1]
+	if !trtree.IsInstanceKey(lastSegment) && !trtree.IsSearchSegment(lastSegment) && lastSegment != trtree.WildcardSegment {
+		return model.NewUSPError(model.ErrCodeDeleteFailure, "not an object instance: %s", objPath)
+	}
+
+	if obj != nil && obj.Access != model.ObjAccessAddDelete && obj.Access != model.ObjAccessDeleteOnly {
+		return model.NewUSPError(model.ErrCodeDeleteFailure, "%s", objPath)
+	}
+	return nil
+}
+
+// isValidParamValue 根据 TR-106 数据类型校验参数值
+func isValidParamValue(paramType string, value string) bool {
+	var err error
+	switch paramType {
+	case model.ParamTypeBoolean:
+		switch value {
+		case "true", "false", "0", "1":
+			return true
+		}
+		return false
+	case model.ParamTypeInt:
+		_, err = strconv.ParseInt(value, 10, 32)
+	case model.ParamTypeLong:
+		_, err = strconv.ParseInt(value, 10, 64)
+	case model.ParamTypeUnsignedInt:
+		_, err = strconv.ParseUint(value, 10, 32)
+	case model.ParamTypeUnsignedLong:
+		_, err = strconv.ParseUint(value, 10, 64)
+	case model.ParamTypeDecimal:
+		_, err = strconv.ParseFloat(value, 64)
+	case model.ParamTypeDateTime:
+		_, err = time.Parse(time.RFC3339, value)
+	case model.ParamTypeBase64:
+		_, err = base64.StdEncoding.DecodeString(value)
+	case model.ParamTypeHexBinary:
+		_, err = hex.DecodeString(value)
+	}
+	return err == nil
+}
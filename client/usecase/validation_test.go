@@ -0,0 +1,62 @@
+package usecase
+
+import (
+	"testing"
+
+	"tr369-wss-client/client/model"
+	"tr369-wss-client/pkg/api"
+)
+
+// validationTestData 校验测试使用的数据，NAT 和 X_TP_RebootCause 均不在元数据文件中
+const validationTestData = `{
+	"Device": {
+		"DeviceInfo": {"SoftwareVersion": "1.0.0", "ProvisioningCode": ""},
+		"NAT": {"PortMappingAllowedOrigins": "", "InterfaceSettingNumberOfEntries": "0"},
+		"X_TP_RebootCause": {"Cause": "LocalReboot", "CommandKey": "", "Reason": ""}
+	}
+}`
+
+func TestSetObjectParamAccess(t *testing.T) {
+	tests := []struct {
+		name    string
+		objPath string
+		param   string
+		value   string
+		errCode uint32
+	}{
+		{"param not in meta", "Device.NAT.", "PortMappingAllowedOrigins", "10.0.0.0/8", 0},
+		{"vendor param not in meta", "Device.X_TP_RebootCause.", "Reason", "Button", 0},
+		{"meta read-write", "Device.DeviceInfo.", "ProvisioningCode", "abc", 0},
+		{"meta read-only", "Device.DeviceInfo.", "SoftwareVersion", "2.0.0", model.ErrCodeParamReadOnly},
+		{"number of entries", "Device.NAT.", "InterfaceSettingNumberOfEntries", "3", model.ErrCodeParamReadOnly},
+		{"unsupported param", "Device.NAT.", "NoSuchParam", "1", model.ErrCodeUnsupportedParam},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, _ := newTestUseCase(t, validationTestData)
+
+			result, _ := uc.setObject(&api.Set_UpdateObject{
+				ObjPath:       tt.objPath,
+				ParamSettings: []*api.Set_UpdateParamSetting{{Param: tt.param, Value: tt.value}},
+			})
+			success := result.GetOperStatus().GetOperSuccess()
+			if success == nil {
+				t.Fatalf("setObject(%s%s) failed: %v", tt.objPath, tt.param, result.GetOperStatus().GetOperFailure())
+			}
+			paramErrs := success.GetUpdatedInstResults()[0].GetParamErrs()
+
+			if tt.errCode == 0 {
+				if len(paramErrs) != 0 {
+					t.Fatalf("setObject(%s%s) param errors = %v, want none", tt.objPath, tt.param, paramErrs)
+				}
+				if got, err := uc.DataRepo.GetValue(tt.objPath + tt.param); err != nil || got != tt.value {
+					t.Errorf("value of %s%s = %v (err %v), want %q", tt.objPath, tt.param, got, err, tt.value)
+				}
+				return
+			}
+			if len(paramErrs) != 1 || paramErrs[0].GetErrCode() != tt.errCode {
+				t.Errorf("setObject(%s%s) param errors = %v, want code %d", tt.objPath, tt.param, paramErrs, tt.errCode)
+			}
+		})
+	}
+}
@@ -14,6 +14,12 @@ type DataRefreshConfig struct {
 
 	// tr181节点
 	TR181DataModelPath string `mapstructure:"tr181_data_model_path"`
+
+	// 出厂数据模板（只读），恢复出厂设置时用于还原数据
+	FactoryTemplatePath string `mapstructure:"factory_template_path"`
+
+	// 固件下载允许读取的本地目录，file:// URL 只能访问该目录下的文件，未配置时不支持 file://
+	FileTransferDir string `mapstructure:"file_transfer_dir"`
 }
 
 type WebsocketConfig struct {
@@ -36,15 +42,45 @@ type WebsocketConfig struct {
 	MessageChannelSize int `mapstructure:"message_channel_size"`
 }
 
+// DefaultSupportedNodesPath 未配置 supported_nodes_path 时使用的节点文件
+const DefaultSupportedNodesPath = "./data/default_tr181_nodes.json"
+
+// DataModelConfig 定义支持的数据模型（GetSupportedDM）相关参数
+type DataModelConfig struct {
+	// 支持的数据模型节点文件，用于推导对象、参数结构
+	SupportedNodesPath string `mapstructure:"supported_nodes_path"`
+
+	// 数据模型元数据文件（类型、访问权限、命令、事件、唯一键），可选
+	SupportedMetaPath string `mapstructure:"supported_meta_path"`
+
+	// 声明式命令描述文件（厂商自定义命令），可选
+	CommandDescriptorPath string `mapstructure:"command_descriptor_path"`
+}
+
 type TR369Config struct {
+	// 默认使用的 USP 协议版本（未协商时）
 	Version string `mapstructure:"version"`
+
+	// Agent 支持的 USP 协议版本列表，逗号分隔，如 "1.0,1.1,1.2,1.3,1.4"
+	SupportedVersions string `mapstructure:"supported_versions"`
+
+	// Record 的 to_id 不是 Agent 的 EndpointID 或 from_id 不是已知 controller 时的处理策略：
+	// reject 丢弃 Record（默认），log 记录告警后继续处理
+	RecordIdPolicy string `mapstructure:"record_id_policy"`
 }
 
+// Record to_id、from_id 校验策略
+const (
+	RecordIdPolicyReject = "reject"
+	RecordIdPolicyLog    = "log"
+)
+
 // Config represents the client configuration
 type Config struct {
 	DataRefreshConfig *DataRefreshConfig `mapstructure:"data_refresh_config"`
 	WebsocketConfig   *WebsocketConfig   `mapstructure:"websocket_config"`
 	Tr369Config       *TR369Config       `mapstructure:"tr369_config"`
+	DataModelConfig   *DataModelConfig   `mapstructure:"data_model_config"`
 }
 
 // GlobalConfig returns the default configuration
@@ -52,6 +88,7 @@ var GlobalConfig = Config{
 	DataRefreshConfig: &DataRefreshConfig{},
 	WebsocketConfig:   &WebsocketConfig{},
 	Tr369Config:       &TR369Config{},
+	DataModelConfig:   &DataModelConfig{},
 }
 
 // ValidateConfig validates the configuration
@@ -102,5 +139,34 @@ func ValidateConfig() error {
 		return fmt.Errorf("MessageChannelSize must be non-negative")
 	}
 
+	// 验证TR369Config
+	if GlobalConfig.Tr369Config == nil {
+		return fmt.Errorf("Tr369Config is nil")
+	}
+
+	if GlobalConfig.Tr369Config.Version == "" {
+		return fmt.Errorf("Version is empty")
+	}
+
+	// 未配置 SupportedVersions 时只支持 Version
+	if GlobalConfig.Tr369Config.SupportedVersions == "" {
+		GlobalConfig.Tr369Config.SupportedVersions = GlobalConfig.Tr369Config.Version
+	}
+
+	switch GlobalConfig.Tr369Config.RecordIdPolicy {
+	case "", RecordIdPolicyReject, RecordIdPolicyLog:
+	default:
+		return fmt.Errorf("RecordIdPolicy must be %q or %q", RecordIdPolicyReject, RecordIdPolicyLog)
+	}
+
+	// DataModelConfig 未配置时使用默认的节点文件
+	if GlobalConfig.DataModelConfig == nil {
+		GlobalConfig.DataModelConfig = &DataModelConfig{}
+	}
+
+	if GlobalConfig.DataModelConfig.SupportedNodesPath == "" {
+		GlobalConfig.DataModelConfig.SupportedNodesPath = DefaultSupportedNodesPath
+	}
+
 	return nil
 }
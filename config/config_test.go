@@ -0,0 +1,48 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	logger "tr369-wss-client/log"
+)
+
+func TestMain(m *testing.M) {
+	logger.InitLogger()
+	os.Exit(m.Run())
+}
+
+// TestInitConfigDefaults 旧版本配置文件（没有 supported_versions 和 data_model_config）仍能通过校验
+func TestInitConfigDefaults(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	content := `{
+		"websocket_config": {
+			"server_url": "ws://localhost:8081/usp",
+			"endpoint_id": "agent-1",
+			"controller_id": "ctrl-1",
+			"ping_interval": 60,
+			"max_message_size": 10485760,
+			"message_channel_size": 1024
+		},
+		"data_refresh_config": {
+			"interval_seconds": 60,
+			"write_count_threshold": 4,
+			"tr181_data_model_path": "./data/default.json"
+		},
+		"tr369_config": {"version": "1.2"}
+	}`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	if err := InitConfig(configPath); err != nil {
+		t.Fatalf("InitConfig() error = %v", err)
+	}
+	if got := GlobalConfig.Tr369Config.SupportedVersions; got != "1.2" {
+		t.Errorf("SupportedVersions = %q, want %q", got, "1.2")
+	}
+	if got := GlobalConfig.DataModelConfig.SupportedNodesPath; got != DefaultSupportedNodesPath {
+		t.Errorf("SupportedNodesPath = %q, want %q", got, DefaultSupportedNodesPath)
+	}
+}
@@ -37,8 +37,11 @@ var rootCmd = &cobra.Command{
 	},
 }
 
+// factoryReset 启动时执行本地恢复出厂设置
+var factoryReset bool
+
 func init() {
-	// 暂时不做操作
+	rootCmd.Flags().BoolVar(&factoryReset, "factory-reset", false, "restore factory defaults before connecting (Boot! cause LocalFactoryReset)")
 }
 
 func main() {
@@ -53,28 +56,52 @@ func startClient() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	messageChannel := make(chan []byte, config.GlobalConfig.WebsocketConfig.MessageChannelSize)
-	defer close(messageChannel)
-
 	// 初始化数据操作
 	// 返回分别实现 DataRepository 和 ListenerManager 接口的实例
 	dataRepo, listenerMgr := repository.NewRepository(&config.GlobalConfig, ctx, cancel)
-	dataRepo.Start()
+
+	// 初始化支持的数据模型注册表
+	supportedDM := repository.NewSupportedDMRegistry(&config.GlobalConfig)
+	if err := supportedDM.Load(); err != nil {
+		logger.Warnf("Failed to load supported data model: %v", err)
+	}
 
 	// 初始化clientUseCase
-	clientUseCase := usecase.NewClientUseCase(ctx, &config.GlobalConfig, dataRepo, listenerMgr, messageChannel)
+	clientUseCase := usecase.NewClientUseCase(ctx, &config.GlobalConfig, dataRepo, listenerMgr, supportedDM)
+
+	// 注册声明式命令（厂商自定义命令），同一份描述同时加入支持的数据模型注册表
+	if descriptorPath := config.GlobalConfig.DataModelConfig.CommandDescriptorPath; descriptorPath != "" {
+		descriptors, err := repository.LoadCommandDescriptors(descriptorPath)
+		if err == nil {
+			err = clientUseCase.RegisterCommandDescriptors(descriptors)
+		}
+		if err != nil {
+			logger.Warnf("Failed to load command descriptors: %v", err)
+		} else {
+			supportedDM.AddCommandDescriptors(descriptors)
+		}
+	}
+
+	// 启动数据仓库，clientUseCase 已注册通知处理函数，启动时恢复持久化的订阅
+	dataRepo.Start()
+
+	// 开机：记录重启原因、调度 Periodic!，Boot! 事件在连接建立后发送
+	if err := clientUseCase.PowerOn(factoryReset); err != nil {
+		logger.Warnf("Factory reset failed: %v", err)
+	}
 
-	// 创建WebSocket客户端
-	wsClient := client.NewWSClient(&config.GlobalConfig, dataRepo, clientUseCase, messageChannel)
+	// 创建各 controller 的 WebSocket 连接
+	wsClient := client.NewControllerConnections(&config.GlobalConfig, dataRepo, clientUseCase)
+	clientUseCase.SetWSClient(wsClient)
 
-	// 连接到服务器
-	logger.Infof("Connecting to TR369 server at %s...", config.GlobalConfig.WebsocketConfig.ServerURL)
+	// 连接到配置文件中的 controller 和 Controller 表中启用的 controller
+	logger.Infof("Connecting to TR369 controllers...")
 	if err := wsClient.Connect(); err != nil {
 		logger.Fatalf("Failed to connect: %v", err)
 	}
 	defer wsClient.Disconnect()
 
-	logger.Infof("Connected to TR369 server successfully")
+	logger.Infof("Connected to TR369 controllers successfully")
 
 	// 启动消息处理
 	wsClient.StartMessageHandler()
@@ -18,6 +18,7 @@ type Handler func(subscriptionId string, change interface{})
 
 type Listener struct {
 	SubscriptionId string
+	Instance       string // 订阅实例路径，如 Device.LocalAgent.Subscription.1.
 	Listener       Handler
 }
 
@@ -0,0 +1,137 @@
+package trtree
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"tr369-wss-client/pkg/api"
+)
+
+// InstancePlaceholder 多实例对象的实例占位符
+const InstancePlaceholder = "{i}"
+
+// IsInstanceKey 判断节点名是否为实例编号
+func IsInstanceKey(key string) bool {
+	_, err := strconv.Atoi(key)
+	return err == nil
+}
+
+// IsMultiInstanceNode 判断节点是否为多实例表（子节点全部为实例编号）
+func IsMultiInstanceNode(node map[string]interface{}) bool {
+	if len(node) == 0 {
+		return false
+	}
+	for key, value := range node {
+		if !IsInstanceKey(key) {
+			return false
+		}
+		if _, ok := value.(map[string]interface{}); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// InstanceNumbers 返回节点下所有实例编号（升序）
+func InstanceNumbers(node map[string]interface{}) []int {
+	var nums []int
+	for key := range node {
+		if index, err := strconv.Atoi(key); err == nil {
+			nums = append(nums, index)
+		}
+	}
+	sort.Ints(nums)
+	return nums
+}
+
+// CollectInstances 收集对象路径下的所有实例及其唯一键
+// 路径支持通配符和搜索表达式，路径本身匹配到的实例也会包含在结果中
+// firstLevelOnly 为 true 时只返回第一层实例，不再向实例内部递归
+// keyNames 返回实例路径对应的唯一键参数名
+func CollectInstances(data map[string]interface{}, path string, firstLevelOnly bool, keyNames func(instPath string) []string) ([]*api.GetInstancesResp_CurrInstance, error) {
+	objPaths, err := ResolveObjectPath(data, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*api.GetInstancesResp_CurrInstance
+	for _, objPath := range objPaths {
+		node, _ := GetNode(data, objPath)
+		segments := strings.Split(strings.TrimSuffix(objPath, "."), ".")
+		if IsInstanceKey(segments[len(segments)-1]) {
+			// 路径本身为实例：返回该实例，非 firstLevelOnly 时继续收集其子实例
+			tablePath := strings.Join(segments[:len(segments)-1], ".") + "."
+			table := map[string]interface{}{segments[len(segments)-1]: node}
+			collectTableInstances(table, tablePath, firstLevelOnly, keyNames, &result)
+		} else if IsMultiInstanceNode(node) {
+			collectTableInstances(node, objPath, firstLevelOnly, keyNames, &result)
+		} else {
+			collectInstances(node, objPath, firstLevelOnly, keyNames, &result)
+		}
+	}
+	return result, nil
+}
+
+// collectInstances 遍历普通对象节点，收集其下多实例表中的实例
+func collectInstances(node map[string]interface{}, path string, firstLevelOnly bool, keyNames func(string) []string, result *[]*api.GetInstancesResp_CurrInstance) {
+	keys := make([]string, 0, len(node))
+	for key := range node {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		child, ok := node[key].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if IsMultiInstanceNode(child) {
+			collectTableInstances(child, path+key+".", firstLevelOnly, keyNames, result)
+		} else {
+			collectInstances(child, path+key+".", firstLevelOnly, keyNames, result)
+		}
+	}
+}
+
+// collectTableInstances 收集多实例表中的实例
+func collectTableInstances(table map[string]interface{}, tablePath string, firstLevelOnly bool, keyNames func(string) []string, result *[]*api.GetInstancesResp_CurrInstance) {
+	for _, num := range InstanceNumbers(table) {
+		key := strconv.Itoa(num)
+		instance, _ := table[key].(map[string]interface{})
+		instPath := tablePath + key + "."
+
+		uniqueKeys := make(map[string]string)
+		for _, name := range keyNames(instPath) {
+			if value, ok := instance[name]; ok {
+				if _, isMap := value.(map[string]interface{}); !isMap {
+					uniqueKeys[name] = changeToString(value)
+				}
+			}
+		}
+
+		*result = append(*result, &api.GetInstancesResp_CurrInstance{
+			InstantiatedObjPath: instPath,
+			UniqueKeys:          uniqueKeys,
+		})
+
+		if !firstLevelOnly {
+			collectInstances(instance, instPath, firstLevelOnly, keyNames, result)
+		}
+	}
+}
+
+// ToSupportedPath 将实例路径转换为支持的数据模型路径
+// 如 "Device.WiFi.SSID.1.Enable" -> "Device.WiFi.SSID.{i}.Enable"
+// 搜索表达式（如 [Name=="x"]）和通配符 * 同样转换为 {i}
+func ToSupportedPath(path string) string {
+	segments, err := SplitPath(path)
+	if err != nil {
+		segments = strings.Split(path, ".")
+	}
+	for i, segment := range segments {
+		if IsInstanceKey(segment) || segment == WildcardSegment || IsSearchSegment(segment) {
+			segments[i] = InstancePlaceholder
+		}
+	}
+	return strings.Join(segments, ".")
+}
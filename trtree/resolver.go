@@ -0,0 +1,470 @@
+package trtree
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// WildcardSegment 实例通配符
+const WildcardSegment = "*"
+
+var (
+	// ErrInvalidPathSyntax 路径语法错误（如搜索表达式不合法）
+	ErrInvalidPathSyntax = errors.New("invalid path syntax")
+	// ErrPathNotFound 路径在数据中不存在
+	ErrPathNotFound = errors.New("path not found")
+)
+
+// searchOperators 搜索表达式支持的运算符（两字符运算符需要优先匹配）
+var searchOperators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// searchTerm 搜索表达式中的一个条件，如 Stats.BytesSent>1000
+type searchTerm struct {
+	param    string
+	operator string
+	value    string
+}
+
+// SplitPath 将 USP 路径拆分为分段，搜索表达式中的 . 不作为分隔符
+// 如 `Device.IP.Interface.[IPv4Address.1.IPAddress=="1.2.3.4"].Name`
+func SplitPath(path string) ([]string, error) {
+	var segments []string
+	var current strings.Builder
+	inBracket, inQuote := false, false
+
+	for _, r := range path {
+		switch {
+		case r == '"' && inBracket:
+			inQuote = !inQuote
+		case r == '[' && !inQuote:
+			if inBracket || current.Len() > 0 {
+				return nil, fmt.Errorf("%w: unexpected '[' in %s", ErrInvalidPathSyntax, path)
+			}
+			inBracket = true
+		case r == ']' && !inQuote:
+			if !inBracket {
+				return nil, fmt.Errorf("%w: unexpected ']' in %s", ErrInvalidPathSyntax, path)
+			}
+			inBracket = false
+		case r == '.' && !inBracket:
+			segments = append(segments, current.String())
+			current.Reset()
+			continue
+		}
+		current.WriteRune(r)
+	}
+
+	if inBracket || inQuote {
+		return nil, fmt.Errorf("%w: unterminated search expression in %s", ErrInvalidPathSyntax, path)
+	}
+	return append(segments, current.String()), nil
+}
+
+// IsSearchSegment 判断路径分段是否为搜索表达式
+func IsSearchSegment(segment string) bool {
+	return strings.HasPrefix(segment, "[") && strings.HasSuffix(segment, "]")
+}
+
+// IsReferenceSegment 判断路径分段是否为引用跟随，如 Interface+、LowerLayers#1+
+func IsReferenceSegment(segment string) bool {
+	return strings.HasSuffix(segment, "+") && !IsSearchSegment(segment)
+}
+
+// HasReference 判断路径中是否包含引用跟随
+func HasReference(path string) bool {
+	segments, err := SplitPath(path)
+	if err != nil {
+		return false
+	}
+	for _, segment := range segments {
+		if IsReferenceSegment(segment) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSearchPath 判断路径中是否包含通配符、搜索表达式或引用跟随
+// 这类路径匹配不到任何对象时不视为错误
+func IsSearchPath(path string) bool {
+	segments, err := SplitPath(path)
+	if err != nil {
+		return false
+	}
+	for _, segment := range segments {
+		if segment == WildcardSegment || IsSearchSegment(segment) || IsReferenceSegment(segment) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolvePath 解析 USP 路径，返回所有匹配的实际路径（按实例编号排序）
+// 支持实例编号、通配符 * 、搜索表达式（==, !=, <, >, <=, >=，以 && 连接）
+// 以及引用跟随（Param+ 跟随单值引用，Param#n+ 跟随列表中第 n 个引用，Param#*+ 跟随所有引用）
+// 对象路径（以 . 结尾）返回匹配的对象路径，参数路径返回匹配的参数路径
+func ResolvePath(data map[string]interface{}, path string) ([]string, error) {
+	segments, err := SplitPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) == 0 || segments[0] == "" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPathSyntax, path)
+	}
+
+	var result []string
+	if err := resolveSegments(data, data, segments, "", &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ResolveObjectPath 解析对象路径，路径不存在时返回 ErrPathNotFound
+// 包含通配符、搜索表达式或引用跟随的路径匹配不到实例时返回空列表
+func ResolveObjectPath(data map[string]interface{}, path string) ([]string, error) {
+	if !strings.HasSuffix(path, ".") {
+		return nil, fmt.Errorf("%w: object path must end with '.': %s", ErrInvalidPathSyntax, path)
+	}
+	paths, err := ResolvePath(data, path)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 && !IsSearchPath(path) {
+		return nil, fmt.Errorf("%w: %s", ErrPathNotFound, path)
+	}
+	return paths, nil
+}
+
+// SplitPathList 拆分逗号分隔的路径列表（如订阅的 ReferenceList），引号内的逗号不拆分，忽略空白和空项
+func SplitPathList(list string) []string {
+	var paths []string
+	for _, path := range splitOutsideQuotes(list, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// IsDynamicPath 判断路径中是否包含搜索表达式或引用跟随
+// 这类路径匹配的实例随数据变化，需要按当前数据重新解析
+func IsDynamicPath(path string) bool {
+	segments, err := SplitPath(path)
+	if err != nil {
+		return false
+	}
+	for _, segment := range segments {
+		if IsSearchSegment(segment) || IsReferenceSegment(segment) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolvePathList 解析逗号分隔的路径列表，返回去重后的路径
+// 包含搜索表达式或引用跟随的路径按当前数据解析为实际路径（可以为空），其他路径（包括通配符路径）原样返回
+func ResolvePathList(data map[string]interface{}, list string) ([]string, error) {
+	var result []string
+	seen := make(map[string]bool)
+	for _, path := range SplitPathList(list) {
+		resolved := []string{path}
+		if IsDynamicPath(path) {
+			var err error
+			if resolved, err = ResolvePath(data, path); err != nil {
+				return nil, err
+			}
+		} else if _, err := SplitPath(path); err != nil {
+			return nil, err
+		}
+		for _, p := range resolved {
+			if !seen[p] {
+				seen[p] = true
+				result = append(result, p)
+			}
+		}
+	}
+	return result, nil
+}
+
+// resolveSegments 递归解析路径分段
+// root 为数据根节点，引用跟随时从根节点重新解析被引用的路径
+func resolveSegments(root, node map[string]interface{}, segments []string, prefix string, result *[]string) error {
+	segment := segments[0]
+	isLast := len(segments) == 1
+
+	// 以 . 结尾的对象路径
+	if segment == "" && isLast {
+		*result = append(*result, prefix)
+		return nil
+	}
+
+	switch {
+	case IsReferenceSegment(segment):
+		refs, err := followReference(node, segment)
+		if err != nil {
+			return err
+		}
+		rest := segments[1:]
+		if len(rest) == 0 {
+			rest = []string{""}
+		}
+		for _, ref := range refs {
+			refSegments := append(strings.Split(strings.TrimSuffix(ref, "."), "."), rest...)
+			if err := resolveSegments(root, root, refSegments, "", result); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case segment == WildcardSegment:
+		for _, num := range InstanceNumbers(node) {
+			key := strconv.Itoa(num)
+			if instance, ok := node[key].(map[string]interface{}); ok && !isLast {
+				if err := resolveSegments(root, instance, segments[1:], prefix+key+".", result); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+
+	case IsSearchSegment(segment):
+		terms, err := parseSearchExpression(segment)
+		if err != nil {
+			return err
+		}
+		for _, num := range InstanceNumbers(node) {
+			key := strconv.Itoa(num)
+			instance, ok := node[key].(map[string]interface{})
+			if !ok || isLast || !matchSearchTerms(instance, terms) {
+				continue
+			}
+			if err := resolveSegments(root, instance, segments[1:], prefix+key+".", result); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	value, ok := node[segment]
+	if !ok {
+		return nil
+	}
+	child, isMap := value.(map[string]interface{})
+	if isLast {
+		if !isMap {
+			*result = append(*result, prefix+segment)
+		}
+		return nil
+	}
+	if !isMap {
+		return nil
+	}
+	return resolveSegments(root, child, segments[1:], prefix+segment+".", result)
+}
+
+// followReference 读取引用参数并返回需要跟随的路径
+// Param+ 只跟随单值引用（列表引用不匹配）；Param#n+ 取列表中第 n 个（从 1 开始）；Param#*+ 取全部
+func followReference(node map[string]interface{}, segment string) ([]string, error) {
+	name := strings.TrimSuffix(segment, "+")
+	index := -1
+	if pos := strings.Index(name, "#"); pos >= 0 {
+		indexStr := name[pos+1:]
+		name = name[:pos]
+		if indexStr == WildcardSegment {
+			index = 0
+		} else if n, err := strconv.Atoi(indexStr); err == nil && n > 0 {
+			index = n
+		} else {
+			return nil, fmt.Errorf("%w: invalid reference index in %q", ErrInvalidPathSyntax, segment)
+		}
+	}
+	if name == "" {
+		return nil, fmt.Errorf("%w: missing reference parameter in %q", ErrInvalidPathSyntax, segment)
+	}
+
+	value, ok := node[name]
+	if !ok {
+		return nil, nil
+	}
+	if _, isMap := value.(map[string]interface{}); isMap {
+		return nil, fmt.Errorf("%w: %s is not a reference parameter", ErrInvalidPathSyntax, name)
+	}
+
+	var refs []string
+	for _, ref := range strings.Split(changeToString(value), ",") {
+		if ref = strings.TrimSpace(ref); ref != "" {
+			refs = append(refs, ref)
+		}
+	}
+
+	switch {
+	case index == 0:
+		return refs, nil
+	case index > 0:
+		if index > len(refs) {
+			return nil, nil
+		}
+		return refs[index-1 : index], nil
+	case len(refs) > 1:
+		return nil, nil
+	}
+	return refs, nil
+}
+
+// parseSearchExpression 解析搜索表达式，如 [Enable==true&&Stats.BytesSent>1000]
+func parseSearchExpression(segment string) ([]searchTerm, error) {
+	expr := segment[1 : len(segment)-1]
+	if expr == "" {
+		return nil, fmt.Errorf("%w: empty search expression", ErrInvalidPathSyntax)
+	}
+
+	var terms []searchTerm
+	for _, part := range splitOutsideQuotes(expr, "&&") {
+		term, err := parseSearchTerm(part)
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+	}
+	return terms, nil
+}
+
+// parseSearchTerm 解析单个搜索条件
+func parseSearchTerm(part string) (searchTerm, error) {
+	for i := 0; i < len(part); i++ {
+		if part[i] == '"' {
+			break
+		}
+		for _, operator := range searchOperators {
+			if !strings.HasPrefix(part[i:], operator) {
+				continue
+			}
+			param := strings.TrimSpace(part[:i])
+			value := strings.TrimSpace(part[i+len(operator):])
+			if param == "" || value == "" {
+				return searchTerm{}, fmt.Errorf("%w: invalid search term %q", ErrInvalidPathSyntax, part)
+			}
+			if strings.HasPrefix(value, "\"") {
+				if len(value) < 2 || !strings.HasSuffix(value, "\"") {
+					return searchTerm{}, fmt.Errorf("%w: unterminated string in %q", ErrInvalidPathSyntax, part)
+				}
+				value = value[1 : len(value)-1]
+			}
+			return searchTerm{param: param, operator: operator, value: value}, nil
+		}
+	}
+	return searchTerm{}, fmt.Errorf("%w: missing operator in %q", ErrInvalidPathSyntax, part)
+}
+
+// splitOutsideQuotes 按分隔符拆分字符串，引号内的分隔符不拆分
+func splitOutsideQuotes(s string, sep string) []string {
+	var parts []string
+	inQuote := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' {
+			inQuote = !inQuote
+			continue
+		}
+		if !inQuote && strings.HasPrefix(s[i:], sep) {
+			parts = append(parts, s[start:i])
+			start = i + len(sep)
+			i += len(sep) - 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// matchSingleInstance 返回多实例表中唯一满足搜索表达式的实例编号
+func matchSingleInstance(table map[string]interface{}, segment string) (string, error) {
+	terms, err := parseSearchExpression(segment)
+	if err != nil {
+		return "", err
+	}
+
+	var matched []string
+	for _, num := range InstanceNumbers(table) {
+		key := strconv.Itoa(num)
+		if instance, ok := table[key].(map[string]interface{}); ok && matchSearchTerms(instance, terms) {
+			matched = append(matched, key)
+		}
+	}
+	switch len(matched) {
+	case 0:
+		return "", fmt.Errorf("no instance matches %s", segment)
+	case 1:
+		return matched[0], nil
+	}
+	return "", fmt.Errorf("%s matches %d instances", segment, len(matched))
+}
+
+// matchSearchTerms 判断实例是否满足所有搜索条件
+func matchSearchTerms(instance map[string]interface{}, terms []searchTerm) bool {
+	for _, term := range terms {
+		value, ok := lookupRelativeParam(instance, term.param)
+		if !ok || !compareSearchValue(value, term.operator, term.value) {
+			return false
+		}
+	}
+	return true
+}
+
+// lookupRelativeParam 获取实例下的相对参数值，支持子对象参数，如 Stats.BytesSent
+func lookupRelativeParam(instance map[string]interface{}, param string) (string, bool) {
+	node := instance
+	segments := strings.Split(param, ".")
+	for i, segment := range segments {
+		value, ok := node[segment]
+		if !ok {
+			return "", false
+		}
+		if i == len(segments)-1 {
+			if _, isMap := value.(map[string]interface{}); isMap {
+				return "", false
+			}
+			return changeToString(value), true
+		}
+		child, ok := value.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		node = child
+	}
+	return "", false
+}
+
+// compareSearchValue 比较参数值与搜索值
+// 两边都是数值时按数值比较，否则按字符串比较（dateTime 格式可直接按字符串比较）
+func compareSearchValue(actual string, operator string, expected string) bool {
+	cmp := strings.Compare(actual, expected)
+	actualNum, errActual := strconv.ParseFloat(actual, 64)
+	expectedNum, errExpected := strconv.ParseFloat(expected, 64)
+	if errActual == nil && errExpected == nil {
+		switch {
+		case actualNum < expectedNum:
+			cmp = -1
+		case actualNum > expectedNum:
+			cmp = 1
+		default:
+			cmp = 0
+		}
+	}
+
+	switch operator {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case ">":
+		return cmp > 0
+	case "<=":
+		return cmp <= 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
@@ -0,0 +1,354 @@
+package trtree
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testTree 解析器测试使用的数据模型
+const testTree = `{
+	"Device": {
+		"Ethernet": {
+			"Link": {
+				"1": {"Name": "eth0", "Enable": "true"},
+				"2": {"Name": "eth1", "Enable": "false"}
+			}
+		},
+		"IP": {
+			"Interface": {
+				"1": {
+					"Name": "wan",
+					"Alias": "a&&b",
+					"Enable": "true",
+					"LowerLayers": "Device.Ethernet.Link.1.,Device.Ethernet.Link.2.",
+					"Stats": {"BytesSent": "2000"},
+					"IPv4Address": {"1": {"IPAddress": "1.2.3.4"}}
+				},
+				"2": {
+					"Name": "lan",
+					"Alias": "lan",
+					"Enable": "true",
+					"LowerLayers": "Device.Ethernet.Link.2.",
+					"Stats": {"BytesSent": "500"},
+					"IPv4Address": {"1": {"IPAddress": "192.168.1.1"}}
+				},
+				"10": {
+					"Name": "guest",
+					"Alias": "guest",
+					"Enable": "false",
+					"LowerLayers": "",
+					"Stats": {"BytesSent": "3000"},
+					"IPv4Address": {}
+				}
+			}
+		},
+		"Hosts": {"Host": {}}
+	}
+}`
+
+func loadTestTree(t *testing.T) map[string]interface{} {
+	t.Helper()
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(testTree), &data); err != nil {
+		t.Fatalf("unmarshal test tree: %v", err)
+	}
+	return data
+}
+
+func TestResolvePath(t *testing.T) {
+	data := loadTestTree(t)
+
+	tests := []struct {
+		name string
+		path string
+		want []string
+	}{
+		{
+			name: "instance number",
+			path: "Device.IP.Interface.2.Name",
+			want: []string{"Device.IP.Interface.2.Name"},
+		},
+		{
+			name: "wildcard parameter sorted by instance number",
+			path: "Device.IP.Interface.*.Name",
+			want: []string{"Device.IP.Interface.1.Name", "Device.IP.Interface.2.Name", "Device.IP.Interface.10.Name"},
+		},
+		{
+			name: "wildcard object",
+			path: "Device.Ethernet.Link.*.",
+			want: []string{"Device.Ethernet.Link.1.", "Device.Ethernet.Link.2."},
+		},
+		{
+			name: "nested wildcards skip empty tables",
+			path: "Device.IP.Interface.*.IPv4Address.*.IPAddress",
+			want: []string{"Device.IP.Interface.1.IPv4Address.1.IPAddress", "Device.IP.Interface.2.IPv4Address.1.IPAddress"},
+		},
+		{
+			name: "search equal string",
+			path: `Device.IP.Interface.[Name=="lan"].Alias`,
+			want: []string{"Device.IP.Interface.2.Alias"},
+		},
+		{
+			name: "search numeric comparison",
+			path: "Device.IP.Interface.[Stats.BytesSent>1000].",
+			want: []string{"Device.IP.Interface.1.", "Device.IP.Interface.10."},
+		},
+		{
+			name: "search with and",
+			path: "Device.IP.Interface.[Enable==true&&Stats.BytesSent>=2000].Name",
+			want: []string{"Device.IP.Interface.1.Name"},
+		},
+		{
+			name: "search with quoted and",
+			path: `Device.IP.Interface.[Alias=="a&&b"].Name`,
+			want: []string{"Device.IP.Interface.1.Name"},
+		},
+		{
+			name: "search with dots in quoted value",
+			path: `Device.IP.Interface.[IPv4Address.1.IPAddress=="1.2.3.4"].Name`,
+			want: []string{"Device.IP.Interface.1.Name"},
+		},
+		{
+			name: "search without match",
+			path: `Device.IP.Interface.[Name=="dmz"].Name`,
+			want: nil,
+		},
+		{
+			name: "single reference",
+			path: "Device.IP.Interface.2.LowerLayers+.Name",
+			want: []string{"Device.Ethernet.Link.2.Name"},
+		},
+		{
+			name: "single reference ignores list",
+			path: "Device.IP.Interface.1.LowerLayers+.Name",
+			want: nil,
+		},
+		{
+			name: "indexed reference",
+			path: "Device.IP.Interface.1.LowerLayers#2+.Name",
+			want: []string{"Device.Ethernet.Link.2.Name"},
+		},
+		{
+			name: "indexed reference out of range",
+			path: "Device.IP.Interface.1.LowerLayers#3+.Name",
+			want: nil,
+		},
+		{
+			name: "all references",
+			path: "Device.IP.Interface.1.LowerLayers#*+.",
+			want: []string{"Device.Ethernet.Link.1.", "Device.Ethernet.Link.2."},
+		},
+		{
+			name: "reference after search",
+			path: `Device.IP.Interface.[Name=="lan"].LowerLayers+.Enable`,
+			want: []string{"Device.Ethernet.Link.2.Enable"},
+		},
+		{
+			name: "empty reference",
+			path: "Device.IP.Interface.10.LowerLayers+.Name",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolvePath(data, tt.path)
+			if err != nil {
+				t.Fatalf("ResolvePath(%q) error: %v", tt.path, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolvePath(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolvePathInvalidSyntax(t *testing.T) {
+	data := loadTestTree(t)
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{name: "empty search expression", path: "Device.IP.Interface.[].Name"},
+		{name: "missing operator", path: "Device.IP.Interface.[Name].Name"},
+		{name: "missing value", path: "Device.IP.Interface.[Name==].Name"},
+		{name: "unterminated search expression", path: "Device.IP.Interface.[Name==1.Name"},
+		{name: "unterminated quote", path: `Device.IP.Interface.[Name=="wan].Name`},
+		{name: "zero reference index", path: "Device.IP.Interface.1.LowerLayers#0+.Name"},
+		{name: "reference to object", path: "Device.IP.Interface.1.Stats+.Name"},
+		{name: "empty path", path: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ResolvePath(data, tt.path); !errors.Is(err, ErrInvalidPathSyntax) {
+				t.Errorf("ResolvePath(%q) error = %v, want %v", tt.path, err, ErrInvalidPathSyntax)
+			}
+		})
+	}
+}
+
+func TestResolveObjectPath(t *testing.T) {
+	data := loadTestTree(t)
+
+	tests := []struct {
+		name    string
+		path    string
+		want    []string
+		wantErr error
+	}{
+		{
+			name: "wildcard instances",
+			path: "Device.Ethernet.Link.*.",
+			want: []string{"Device.Ethernet.Link.1.", "Device.Ethernet.Link.2."},
+		},
+		{
+			name: "wildcard on empty table",
+			path: "Device.Hosts.Host.*.",
+			want: nil,
+		},
+		{
+			name:    "missing instance",
+			path:    "Device.Ethernet.Link.3.",
+			wantErr: ErrPathNotFound,
+		},
+		{
+			name:    "parameter path",
+			path:    "Device.Ethernet.Link.1.Name",
+			wantErr: ErrInvalidPathSyntax,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveObjectPath(data, tt.path)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ResolveObjectPath(%q) error = %v, want %v", tt.path, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolveObjectPath(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitPathList(t *testing.T) {
+	tests := []struct {
+		name string
+		list string
+		want []string
+	}{
+		{name: "single path", list: "Device.IP.Interface.1.Name", want: []string{"Device.IP.Interface.1.Name"}},
+		{name: "spaces and empty items", list: " Device.IP., ,Device.Ethernet.,", want: []string{"Device.IP.", "Device.Ethernet."}},
+		{name: "comma inside quotes", list: `Device.IP.Interface.[Alias=="a,b"].Name,Device.Hosts.`,
+			want: []string{`Device.IP.Interface.[Alias=="a,b"].Name`, "Device.Hosts."}},
+		{name: "empty list", list: "", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitPathList(tt.list); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitPathList(%q) = %v, want %v", tt.list, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolvePathList(t *testing.T) {
+	data := loadTestTree(t)
+
+	tests := []struct {
+		name    string
+		list    string
+		want    []string
+		wantErr error
+	}{
+		{
+			name: "static and wildcard paths unchanged",
+			list: "Device.IP.Interface.*.Name,Device.Hosts.Host.",
+			want: []string{"Device.IP.Interface.*.Name", "Device.Hosts.Host."},
+		},
+		{
+			name: "search expression resolved",
+			list: "Device.IP.Interface.[Enable==true].Name",
+			want: []string{"Device.IP.Interface.1.Name", "Device.IP.Interface.2.Name"},
+		},
+		{
+			name: "reference followed and duplicates removed",
+			list: "Device.IP.Interface.2.LowerLayers+.Name,Device.Ethernet.Link.2.Name",
+			want: []string{"Device.Ethernet.Link.2.Name"},
+		},
+		{
+			name: "search expression without matches",
+			list: "Device.IP.Interface.[Name==none].Name",
+			want: nil,
+		},
+		{
+			name:    "invalid path",
+			list:    "Device.IP.,Device.IP.Interface.[Name].Name",
+			wantErr: ErrInvalidPathSyntax,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolvePathList(data, tt.list)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ResolvePathList(%q) error = %v, want %v", tt.list, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolvePathList(%q) = %v, want %v", tt.list, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetValueInMap(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		key       string
+		wantPath  string
+		wantError bool
+	}{
+		{name: "instance number", path: "Device.IP.Interface.2.", key: "Name", wantPath: "Device.IP.Interface.2.Name"},
+		{name: "new parameter", path: "Device.IP.Interface.2.", key: "Description", wantPath: "Device.IP.Interface.2.Description"},
+		{name: "search unique match", path: `Device.IP.Interface.[Name=="lan"].`, key: "Alias", wantPath: "Device.IP.Interface.2.Alias"},
+		{name: "search numeric comparison", path: "Device.IP.Interface.[Stats.BytesSent<1000].", key: "Alias", wantPath: "Device.IP.Interface.2.Alias"},
+		{name: "search with dots in quoted value", path: `Device.IP.Interface.[IPv4Address.1.IPAddress=="1.2.3.4"].`, key: "Alias", wantPath: "Device.IP.Interface.1.Alias"},
+		{name: "search multiple matches", path: "Device.IP.Interface.[Enable==true].", key: "Alias", wantError: true},
+		{name: "search without match", path: `Device.IP.Interface.[Name=="dmz"].`, key: "Alias", wantError: true},
+		{name: "wildcard", path: "Device.IP.Interface.*.", key: "Alias", wantError: true},
+		{name: "through parameter", path: "Device.IP.Interface.1.Name.", key: "Alias", wantError: true},
+		{name: "object target", path: "Device.IP.Interface.1.", key: "Stats", wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := loadTestTree(t)
+			err := HandleSetRequest(data, tt.path, tt.key, "new")
+			if tt.wantError {
+				if err == nil {
+					t.Fatalf("HandleSetRequest(%q, %q) succeeded, want error", tt.path, tt.key)
+				}
+				if !reflect.DeepEqual(data, loadTestTree(t)) {
+					t.Errorf("HandleSetRequest(%q, %q) modified data on error", tt.path, tt.key)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("HandleSetRequest(%q, %q) error: %v", tt.path, tt.key, err)
+			}
+			if got, err := ResolvePath(data, tt.wantPath); err != nil || len(got) != 1 {
+				t.Fatalf("ResolvePath(%q) = %v, %v", tt.wantPath, got, err)
+			}
+			if value, _, _ := FindKeyInMap(data, strings.Split(tt.wantPath, "."), ""); value != "new" {
+				t.Errorf("%s = %v, want %q", tt.wantPath, value, "new")
+			}
+		})
+	}
+}
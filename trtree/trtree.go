@@ -149,136 +149,164 @@ func changeToString(data interface{}) string {
 	return ""
 }
 
-func constructResultParams(data map[string]interface{}, tmap map[string]string, path string) map[string]string {
-
-	for key := range data {
-		if value, ok := data[key].(map[string]interface{}); ok {
-			tmap = constructResultParams(value, tmap, path+key+".")
-		} else {
-			// 到达末尾
-			tmap[path+key] = changeToString(data[key])
+// ResolveGetPath 解析 GET 请求路径，每个对象实例返回一个 ResolvedPathResult
+// 对象路径按对象实例分组，每个结果只包含该对象自身的参数（参数名为相对名称），结果按路径排序
+// maxDepth 限制返回的对象层级（请求的对象为第 1 层），为 0 时不限制；参数路径按所属对象分组
+func ResolveGetPath(data map[string]interface{}, path string, maxDepth uint32) ([]*api.GetResp_ResolvedPathResult, error) {
+	paths, err := ResolvePath(data, path)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 && !IsSearchPath(path) {
+		return nil, fmt.Errorf("%w: %s", ErrPathNotFound, path)
+	}
+
+	results := []*api.GetResp_ResolvedPathResult{}
+	if strings.HasSuffix(path, ".") {
+		for _, objPath := range paths {
+			node, _ := GetNode(data, objPath)
+			if IsMultiInstanceNode(node) {
+				// 请求路径为多实例表：表本身没有参数，其实例与表处于同一层级
+				for _, num := range InstanceNumbers(node) {
+					key := strconv.Itoa(num)
+					instance, _ := node[key].(map[string]interface{})
+					collectObjectResults(instance, objPath+key+".", 1, maxDepth, true, &results)
+				}
+				continue
+			}
+			collectObjectResults(node, objPath, 1, maxDepth, true, &results)
 		}
+		return results, nil
+	}
+
+	byObject := make(map[string]*api.GetResp_ResolvedPathResult)
+	for _, paramPath := range paths {
+		index := strings.LastIndex(paramPath, ".")
+		objPath, name := paramPath[:index+1], paramPath[index+1:]
+		result, ok := byObject[objPath]
+		if !ok {
+			result = &api.GetResp_ResolvedPathResult{
+				ResolvedPath: objPath,
+				ResultParams: make(map[string]string),
+			}
+			byObject[objPath] = result
+			results = append(results, result)
+		}
+		value, _, _ := FindKeyInMap(data, strings.Split(paramPath, "."), "")
+		result.ResultParams[name] = changeToString(value)
 	}
-
-	return tmap
-
+	return results, nil
 }
 
-func handleGetRequest(data map[string]interface{}, path string) *api.GetResp_ResolvedPathResult {
-
-	tmap := make(map[string]string)
-	// 判断是否以.结尾
-	paths := strings.Split(path, ".")
-
-	if len(paths) < 2 {
-		return nil
+// collectObjectResults 按对象实例收集 GET 结果，每个对象只返回自身的参数
+// depth 为当前对象相对请求对象的层级，多实例表与其实例算作同一层；没有参数的子对象不返回结果
+func collectObjectResults(node map[string]interface{}, objPath string, depth uint32, maxDepth uint32, always bool, results *[]*api.GetResp_ResolvedPathResult) {
+	params := make(map[string]string)
+	var children []string
+	for _, key := range sortedNodeKeys(node) {
+		if _, ok := node[key].(map[string]interface{}); ok {
+			children = append(children, key)
+		} else {
+			params[key] = changeToString(node[key])
+		}
 	}
 
-	value, fpath, found := FindKeyInMap(data, paths, "")
-	if !found {
-		return nil
+	if len(params) > 0 || always {
+		*results = append(*results, &api.GetResp_ResolvedPathResult{
+			ResolvedPath: objPath,
+			ResultParams: params,
+		})
 	}
-	if paths[len(paths)-1] == "" {
-		tmap = constructResultParams(value.(map[string]interface{}), tmap, "")
 
-		return &api.GetResp_ResolvedPathResult{
-			ResolvedPath: fpath,
-			ResultParams: tmap,
-		}
+	if maxDepth != 0 && depth >= maxDepth {
+		return
 	}
-
-	tmap[paths[len(paths)-1]] = changeToString(value)
-
-	return &api.GetResp_ResolvedPathResult{
-		ResolvedPath: fpath[0 : len(fpath)-len(paths[len(paths)-1])],
-		ResultParams: tmap,
+	for _, key := range children {
+		child := node[key].(map[string]interface{})
+		if !IsMultiInstanceNode(child) {
+			collectObjectResults(child, objPath+key+".", depth+1, maxDepth, false, results)
+			continue
+		}
+		for _, num := range InstanceNumbers(child) {
+			instKey := strconv.Itoa(num)
+			instance, _ := child[instKey].(map[string]interface{})
+			collectObjectResults(instance, objPath+key+"."+instKey+".", depth+1, maxDepth, false, results)
+		}
 	}
 }
 
-func ConstructGetResp(data map[string]interface{}, paths []string) api.Response_GetResp {
-	response := api.Response_GetResp{
-		GetResp: &api.GetResp{
-			ReqPathResults: []*api.GetResp_RequestedPathResult{},
-		},
-	}
-
-	for _, path := range paths {
-		requestedPathResult := api.GetResp_RequestedPathResult{
-			RequestedPath:       path,
-			ResolvedPathResults: []*api.GetResp_ResolvedPathResult{},
+// sortedNodeKeys 返回节点下的键（实例编号按数值排序，其余按字典序排序）
+func sortedNodeKeys(node map[string]interface{}) []string {
+	keys := make([]string, 0, len(node))
+	for key := range node {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, errA := strconv.Atoi(keys[i])
+		b, errB := strconv.Atoi(keys[j])
+		if errA == nil && errB == nil {
+			return a < b
 		}
-		result := handleGetRequest(data, path)
+		return keys[i] < keys[j]
+	})
+	return keys
+}
 
-		if result != nil {
-			requestedPathResult.ResolvedPathResults = append(requestedPathResult.ResolvedPathResults, result)
+// GetNode 获取实际路径（不含通配符和搜索表达式）对应的对象节点
+func GetNode(data map[string]interface{}, objPath string) (map[string]interface{}, bool) {
+	node := data
+	for _, segment := range strings.Split(strings.TrimSuffix(objPath, "."), ".") {
+		child, ok := node[segment].(map[string]interface{})
+		if !ok {
+			return nil, false
 		}
-		response.GetResp.ReqPathResults = append(response.GetResp.ReqPathResults, &requestedPathResult)
+		node = child
 	}
-
-	return response
+	return node, true
 }
 
-func SetValueInMap(data map[string]interface{}, paths []string, value string) {
+// SetValueInMap 按路径分段设置参数值，缺少的中间对象自动创建
+// 搜索表达式必须唯一匹配一个实例；路径经过参数、使用通配符、搜索表达式没有唯一匹配或目标是对象时返回错误
+func SetValueInMap(data map[string]interface{}, paths []string, value string) error {
 
 	if len(paths) == 1 {
+		if _, isObject := data[paths[0]].(map[string]interface{}); isObject {
+			return fmt.Errorf("%s is an object", paths[0])
+		}
 		data[paths[0]] = value
-		return
+		return nil
 	}
 
 	path := paths[0]
 	if oldValue, ok := data[path]; ok {
 		if dataMap, ok := oldValue.(map[string]interface{}); ok {
-			SetValueInMap(dataMap, paths[1:], value)
-		} else {
-			return
-		}
-	} else if strings.Contains(path, "[") {
-		subPath := path[1 : len(path)-1]
-		subPaths := strings.Split(subPath, "&&")
-		cIndex := "0"
-
-		for key := range data {
-			total := 0
-			if value, ok := data[key].(map[string]interface{}); ok {
-
-				for _, tpath := range subPaths {
-					tpaths := strings.Split(tpath, "==")
-					if len(tpaths) != 2 {
-						return
-					}
-
-					if value[tpaths[0]] == strings.ReplaceAll(tpaths[1], "\"", "") {
-						total = total + 1
-					}
-				}
-			}
-
-			if total == len(subPaths) {
-				cIndex = key
-			}
+			return SetValueInMap(dataMap, paths[1:], value)
 		}
-
-		if cIndex == "0" {
-			return
+		return fmt.Errorf("%s is not an object", path)
+	} else if IsSearchSegment(path) {
+		key, err := matchSingleInstance(data, path)
+		if err != nil {
+			return err
 		}
-
-		dataMap, _ := data[cIndex].(map[string]interface{})
-		SetValueInMap(dataMap, paths[1:], value)
-
-	} else {
-		tempMap := make(map[string]interface{})
-		data[path] = tempMap
-		SetValueInMap(tempMap, paths[1:], value)
+		dataMap, _ := data[key].(map[string]interface{})
+		return SetValueInMap(dataMap, paths[1:], value)
+	} else if path == WildcardSegment {
+		return fmt.Errorf("%w: wildcard is not allowed when setting a value", ErrInvalidPathSyntax)
 	}
 
-	return
-
+	tempMap := make(map[string]interface{})
+	data[path] = tempMap
+	return SetValueInMap(tempMap, paths[1:], value)
 }
 
-func HandleSetRequest(data map[string]interface{}, path string, key string, value string) {
+// HandleSetRequest 设置 path+key 的参数值
+func HandleSetRequest(data map[string]interface{}, path string, key string, value string) error {
 
-	paths := strings.Split(path+key, ".")
-	SetValueInMap(data, paths, value)
+	paths, err := SplitPath(path + key)
+	if err != nil {
+		return err
+	}
+	return SetValueInMap(data, paths, value)
 
 }
 
@@ -302,16 +330,10 @@ func GetNewInstance(data map[string]interface{}, path string) (tpath string) {
 		return path + "1."
 	}
 
-	var nums []int
-	for key := range value.(map[string]interface{}) {
-		if index, err := strconv.Atoi(key); err == nil {
-			nums = append(nums, index)
-		}
-	}
+	nums := InstanceNumbers(value.(map[string]interface{}))
 	if len(nums) == 0 {
 		return path + "1."
 	}
-	sort.Ints(nums)
 	lastNumber := strconv.Itoa(nums[len(nums)-1] + 1)
 	return path + lastNumber + "."
 }
@@ -0,0 +1,95 @@
+package trtree
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestResolveGetPath(t *testing.T) {
+	data := loadTestTree(t)
+	lan := map[string]string{"Name": "lan", "Alias": "lan", "Enable": "true", "LowerLayers": "Device.Ethernet.Link.2."}
+
+	tests := []struct {
+		name     string
+		path     string
+		maxDepth uint32
+		want     map[string]map[string]string
+		order    []string
+	}{
+		{
+			name:     "object depth 1",
+			path:     "Device.IP.Interface.2.",
+			maxDepth: 1,
+			want:     map[string]map[string]string{"Device.IP.Interface.2.": lan},
+			order:    []string{"Device.IP.Interface.2."},
+		},
+		{
+			name:     "object unlimited depth",
+			path:     "Device.IP.Interface.2.",
+			maxDepth: 0,
+			want: map[string]map[string]string{
+				"Device.IP.Interface.2.":               lan,
+				"Device.IP.Interface.2.IPv4Address.1.": {"IPAddress": "192.168.1.1"},
+				"Device.IP.Interface.2.Stats.":         {"BytesSent": "500"},
+			},
+			order: []string{"Device.IP.Interface.2.", "Device.IP.Interface.2.IPv4Address.1.", "Device.IP.Interface.2.Stats."},
+		},
+		{
+			name:     "table instances share the first level",
+			path:     "Device.Ethernet.Link.",
+			maxDepth: 1,
+			want: map[string]map[string]string{
+				"Device.Ethernet.Link.1.": {"Name": "eth0", "Enable": "true"},
+				"Device.Ethernet.Link.2.": {"Name": "eth1", "Enable": "false"},
+			},
+			order: []string{"Device.Ethernet.Link.1.", "Device.Ethernet.Link.2."},
+		},
+		{
+			name: "parameters grouped by object",
+			path: "Device.IP.Interface.*.Name",
+			want: map[string]map[string]string{
+				"Device.IP.Interface.1.":  {"Name": "wan"},
+				"Device.IP.Interface.2.":  {"Name": "lan"},
+				"Device.IP.Interface.10.": {"Name": "guest"},
+			},
+			order: []string{"Device.IP.Interface.1.", "Device.IP.Interface.2.", "Device.IP.Interface.10."},
+		},
+		{
+			name:  "search without match",
+			path:  `Device.IP.Interface.[Name=="dmz"].`,
+			want:  map[string]map[string]string{},
+			order: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := ResolveGetPath(data, tt.path, tt.maxDepth)
+			if err != nil {
+				t.Fatalf("ResolveGetPath(%q) error: %v", tt.path, err)
+			}
+			var order []string
+			got := make(map[string]map[string]string)
+			for _, result := range results {
+				order = append(order, result.GetResolvedPath())
+				got[result.GetResolvedPath()] = result.GetResultParams()
+			}
+			if !reflect.DeepEqual(order, tt.order) {
+				t.Errorf("ResolveGetPath(%q) paths = %v, want %v", tt.path, order, tt.order)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolveGetPath(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveGetPathNotFound(t *testing.T) {
+	data := loadTestTree(t)
+	for _, path := range []string{"Device.IP.Interface.3.", "Device.IP.Interface.1.NoSuchParam"} {
+		if _, err := ResolveGetPath(data, path, 0); !errors.Is(err, ErrPathNotFound) {
+			t.Errorf("ResolveGetPath(%q) error = %v, want %v", path, err, ErrPathNotFound)
+		}
+	}
+}
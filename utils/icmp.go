@@ -0,0 +1,167 @@
+package utils
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// ICMP 回显请求/应答类型
+const (
+	icmpv4EchoRequest = 8
+	icmpv4EchoReply   = 0
+	icmpv6EchoRequest = 128
+	icmpv6EchoReply   = 129
+)
+
+// icmpEchoHeaderLen ICMP 回显报文头长度（type、code、checksum、id、seq）
+const icmpEchoHeaderLen = 8
+
+// icmpSeq 为每个 ICMPPinger 分配不同的标识符
+var icmpSeq atomic.Uint32
+
+// ErrICMPTimeout 在超时时间内没有收到回显应答
+var ErrICMPTimeout = errors.New("icmp echo timeout")
+
+// ICMPPinger 发送 ICMP 回显请求并等待应答
+// 优先使用原始套接字（需要 root 或 CAP_NET_RAW），失败时在 Linux 上退回到无特权的 ICMP 数据报套接字
+type ICMPPinger struct {
+	conn     net.PacketConn
+	ipv6     bool
+	datagram bool // 数据报套接字由内核填写 id 和校验和
+	id       int
+}
+
+// NewICMPPinger 创建 ICMPPinger，dscp 大于 0 时设置发送报文的 DSCP
+func NewICMPPinger(ipv6 bool, dscp int) (*ICMPPinger, error) {
+	network, address := "ip4:icmp", "0.0.0.0"
+	if ipv6 {
+		network, address = "ip6:ipv6-icmp", "::"
+	}
+
+	pinger := &ICMPPinger{
+		ipv6: ipv6,
+		id:   (os.Getpid() + int(icmpSeq.Add(1))) & 0xffff,
+	}
+	conn, err := net.ListenPacket(network, address)
+	if err != nil {
+		var dgramErr error
+		conn, dgramErr = listenICMPDatagram(ipv6)
+		if dgramErr != nil {
+			return nil, fmt.Errorf("open icmp socket: %v (datagram: %v)", err, dgramErr)
+		}
+		pinger.datagram = true
+	}
+	pinger.conn = conn
+
+	if dscp > 0 {
+		if err := setICMPDSCP(conn, ipv6, dscp); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("set dscp: %v", err)
+		}
+	}
+	return pinger, nil
+}
+
+// Echo 向 dst 发送一个数据长度为 dataSize 的回显请求，返回往返时间
+func (p *ICMPPinger) Echo(dst net.IP, seq int, dataSize int, timeout time.Duration) (time.Duration, error) {
+	request := p.echoRequest(seq, dataSize)
+
+	var addr net.Addr = &net.IPAddr{IP: dst}
+	if p.datagram {
+		addr = &net.UDPAddr{IP: dst}
+	}
+
+	start := time.Now()
+	deadline := start.Add(timeout)
+	if err := p.conn.SetDeadline(deadline); err != nil {
+		return 0, err
+	}
+	if _, err := p.conn.WriteTo(request, addr); err != nil {
+		return 0, err
+	}
+
+	buf := make([]byte, icmpEchoHeaderLen+dataSize+128)
+	for {
+		n, from, err := p.conn.ReadFrom(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return 0, ErrICMPTimeout
+			}
+			return 0, err
+		}
+		if p.isReply(buf[:n], from, dst, seq) {
+			return time.Since(start), nil
+		}
+	}
+}
+
+// Close 关闭套接字
+func (p *ICMPPinger) Close() error {
+	return p.conn.Close()
+}
+
+// echoRequest 构造回显请求报文，IPv6 的校验和由内核计算
+func (p *ICMPPinger) echoRequest(seq int, dataSize int) []byte {
+	packet := make([]byte, icmpEchoHeaderLen+dataSize)
+	packet[0] = icmpv4EchoRequest
+	if p.ipv6 {
+		packet[0] = icmpv6EchoRequest
+	}
+	binary.BigEndian.PutUint16(packet[4:], uint16(p.id))
+	binary.BigEndian.PutUint16(packet[6:], uint16(seq))
+	for i := icmpEchoHeaderLen; i < len(packet); i++ {
+		packet[i] = byte(i)
+	}
+	if !p.ipv6 {
+		binary.BigEndian.PutUint16(packet[2:], icmpChecksum(packet))
+	}
+	return packet
+}
+
+// isReply 判断收到的报文是否为本次请求的回显应答
+// 数据报套接字的 id 由内核改写并过滤，只有原始套接字需要比较 id
+func (p *ICMPPinger) isReply(packet []byte, from net.Addr, dst net.IP, seq int) bool {
+	if len(packet) < icmpEchoHeaderLen {
+		return false
+	}
+	replyType := byte(icmpv4EchoReply)
+	if p.ipv6 {
+		replyType = icmpv6EchoReply
+	}
+	if packet[0] != replyType || int(binary.BigEndian.Uint16(packet[6:])) != seq&0xffff {
+		return false
+	}
+	if !p.datagram && int(binary.BigEndian.Uint16(packet[4:])) != p.id {
+		return false
+	}
+
+	var fromIP net.IP
+	switch addr := from.(type) {
+	case *net.IPAddr:
+		fromIP = addr.IP
+	case *net.UDPAddr:
+		fromIP = addr.IP
+	}
+	return fromIP == nil || fromIP.Equal(dst)
+}
+
+// icmpChecksum 计算 ICMPv4 校验和
+func icmpChecksum(packet []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(packet); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(packet[i:]))
+	}
+	if len(packet)%2 == 1 {
+		sum += uint32(packet[len(packet)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package utils
+
+import (
+	"errors"
+	"net"
+)
+
+// listenICMPDatagram 非 Linux 平台不支持无特权的 ICMP 套接字
+func listenICMPDatagram(ipv6 bool) (net.PacketConn, error) {
+	return nil, errors.New("unprivileged icmp is not supported on this platform")
+}
+
+// setICMPDSCP 非 Linux 平台忽略 DSCP 设置
+func setICMPDSCP(conn net.PacketConn, ipv6 bool, dscp int) error {
+	return nil
+}
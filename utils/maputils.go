@@ -0,0 +1,15 @@
+package utils
+
+import (
+	"sort"
+)
+
+// SortedKeys 返回 map 的有序键列表，保证输出顺序稳定
+func SortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
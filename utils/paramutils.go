@@ -0,0 +1,6 @@
+package utils
+
+// IsTrue 判断 boolean 类型的参数值是否为真（TR-106 允许 "true" 和 "1"）
+func IsTrue(value string) bool {
+	return value == "true" || value == "1"
+}
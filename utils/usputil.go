@@ -63,26 +63,71 @@ func CreateGetResponseMessage(msgId string, resp api.Response_GetResp) (result *
 	return
 }
 
-func CreateSetResponseMessage(msgId string, requestPath []string, affectedPath []string, updatedParams []map[string]string) (result *api.Msg) {
-	var updatedObjResults []*api.SetResp_UpdatedObjectResult
-	for k, path := range requestPath {
-		updatedObjResult := &api.SetResp_UpdatedObjectResult{
-			RequestedPath: path,
-			OperStatus: &api.SetResp_UpdatedObjectResult_OperationStatus{
-				OperStatus: &api.SetResp_UpdatedObjectResult_OperationStatus_OperSuccess{
-					OperSuccess: &api.SetResp_UpdatedObjectResult_OperationStatus_OperationSuccess{
-						UpdatedInstResults: []*api.SetResp_UpdatedInstanceResult{
-							{
-								AffectedPath:  affectedPath[k],
-								UpdatedParams: updatedParams[k],
-							},
-						},
-					},
+func CreateGetSupportedDMResponseMessage(msgId string, resp api.Response_GetSupportedDmResp) (result *api.Msg) {
+	result = &api.Msg{
+		Header: &api.Header{
+			MsgType: api.Header_GET_SUPPORTED_DM_RESP,
+			MsgId:   msgId,
+		},
+		Body: &api.Body{
+			MsgBody: &api.Body_Response{
+				Response: &api.Response{
+					RespType: &resp,
+				},
+			},
+		},
+	}
+
+	return
+}
+
+func CreateGetInstancesResponseMessage(msgId string, resp api.Response_GetInstancesResp) (result *api.Msg) {
+	result = &api.Msg{
+		Header: &api.Header{
+			MsgType: api.Header_GET_INSTANCES_RESP,
+			MsgId:   msgId,
+		},
+		Body: &api.Body{
+			MsgBody: &api.Body_Response{
+				Response: &api.Response{
+					RespType: &resp,
 				},
 			},
-		}
-		updatedObjResults = append(updatedObjResults, updatedObjResult)
+		},
 	}
+
+	return
+}
+
+func CreateSetSuccessResult(requestedPath string, instResults []*api.SetResp_UpdatedInstanceResult) *api.SetResp_UpdatedObjectResult {
+	return &api.SetResp_UpdatedObjectResult{
+		RequestedPath: requestedPath,
+		OperStatus: &api.SetResp_UpdatedObjectResult_OperationStatus{
+			OperStatus: &api.SetResp_UpdatedObjectResult_OperationStatus_OperSuccess{
+				OperSuccess: &api.SetResp_UpdatedObjectResult_OperationStatus_OperationSuccess{
+					UpdatedInstResults: instResults,
+				},
+			},
+		},
+	}
+}
+
+func CreateSetFailureResult(requestedPath string, errCode uint32, errMsg string, instFailures []*api.SetResp_UpdatedInstanceFailure) *api.SetResp_UpdatedObjectResult {
+	return &api.SetResp_UpdatedObjectResult{
+		RequestedPath: requestedPath,
+		OperStatus: &api.SetResp_UpdatedObjectResult_OperationStatus{
+			OperStatus: &api.SetResp_UpdatedObjectResult_OperationStatus_OperFailure{
+				OperFailure: &api.SetResp_UpdatedObjectResult_OperationStatus_OperationFailure{
+					ErrCode:             errCode,
+					ErrMsg:              errMsg,
+					UpdatedInstFailures: instFailures,
+				},
+			},
+		},
+	}
+}
+
+func CreateSetResponseMessage(msgId string, updatedObjResults []*api.SetResp_UpdatedObjectResult) (result *api.Msg) {
 	result = &api.Msg{
 		Header: &api.Header{
 			MsgType: api.Header_SET_RESP,
@@ -125,23 +170,36 @@ func CreateOperateResponseMessage(msgId string, operationResults []*api.OperateR
 	return
 }
 
-func CreateAddResponseMessage(msgId string, requestPath []string, affectedPath []string, updatedParams []map[string]string) (result *api.Msg) {
-	var createdObjResults []*api.AddResp_CreatedObjectResult
-	for k, path := range requestPath {
-		createdObjResult := &api.AddResp_CreatedObjectResult{
-			RequestedPath: path,
-			OperStatus: &api.AddResp_CreatedObjectResult_OperationStatus{
-				OperStatus: &api.AddResp_CreatedObjectResult_OperationStatus_OperSuccess{
-					OperSuccess: &api.AddResp_CreatedObjectResult_OperationStatus_OperationSuccess{
-						InstantiatedPath: affectedPath[k],
-						UniqueKeys:       updatedParams[k],
-					},
+func CreateAddSuccessResult(requestedPath string, instantiatedPath string, uniqueKeys map[string]string, paramErrs []*api.AddResp_ParameterError) *api.AddResp_CreatedObjectResult {
+	return &api.AddResp_CreatedObjectResult{
+		RequestedPath: requestedPath,
+		OperStatus: &api.AddResp_CreatedObjectResult_OperationStatus{
+			OperStatus: &api.AddResp_CreatedObjectResult_OperationStatus_OperSuccess{
+				OperSuccess: &api.AddResp_CreatedObjectResult_OperationStatus_OperationSuccess{
+					InstantiatedPath: instantiatedPath,
+					ParamErrs:        paramErrs,
+					UniqueKeys:       uniqueKeys,
 				},
 			},
-		}
-		createdObjResults = append(createdObjResults, createdObjResult)
+		},
 	}
+}
 
+func CreateAddFailureResult(requestedPath string, errCode uint32, errMsg string) *api.AddResp_CreatedObjectResult {
+	return &api.AddResp_CreatedObjectResult{
+		RequestedPath: requestedPath,
+		OperStatus: &api.AddResp_CreatedObjectResult_OperationStatus{
+			OperStatus: &api.AddResp_CreatedObjectResult_OperationStatus_OperFailure{
+				OperFailure: &api.AddResp_CreatedObjectResult_OperationStatus_OperationFailure{
+					ErrCode: errCode,
+					ErrMsg:  errMsg,
+				},
+			},
+		},
+	}
+}
+
+func CreateAddResponseMessage(msgId string, createdObjResults []*api.AddResp_CreatedObjectResult) (result *api.Msg) {
 	result = &api.Msg{
 		Header: &api.Header{
 			MsgType: api.Header_ADD_RESP,
@@ -162,22 +220,35 @@ func CreateAddResponseMessage(msgId string, requestPath []string, affectedPath [
 	return
 }
 
-func CreateDeleteResponseMessage(msgId string, requestPath []string, affectedPath []string) (result *api.Msg) {
-	var deletedObjResults []*api.DeleteResp_DeletedObjectResult
-	for k, path := range requestPath {
-		deletedObjResult := &api.DeleteResp_DeletedObjectResult{
-			RequestedPath: path,
-			OperStatus: &api.DeleteResp_DeletedObjectResult_OperationStatus{
-				OperStatus: &api.DeleteResp_DeletedObjectResult_OperationStatus_OperSuccess{
-					OperSuccess: &api.DeleteResp_DeletedObjectResult_OperationStatus_OperationSuccess{
-						AffectedPaths: []string{affectedPath[k]},
-					},
+func CreateDeleteSuccessResult(requestedPath string, affectedPaths []string, unaffectedPathErrs []*api.DeleteResp_UnaffectedPathError) *api.DeleteResp_DeletedObjectResult {
+	return &api.DeleteResp_DeletedObjectResult{
+		RequestedPath: requestedPath,
+		OperStatus: &api.DeleteResp_DeletedObjectResult_OperationStatus{
+			OperStatus: &api.DeleteResp_DeletedObjectResult_OperationStatus_OperSuccess{
+				OperSuccess: &api.DeleteResp_DeletedObjectResult_OperationStatus_OperationSuccess{
+					AffectedPaths:      affectedPaths,
+					UnaffectedPathErrs: unaffectedPathErrs,
 				},
 			},
-		}
-		deletedObjResults = append(deletedObjResults, deletedObjResult)
+		},
 	}
+}
 
+func CreateDeleteFailureResult(requestedPath string, errCode uint32, errMsg string) *api.DeleteResp_DeletedObjectResult {
+	return &api.DeleteResp_DeletedObjectResult{
+		RequestedPath: requestedPath,
+		OperStatus: &api.DeleteResp_DeletedObjectResult_OperationStatus{
+			OperStatus: &api.DeleteResp_DeletedObjectResult_OperationStatus_OperFailure{
+				OperFailure: &api.DeleteResp_DeletedObjectResult_OperationStatus_OperationFailure{
+					ErrCode: errCode,
+					ErrMsg:  errMsg,
+				},
+			},
+		},
+	}
+}
+
+func CreateDeleteResponseMessage(msgId string, deletedObjResults []*api.DeleteResp_DeletedObjectResult) (result *api.Msg) {
 	result = &api.Msg{
 		Header: &api.Header{
 			MsgType: api.Header_DELETE_RESP,
@@ -307,6 +378,63 @@ func CreateGetSupportedProtocolMessage(ctx context.Context, controllerSupported
 	return
 }
 
+func CreateGetSupportedProtocolResponseMessage(msgId string, agentSupported string) (result *api.Msg) {
+	result = &api.Msg{
+		Header: &api.Header{
+			MsgType: api.Header_GET_SUPPORTED_PROTO_RESP,
+			MsgId:   msgId,
+		},
+		Body: &api.Body{
+			MsgBody: &api.Body_Response{
+				Response: &api.Response{
+					RespType: &api.Response_GetSupportedProtocolResp{
+						GetSupportedProtocolResp: &api.GetSupportedProtocolResp{
+							AapitSupportedProtocolVersions: agentSupported,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	return
+}
+
+func CreateErrorMessage(msgId string, errCode uint32, errMsg string, paramErrs []*api.Error_ParamError) (result *api.Msg) {
+	result = &api.Msg{
+		Header: &api.Header{
+			MsgType: api.Header_ERROR,
+			MsgId:   msgId,
+		},
+		Body: &api.Body{
+			MsgBody: &api.Body_Error{
+				Error: &api.Error{
+					ErrCode:   errCode,
+					ErrMsg:    errMsg,
+					ParamErrs: paramErrs,
+				},
+			},
+		},
+	}
+
+	return
+}
+
+// CreateDisconnectRecord 创建 DisconnectRecord，通知对端即将断开连接
+func CreateDisconnectRecord(ver, to, from string, reason string, reasonCode uint32) *api.Record {
+	return &api.Record{
+		Version: ver,
+		ToId:    to,
+		FromId:  from,
+		RecordType: &api.Record_Disconnect{
+			Disconnect: &api.DisconnectRecord{
+				Reason:     reason,
+				ReasonCode: reasonCode,
+			},
+		},
+	}
+}
+
 func CreateUspRecordNoSession(ver, to, from string, msg *api.Msg) (result *api.Record) {
 	result = &api.Record{
 		Version: ver,
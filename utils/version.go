@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ParseVersions 解析逗号分隔的 USP 协议版本列表
+func ParseVersions(versions string) []string {
+	var result []string
+	for _, version := range strings.Split(versions, ",") {
+		version = strings.TrimSpace(version)
+		if version != "" {
+			result = append(result, version)
+		}
+	}
+	return result
+}
+
+// IsVersionSupported 判断版本是否在支持的版本列表中
+func IsVersionSupported(supported string, version string) bool {
+	for _, v := range ParseVersions(supported) {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// NegotiateVersion 协商双方都支持的最高 USP 协议版本
+func NegotiateVersion(agentSupported string, controllerSupported string) (string, bool) {
+	best := ""
+	for _, version := range ParseVersions(controllerSupported) {
+		if !IsVersionSupported(agentSupported, version) {
+			continue
+		}
+		if best == "" || CompareVersion(version, best) > 0 {
+			best = version
+		}
+	}
+	return best, best != ""
+}
+
+// CompareVersion 比较两个 "major.minor" 格式的版本号
+// 返回 1 表示 a > b，-1 表示 a < b，0 表示相等
+func CompareVersion(a string, b string) int {
+	partsA := strings.Split(a, ".")
+	partsB := strings.Split(b, ".")
+	for i := 0; i < len(partsA) || i < len(partsB); i++ {
+		var numA, numB int
+		if i < len(partsA) {
+			numA, _ = strconv.Atoi(partsA[i])
+		}
+		if i < len(partsB) {
+			numB, _ = strconv.Atoi(partsB[i])
+		}
+		if numA != numB {
+			if numA > numB {
+				return 1
+			}
+			return -1
+		}
+	}
+	return 0
+}
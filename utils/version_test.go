@@ -0,0 +1,49 @@
+package utils
+
+import "testing"
+
+func TestNegotiateVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		agent      string
+		controller string
+		want       string
+		wantOk     bool
+	}{
+		{name: "highest common version", agent: "1.0,1.1,1.2,1.3", controller: "1.1,1.3", want: "1.3", wantOk: true},
+		{name: "controller order does not matter", agent: "1.0,1.1,1.2", controller: "1.2,1.0,1.1", want: "1.2", wantOk: true},
+		{name: "numeric minor comparison", agent: "1.2,1.10", controller: "1.10,1.2", want: "1.10", wantOk: true},
+		{name: "controller supports newer versions", agent: "1.0,1.1", controller: "1.1,1.2,1.3", want: "1.1", wantOk: true},
+		{name: "spaces and empty items", agent: " 1.0 , 1.2 ,", controller: "1.2, ,1.0", want: "1.2", wantOk: true},
+		{name: "no common version", agent: "1.0,1.1", controller: "1.2,1.3", want: "", wantOk: false},
+		{name: "empty controller list", agent: "1.0,1.1", controller: "", want: "", wantOk: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := NegotiateVersion(tt.agent, tt.controller)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("NegotiateVersion(%q, %q) = (%q, %v), want (%q, %v)", tt.agent, tt.controller, got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestCompareVersion(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "1.3", b: "1.3", want: 0},
+		{a: "1.3", b: "1.2", want: 1},
+		{a: "1.2", b: "1.10", want: -1},
+		{a: "2.0", b: "1.9", want: 1},
+		{a: "1", b: "1.0", want: 0},
+	}
+
+	for _, tt := range tests {
+		if got := CompareVersion(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersion(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}